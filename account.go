@@ -0,0 +1,178 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// accountInfo contains information about a DNSimple account.
+type accountInfo struct {
+	// Email is the e-mail address of the account.
+	Email string
+
+	// Plan is the name of the subscription plan (e.g. "Silver").
+	// Plan is empty if the plan could not be determined.
+	Plan string
+
+	// DomainCount is the number of domains in the account.
+	DomainCount int
+
+	// RateLimit contains the API rate limit status of the last request.
+	RateLimit rateLimit
+}
+
+// rateLimit contains the API rate limit status as reported by the DNSimple API.
+type rateLimit struct {
+	// Limit is the maximum number of requests per hour. Zero if unknown.
+	Limit int
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is the time at which the current window ends.
+	Reset time.Time
+}
+
+// IsKnown returns true if the API reported a rate limit.
+func (limit rateLimit) IsKnown() bool {
+	return limit.Limit > 0
+}
+
+// accountInfoProvider returns information about the account
+// that belongs to a set of credentials.
+type accountInfoProvider interface {
+	// GetAccountInfo returns information about the account.
+	// Returns an error if the credentials are rejected by the API.
+	GetAccountInfo(ctx context.Context) (accountInfo, error)
+}
+
+// accountInfoProviderCreator creates account info providers.
+type accountInfoProviderCreator interface {
+	// CreateAccountInfoProvider creates an account info provider for the given credentials.
+	CreateAccountInfoProvider(credentials deens.APICredentials) (accountInfoProvider, error)
+}
+
+// dnsimpleAccountInfoProviderFactory creates DNSimple account info providers.
+type dnsimpleAccountInfoProviderFactory struct {
+	settings *apiSettings
+}
+
+// CreateAccountInfoProvider creates a DNSimple account info provider for the given credentials.
+func (factory dnsimpleAccountInfoProviderFactory) CreateAccountInfoProvider(credentials deens.APICredentials) (accountInfoProvider, error) {
+	client, err := newDNSimpleClient(credentials, *factory.settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return dnsimpleAccountInfoProvider{client}, nil
+}
+
+// dnsimpleAccountInfoProvider reads account information from the DNSimple API.
+type dnsimpleAccountInfoProvider struct {
+	client *dnsimple.Client
+}
+
+// dnsimpleUser is the user model of the DNSimple API.
+type dnsimpleUser struct {
+	Email       string `json:"email"`
+	DomainCount int    `json:"domain_count"`
+}
+
+// dnsimpleSubscription is the subscription model of the DNSimple API.
+type dnsimpleSubscription struct {
+	Plan string `json:"plan"`
+}
+
+// GetAccountInfo fetches the user and subscription details of the account.
+// The user endpoint is used to verify the credentials; the subscription
+// is optional and an empty plan is returned if it cannot be fetched.
+func (provider dnsimpleAccountInfoProvider) GetAccountInfo(ctx context.Context) (accountInfo, error) {
+
+	var userResponse struct {
+		User dnsimpleUser `json:"user"`
+	}
+
+	limit, userError := provider.get(ctx, "/user", &userResponse)
+	if userError != nil {
+		return accountInfo{}, userError
+	}
+
+	info := accountInfo{
+		Email:       userResponse.User.Email,
+		DomainCount: userResponse.User.DomainCount,
+		RateLimit:   limit,
+	}
+
+	var subscriptionResponse struct {
+		Subscription dnsimpleSubscription `json:"subscription"`
+	}
+
+	if limit, subscriptionError := provider.get(ctx, "/subscription", &subscriptionResponse); subscriptionError == nil {
+		info.Plan = subscriptionResponse.Subscription.Plan
+		info.RateLimit = limit
+	}
+
+	return info, nil
+}
+
+// get performs a GET request against the given endpoint and decodes
+// the JSON response into the given model.
+func (provider dnsimpleAccountInfoProvider) get(ctx context.Context, endpoint string, model interface{}) (rateLimit, error) {
+	request, requestError := provider.client.NewRequest(nil, "GET", endpoint)
+	if requestError != nil {
+		return rateLimit{}, requestError
+	}
+
+	response, responseError := provider.client.Http.Do(request.WithContext(ctx))
+	if responseError != nil {
+		return rateLimit{}, responseError
+	}
+
+	defer response.Body.Close()
+
+	limit := parseRateLimit(response.Header)
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return limit, fmt.Errorf("Authentication failed. Please check your e-mail address and API token")
+	default:
+		return limit, fmt.Errorf("API Error: %s", response.Status)
+	}
+
+	body, readError := ioutil.ReadAll(response.Body)
+	if readError != nil {
+		return limit, readError
+	}
+
+	if unmarshalError := json.Unmarshal(body, model); unmarshalError != nil {
+		return limit, fmt.Errorf("Unable to parse the API response: %s", unmarshalError.Error())
+	}
+
+	return limit, nil
+}
+
+// parseRateLimit reads the rate limit status from the
+// X-RateLimit-* headers of an API response.
+func parseRateLimit(header http.Header) rateLimit {
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+
+	var reset time.Time
+	if resetTimestamp, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(resetTimestamp, 0)
+	}
+
+	return rateLimit{limit, remaining, reset}
+}
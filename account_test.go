@@ -0,0 +1,104 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestAccountInfoProvider returns an account info provider
+// that uses the given test server as API endpoint.
+func newTestAccountInfoProvider(t *testing.T, server *httptest.Server) accountInfoProvider {
+	factory := dnsimpleAccountInfoProviderFactory{&apiSettings{URL: server.URL}}
+	provider, err := factory.CreateAccountInfoProvider(deens.APICredentials{Email: "user@example.com", Token: "1234"})
+	if err != nil {
+		t.Fatalf("Unable to create account info provider: %s", err.Error())
+	}
+
+	return provider
+}
+
+func Test_dnsimpleAccountInfoProvider_GetAccountInfo_ValidCredentials_AccountInfoIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "3600")
+		w.Header().Set("X-RateLimit-Remaining", "3598")
+		w.Header().Set("X-RateLimit-Reset", "1454972400")
+
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprintf(w, `{"user": {"id": 1, "email": "user@example.com", "domain_count": 3}}`)
+		case "/subscription":
+			fmt.Fprintf(w, `{"subscription": {"id": 1, "plan": "Silver"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestAccountInfoProvider(t, server)
+
+	// act
+	info, err := provider.GetAccountInfo(context.Background())
+
+	// assert
+	if err != nil {
+		t.Fatalf("GetAccountInfo() returned an error: %s", err.Error())
+	}
+
+	if info.Email != "user@example.com" || info.Plan != "Silver" || info.DomainCount != 3 || info.RateLimit.Remaining != 3598 {
+		t.Fail()
+		t.Logf("GetAccountInfo() returned unexpected account info: %#v", info)
+	}
+}
+
+func Test_dnsimpleAccountInfoProvider_GetAccountInfo_InvalidCredentials_ErrorIsReturned(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, `{"message": "Authentication failed"}`)
+	}))
+	defer server.Close()
+
+	provider := newTestAccountInfoProvider(t, server)
+
+	// act
+	_, err := provider.GetAccountInfo(context.Background())
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("GetAccountInfo() should return an error if the API rejects the credentials.")
+	}
+}
+
+func Test_dnsimpleAccountInfoProvider_GetAccountInfo_NoSubscription_PlanIsEmpty(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintf(w, `{"user": {"id": 1, "email": "user@example.com", "domain_count": 3}}`)
+	}))
+	defer server.Close()
+
+	provider := newTestAccountInfoProvider(t, server)
+
+	// act
+	info, err := provider.GetAccountInfo(context.Background())
+
+	// assert
+	if err != nil || info.Plan != "" {
+		t.Fail()
+		t.Logf("GetAccountInfo() should succeed with an empty plan if the subscription is not available (Error: %s).", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/spf13/afero"
+	"time"
+)
+
+var (
+	actionNameBackup = "backup"
+
+	backupArguments = flag.NewFlagSet(actionNameBackup, flag.ContinueOnError)
+	backupDomain    = backupArguments.String("domain", "", "Only save the records of the given domain (default: all domains)")
+	backupDirectory = backupArguments.String("dir", "", "The directory to write the snapshot to (default: ~/.dee/backups)")
+)
+
+type backupAction struct {
+	infoProviderFactory dnsInfoProviderCreator
+	fs                  afero.Fs
+	folder              string
+}
+
+func (action backupAction) Name() string {
+	return actionNameBackup
+}
+
+func (action backupAction) Description() string {
+	return "Save a snapshot of the records of one or all domains"
+}
+
+func (action backupAction) Usage() string {
+	buf := new(bytes.Buffer)
+	backupArguments.SetOutput(buf)
+	backupArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute writes all records of the given domain or of all domains
+// of the account to a new timestamped snapshot file.
+func (action backupAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*backupDomain = ""
+	*backupDirectory = action.folder
+	if parseError := backupArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	domain, domainError := normalizeName(*backupDomain)
+	if domainError != nil {
+		return nil, domainError
+	}
+
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	domains := []string{domain}
+	if domain == "" {
+		domainNames, domainNamesError := infoProvider.GetDomainNames(ctx)
+		if domainNamesError != nil {
+			return nil, fmt.Errorf("Unable to fetch the domains: %s", domainNamesError.Error())
+		}
+
+		domains = domainNames
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("No domains found")
+	}
+
+	// fetch the records
+	s := snapshot{Version: snapshotVersion, Time: time.Now().UTC()}
+	recordCount := 0
+	for _, domainName := range domains {
+		records, recordsError := infoProvider.GetDomainRecords(ctx, domainName)
+		if recordsError != nil {
+			return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", domainName, recordsError.Error())
+		}
+
+		s.Zones = append(s.Zones, snapshotZone{Domain: domainName, Records: records})
+		recordCount += len(records)
+	}
+
+	filePath, writeError := writeSnapshot(action.fs, *backupDirectory, s)
+	if writeError != nil {
+		return nil, fmt.Errorf("Unable to write the snapshot: %s", writeError.Error())
+	}
+
+	return successMessage{fmt.Sprintf("Saved %d record(s) of %d domain(s) to %s", recordCount, len(s.Zones), filePath)}, nil
+}
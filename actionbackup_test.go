@@ -0,0 +1,70 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"strings"
+	"testing"
+)
+
+// backupAction.Execute should write the records of all domains to a single snapshot.
+func Test_backupAction_AllDomains_SnapshotContainsAllZones(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return []string{"example.com", "example.org"}, nil
+		},
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "MX", Content: "mail." + domain, Ttl: 3600, Prio: 10}}, nil
+		},
+	}
+
+	action := backupAction{testInfoProviderFactory{infoProvider, nil}, fs, "/home/user/.dee/backups"}
+
+	// act
+	result, err := action.Execute(context.Background(), []string{})
+
+	// assert
+	if err != nil {
+		t.Fail()
+		t.Logf("backupAction.Execute should not fail: %s", err.Error())
+		return
+	}
+
+	filePath := result.Text()[strings.Index(result.Text(), "/home"):]
+	s, readError := readSnapshot(fs, filePath)
+	zone, exists := s.Zone("example.org")
+	if readError != nil || len(s.Zones) != 2 || !exists || zone.Records[0].Prio != 10 || zone.Records[0].Id != 1 {
+		t.Fail()
+		t.Logf("The snapshot %q should contain both zones with all fields (snapshot: %v, error: %v)", filePath, s, readError)
+	}
+}
+
+// backupAction.Execute should write a snapshot of the given domain to the given directory.
+func Test_backupAction_SingleDomain_SnapshotIsNamedAfterDomain(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testZone, nil
+		},
+	}
+
+	arguments := []string{"-domain", "Example.com", "-dir", "/backups"}
+	action := backupAction{testInfoProviderFactory{infoProvider, nil}, fs, "/home/user/.dee/backups"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || !strings.Contains(result.Text(), "Saved 3 record(s) of 1 domain(s) to /backups/example.com-") {
+		t.Fail()
+		t.Logf("backupAction.Execute(%q) should save the records of example.com (result: %v, error: %v)", arguments, result, err)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	actionNameClone = "clone"
+
+	cloneArguments     = flag.NewFlagSet(actionNameClone, flag.ContinueOnError)
+	cloneFrom          = cloneArguments.String("from", "", "The domain to copy the records from (e.g. example.com)")
+	cloneTo            = cloneArguments.String("to", "", "The domain to copy the records to (e.g. example.net)")
+	cloneName          = cloneArguments.String("name", "", "Only copy the records whose subdomain matches the glob pattern (e.g. \"www\" or \"api-*\")")
+	cloneTypes         = &stringList{}
+	cloneRewrites      = &stringList{}
+	cloneKeepHostnames = cloneArguments.Bool("keep-hostnames", false, "Do not replace the hostnames of the source domain in the contents of the records")
+	cloneConflict      = cloneArguments.String("conflict", cloneConflictFail, "What to do with records which already exist with different values (skip, overwrite, fail)")
+	cloneDryRun        = cloneArguments.Bool("dry-run", false, "Only show the changes without applying them")
+	cloneYes           = cloneArguments.Bool("yes", false, "Apply the changes without asking for confirmation")
+)
+
+// The supported values of the -conflict option.
+const (
+	cloneConflictSkip      = "skip"
+	cloneConflictOverwrite = "overwrite"
+	cloneConflictFail      = "fail"
+)
+
+func init() {
+	cloneArguments.Var(cloneTypes, "type", "Only copy records of the given types; can be given multiple times or as a comma-separated list (e.g. A,MX)")
+	cloneArguments.Var(cloneRewrites, "rewrite", "Replace a text in the contents of the records; can be given multiple times (e.g. \"old.example.com=new.example.net\")")
+}
+
+type cloneAction struct {
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	stdin               *os.File
+	stderr              io.Writer
+}
+
+func (action cloneAction) Name() string {
+	return actionNameClone
+}
+
+func (action cloneAction) Description() string {
+	return "Copy the records of one domain to another domain"
+}
+
+func (action cloneAction) Usage() string {
+	buf := new(bytes.Buffer)
+	cloneArguments.SetOutput(buf)
+	cloneArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute copies the matching records of the source domain to the target
+// domain. Hostnames of the source domain are replaced with the target domain.
+// Records which already exist in the target domain with different values
+// are skipped, overwritten or abort the clone depending on -conflict.
+func (action cloneAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*cloneFrom = ""
+	*cloneTo = ""
+	*cloneName = ""
+	*cloneTypes = stringList{}
+	*cloneRewrites = stringList{}
+	*cloneKeepHostnames = false
+	*cloneConflict = cloneConflictFail
+	*cloneDryRun = false
+	*cloneYes = false
+	if parseError := cloneArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	from, fromError := normalizeName(*cloneFrom)
+	if fromError != nil {
+		return nil, fromError
+	}
+
+	to, toError := normalizeName(*cloneTo)
+	if toError != nil {
+		return nil, toError
+	}
+
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("Please specify the source and the target domain with -from and -to")
+	}
+
+	if from == to {
+		return nil, fmt.Errorf("The source and the target domain are the same")
+	}
+
+	if *cloneConflict != cloneConflictSkip && *cloneConflict != cloneConflictOverwrite && *cloneConflict != cloneConflictFail {
+		return nil, fmt.Errorf("Invalid conflict option %q. Please use skip, overwrite or fail", *cloneConflict)
+	}
+
+	rewrites, rewritesError := parseRewriteRules(*cloneRewrites)
+	if rewritesError != nil {
+		return nil, rewritesError
+	}
+
+	filter, filterError := newRecordFilter(*cloneName, "", "", "")
+	if filterError != nil {
+		return nil, filterError
+	}
+
+	// get the records of both domains
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	sourceRecords, sourceError := infoProvider.GetDomainRecords(ctx, from)
+	if sourceError != nil {
+		return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", from, sourceError.Error())
+	}
+
+	targetRecords, targetError := infoProvider.GetDomainRecords(ctx, to)
+	if targetError != nil {
+		return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", to, targetError.Error())
+	}
+
+	// select and rewrite the records
+	var records []dnsimple.Record
+	for _, record := range withoutProviderRecords(sourceRecords) {
+		if filter.Matches(record) && (len(*cloneTypes) == 0 || containsRecordType(*cloneTypes, record.RecordType)) {
+			records = append(records, record)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("No matching records found in %s", toDisplayName(from))
+	}
+
+	if !*cloneKeepHostnames {
+		records = rewriteRecordContents(records, from, to)
+	}
+
+	records = applyRewriteRules(records, rewrites)
+
+	// plan the changes
+	plan, conflicts := newClonePlan(to, withoutProviderRecords(targetRecords), records, *cloneConflict == cloneConflictOverwrite)
+
+	if len(conflicts) > 0 && *cloneConflict == cloneConflictFail {
+		return nil, fmt.Errorf("%d record set(s) already exist in %s with different values:\n%s\nUse -conflict skip or -conflict overwrite", len(conflicts), toDisplayName(to), strings.Join(conflicts, "\n"))
+	}
+
+	skipped := ""
+	if len(conflicts) > 0 && *cloneConflict == cloneConflictSkip {
+		skipped = fmt.Sprintf("Skipping %d record set(s) which already exist with different values:\n%s\n\n", len(conflicts), strings.Join(conflicts, "\n"))
+	}
+
+	if plan.IsEmpty() {
+		return successMessage{fmt.Sprintf("%sNothing to clone. %s already has the records of %s", skipped, toDisplayName(to), toDisplayName(from))}, nil
+	}
+
+	if *cloneDryRun {
+		return successMessage{skipped + formatZonePlan(plan)}, nil
+	}
+
+	printf(action.stderr, "%s%s\n\n", skipped, formatZonePlan(plan))
+
+	if confirmError := confirmChanges(action.stdin, action.stderr, *cloneYes, len(plan.Changes), "Clone"); confirmError != nil {
+		return nil, confirmError
+	}
+
+	// apply the changes
+	editor, editorError := action.dnsEditorFactory.CreateDNSEditor()
+	if editorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", editorError.Error())
+	}
+
+	applied, applyError := plan.Apply(ctx, editor)
+	if applyError != nil {
+		return nil, fmt.Errorf("The clone failed after %d of %d change(s): %s", applied, len(plan.Changes), applyError.Error())
+	}
+
+	return successMessage{fmt.Sprintf("Cloned the records of %s to %s (%d change(s))", toDisplayName(from), toDisplayName(to), applied)}, nil
+}
+
+// newClonePlan returns the changes which copy the given records to the given
+// domain. Record sets (records with the same name and type) which do not exist
+// in the target records are created. Record sets which exist with different
+// values are returned as conflicts and only overwritten if requested.
+// A CNAME record conflicts with all other records with the same name.
+func newClonePlan(domain string, targetRecords, records []dnsimple.Record, overwrite bool) (zonePlan, []string) {
+	plan := zonePlan{Domain: domain}
+	var conflicts []string
+
+	// group the records by name and type
+	var recordSets [][]dnsimple.Record
+	for _, record := range records {
+		index := -1
+		for setIndex, recordSet := range recordSets {
+			if isSameRecordSet(recordSet[0], record) {
+				index = setIndex
+				break
+			}
+		}
+
+		if index < 0 {
+			recordSets = append(recordSets, nil)
+			index = len(recordSets) - 1
+		}
+
+		recordSets[index] = append(recordSets[index], record)
+	}
+
+	planned := make(map[int64]bool)
+	for _, recordSet := range recordSets {
+		var existingRecords []dnsimple.Record
+		for _, targetRecord := range targetRecords {
+			if isSameRecordSet(targetRecord, recordSet[0]) || (strings.EqualFold(targetRecord.Name, recordSet[0].Name) && (targetRecord.RecordType == "CNAME" || recordSet[0].RecordType == "CNAME")) {
+				existingRecords = append(existingRecords, targetRecord)
+			}
+		}
+
+		setPlan := newZonePlan(domain, existingRecords, recordSet)
+		if setPlan.IsEmpty() {
+			continue
+		}
+
+		if len(existingRecords) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s)", getFormattedDomainName(recordSet[0].Name, domain), recordSet[0].RecordType))
+			if !overwrite {
+				continue
+			}
+		}
+
+		// target records which conflict with multiple record sets are changed only once
+		for _, change := range setPlan.Changes {
+			if change.Current != nil {
+				if planned[change.Current.Id] {
+					continue
+				}
+
+				planned[change.Current.Id] = true
+			}
+
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return compareRecords(plan.Changes[i].Record(), plan.Changes[j].Record()) < 0
+	})
+
+	return plan, conflicts
+}
+
+// rewriteRule replaces a text in the contents of records.
+type rewriteRule struct {
+	old string
+	new string
+}
+
+// parseRewriteRules parses the given rules (e.g. "old.example.com=new.example.net").
+func parseRewriteRules(rules []string) ([]rewriteRule, error) {
+	var rewriteRules []rewriteRule
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid rewrite rule %q. Please use the format old=new", rule)
+		}
+
+		rewriteRules = append(rewriteRules, rewriteRule{parts[0], parts[1]})
+	}
+
+	return rewriteRules, nil
+}
+
+// applyRewriteRules replaces the texts of the given rules in the contents of the given records.
+func applyRewriteRules(records []dnsimple.Record, rules []rewriteRule) []dnsimple.Record {
+	rewritten := make([]dnsimple.Record, len(records))
+	for index, record := range records {
+		for _, rule := range rules {
+			record.Content = strings.Replace(record.Content, rule.old, rule.new, -1)
+		}
+
+		rewritten[index] = record
+	}
+
+	return rewritten
+}
+
+// containsRecordType returns true if the given list contains the given record type.
+func containsRecordType(recordTypes []string, recordType string) bool {
+	for _, value := range recordTypes {
+		if strings.EqualFold(value, recordType) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"strings"
+	"testing"
+)
+
+// testCloneZones contains the records of example.com and example.net.
+var testCloneZones = map[string][]dnsimple.Record{
+	"example.com": {
+		{Id: 1, Name: "", RecordType: "NS", Content: "ns1.dnsimple.com", Ttl: 3600},
+		{Id: 2, Name: "", RecordType: "MX", Content: "mail.example.com", Ttl: 3600, Prio: 10},
+		{Id: 3, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+		{Id: 4, Name: "api", RecordType: "CNAME", Content: "app.example.com", Ttl: 600},
+		{Id: 5, Name: "", RecordType: "TXT", Content: "v=spf1 include:example.com -all", Ttl: 600},
+	},
+	"example.net": {
+		{Id: 11, Name: "", RecordType: "NS", Content: "ns1.dnsimple.com", Ttl: 3600},
+		{Id: 12, Name: "www", RecordType: "A", Content: "5.6.7.8", Ttl: 600},
+	},
+}
+
+// cloneAction.Execute should fail without changing anything if a record set exists with different values.
+func Test_cloneAction_Conflict_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testCloneZones[domain], nil
+		},
+	}
+
+	action := cloneAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+	arguments := []string{"-from", "example.com", "-to", "example.net", "-yes"}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "www.example.net (A)") || len(changes) != 0 {
+		t.Fail()
+		t.Logf("cloneAction.Execute(%q) should fail because of the www record (changes: %v, error: %v)", arguments, changes, err)
+	}
+}
+
+// cloneAction.Execute should copy the records with rewritten hostnames and skip conflicts.
+func Test_cloneAction_SkipConflicts_RecordsAreCopied(t *testing.T) {
+	// arrange
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testCloneZones[domain], nil
+		},
+	}
+
+	action := cloneAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+	arguments := []string{"-from", "example.com", "-to", "example.net", "-conflict", "skip", "-rewrite", "-all=~all", "-yes"}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	expected := []string{
+		"create @ 3600 IN MX 10 mail.example.net",
+		`create @ 600 IN TXT "v=spf1 include:example.com ~all"`,
+		"create api 600 IN CNAME app.example.net",
+	}
+
+	if err != nil || strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Fail()
+		t.Logf("cloneAction.Execute(%q) made the changes\n%s\n(error: %v) but the following changes were expected\n%s", arguments, strings.Join(changes, "\n"), err, strings.Join(expected, "\n"))
+	}
+}
+
+// cloneAction.Execute should only copy the records matching the filters and overwrite conflicts.
+func Test_cloneAction_FiltersAndOverwrite_MatchingRecordsAreOverwritten(t *testing.T) {
+	// arrange
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testCloneZones[domain], nil
+		},
+	}
+
+	action := cloneAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+	arguments := []string{"-from", "example.com", "-to", "example.net", "-type", "A,CNAME", "-name", "w*", "-conflict", "overwrite", "-yes"}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || len(changes) != 1 || changes[0] != "update 12 1.2.3.4" {
+		t.Fail()
+		t.Logf("cloneAction.Execute(%q) should only overwrite the www record (changes: %v, error: %v)", arguments, changes, err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	actionNameConfig = "config"
+)
+
+type configAction struct {
+	configStore configStore
+	stderr      io.Writer
+}
+
+func (action configAction) Name() string {
+	return actionNameConfig
+}
+
+func (action configAction) Description() string {
+	return "Show or change the settings in the config file"
+}
+
+func (action configAction) Usage() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "  list\n")
+	fmt.Fprintf(buf, "    \tList all settings\n")
+	fmt.Fprintf(buf, "  get <key>\n")
+	fmt.Fprintf(buf, "    \tShow the value of a setting\n")
+	fmt.Fprintf(buf, "  set <key> <value>\n")
+	fmt.Fprintf(buf, "    \tChange the value of a setting\n")
+	fmt.Fprintf(buf, "  unset <key>\n")
+	fmt.Fprintf(buf, "    \tRemove a setting\n")
+	fmt.Fprintf(buf, "\n")
+	fmt.Fprintf(buf, "  Available settings:\n")
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 3, ' ', 0)
+	for _, key := range configKeys {
+		fmt.Fprintf(w, "    %s\t%s\n", key.Name, key.Description)
+	}
+
+	w.Flush()
+
+	return buf.String()
+}
+
+// Execute lists, reads or changes the settings of the config file.
+func (action configAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	if action.configStore == nil {
+		return nil, fmt.Errorf("No config store present")
+	}
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("No config command supplied (list, get, set, unset)")
+	}
+
+	command := strings.ToLower(arguments[0])
+	parameters := arguments[1:]
+
+	// set and unset replace a config file which cannot be parsed
+	config, configError := action.configStore.GetConfiguration()
+	unparseable := isConfigParseError(configError) && (command == "set" || command == "unset")
+	if unparseable {
+		printf(action.stderr, "Warning: %s\nThe content of the config file will be replaced.\n", configError.Error())
+		config = configuration{}
+	} else if configError != nil {
+		return nil, configError
+	}
+
+	switch command {
+
+	case "list":
+		if len(parameters) != 0 {
+			return nil, fmt.Errorf("Usage: config list")
+		}
+
+		return successMessage{formatConfiguration(config)}, nil
+
+	case "get":
+		if len(parameters) != 1 {
+			return nil, fmt.Errorf("Usage: config get <key>")
+		}
+
+		key := parameters[0]
+		if _, exists := getConfigKey(key); !exists {
+			return nil, fmt.Errorf("Unknown setting %q", key)
+		}
+
+		value, exists := config[key]
+		if !exists {
+			return nil, fmt.Errorf("The setting %q is not set", key)
+		}
+
+		return successMessage{value}, nil
+
+	case "set":
+		if len(parameters) != 2 {
+			return nil, fmt.Errorf("Usage: config set <key> <value>")
+		}
+
+		key, value := parameters[0], parameters[1]
+		configKey, exists := getConfigKey(key)
+		if !exists {
+			return nil, fmt.Errorf("Unknown setting %q", key)
+		}
+
+		if err := configKey.Validate(value); err != nil {
+			return nil, fmt.Errorf("Invalid value for %q: %s", key, err.Error())
+		}
+
+		config[key] = value
+		if err := action.configStore.SaveConfiguration(config); err != nil {
+			return nil, fmt.Errorf("Unable to save the configuration: %s", err.Error())
+		}
+
+		return successMessage{fmt.Sprintf("Set: %s = %s", key, value)}, nil
+
+	case "unset":
+		if len(parameters) != 1 {
+			return nil, fmt.Errorf("Usage: config unset <key>")
+		}
+
+		key := parameters[0]
+		if _, exists := config[key]; !exists && !unparseable {
+			return nil, fmt.Errorf("The setting %q is not set", key)
+		}
+
+		delete(config, key)
+		if err := action.configStore.SaveConfiguration(config); err != nil {
+			return nil, fmt.Errorf("Unable to save the configuration: %s", err.Error())
+		}
+
+		return successMessage{fmt.Sprintf("Unset: %s", key)}, nil
+
+	}
+
+	return nil, fmt.Errorf("Unknown config command %q (list, get, set, unset)", command)
+}
+
+// formatConfiguration formats the given configuration as a table.
+func formatConfiguration(config configuration) string {
+	buf := new(bytes.Buffer)
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 3, ' ', 0)
+
+	for index, key := range config.Keys() {
+		fmt.Fprintf(w, "%s\t%s", key, config[key])
+
+		if index < len(config)-1 {
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	w.Flush()
+
+	return buf.String()
+}
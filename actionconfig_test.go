@@ -0,0 +1,188 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/spf13/afero"
+	"strings"
+	"testing"
+)
+
+// newTestConfigStore returns an in-memory config store with the given configuration.
+func newTestConfigStore(config configuration) configStore {
+	store := newFilesystemConfigStore(afero.NewMemMapFs(), "/home/user/.dee/config.json")
+	store.SaveConfiguration(config)
+	return store
+}
+
+func Test_configAction_Name_ConfigIsReturned(t *testing.T) {
+	// act
+	result := configAction{}.Name()
+
+	// assert
+	if result != "config" {
+		t.Fail()
+		t.Logf("configAction.Name() should have returned %q but returned %q instead.", "config", result)
+	}
+}
+
+func Test_configAction_Usage_ResultContainsAllSettings(t *testing.T) {
+	// act
+	result := configAction{}.Usage()
+
+	// assert
+	for _, key := range configKeys {
+		if !strings.Contains(result, key.Name) {
+			t.Fail()
+			t.Logf("configAction.Usage() should list the setting %q.", key.Name)
+		}
+	}
+}
+
+func Test_configAction_SetAndGet_ValueIsStored(t *testing.T) {
+	// arrange
+	store := newTestConfigStore(configuration{})
+	action := configAction{store, nil}
+
+	// act
+	_, setError := action.Execute(context.Background(), []string{"set", "ttl", "60"})
+	result, getError := action.Execute(context.Background(), []string{"get", "ttl"})
+
+	// assert
+	if setError != nil || getError != nil || result.Text() != "60" {
+		t.Fail()
+		t.Logf("config get should return the value set with config set (Errors: %v, %v).", setError, getError)
+	}
+}
+
+func Test_configAction_List_AllSettingsAreListed(t *testing.T) {
+	// arrange
+	store := newTestConfigStore(configuration{"domain": "example.com", "ttl": "60"})
+	action := configAction{store, nil}
+
+	// act
+	result, err := action.Execute(context.Background(), []string{"list"})
+
+	// assert
+	if err != nil || !strings.Contains(result.Text(), "example.com") || !strings.Contains(result.Text(), "60") {
+		t.Fail()
+		t.Logf("config list should list all settings (Error: %s).", err)
+	}
+}
+
+func Test_configAction_Unset_ValueIsRemoved(t *testing.T) {
+	// arrange
+	store := newTestConfigStore(configuration{"domain": "example.com"})
+	action := configAction{store, nil}
+
+	// act
+	_, unsetError := action.Execute(context.Background(), []string{"unset", "domain"})
+	config, _ := store.GetConfiguration()
+
+	// assert
+	if unsetError != nil || len(config) != 0 {
+		t.Fail()
+		t.Logf("config unset should remove the setting (Error: %s).", unsetError)
+	}
+}
+
+func Test_configAction_InvalidCommands_ErrorIsReturned(t *testing.T) {
+	// arrange
+	argumentsSet := [][]string{
+		{},
+		{"remove", "ttl"},
+		{"get"},
+		{"get", "unknown"},
+		{"get", "domain"},
+		{"set", "ttl"},
+		{"set", "ttl", "-60"},
+		{"set", "unknown", "value"},
+		{"set", "format", "xml"},
+		{"unset", "domain"},
+	}
+
+	for _, arguments := range argumentsSet {
+		action := configAction{newTestConfigStore(configuration{}), nil}
+
+		// act
+		_, err := action.Execute(context.Background(), arguments)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("configAction.Execute(%q) should return an error.", arguments)
+		}
+	}
+}
+
+func Test_configAction_UnparseableConfigFile_SetReplacesTheFile(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/config.json", []byte(`{"ttl": "60",`), 0600)
+	store := newFilesystemConfigStore(fs, "/home/user/.dee/config.json")
+	stderr := new(bytes.Buffer)
+	action := configAction{store, stderr}
+
+	// act
+	_, setError := action.Execute(context.Background(), []string{"set", "domain", "example.com"})
+	config, getError := store.GetConfiguration()
+
+	// assert
+	if setError != nil || getError != nil || len(config) != 1 || config["domain"] != "example.com" {
+		t.Fail()
+		t.Logf("config set should replace the unparseable config file (Errors: %v, %v, Config: %v).", setError, getError, config)
+	}
+
+	if !strings.Contains(stderr.String(), "Warning: Unable to parse") {
+		t.Fail()
+		t.Logf("config set should warn that the config file will be replaced but printed %q.", stderr.String())
+	}
+}
+
+func Test_configAction_UnparseableConfigFile_UnsetReplacesTheFile(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/config.yaml", []byte("ttl 60\n"), 0600)
+	store := newFilesystemConfigStore(fs, "/home/user/.dee/config.yaml")
+	action := configAction{store, nil}
+
+	// act
+	_, unsetError := action.Execute(context.Background(), []string{"unset", "ttl"})
+	config, getError := store.GetConfiguration()
+
+	// assert
+	if unsetError != nil || getError != nil || len(config) != 0 {
+		t.Fail()
+		t.Logf("config unset should replace the unparseable config file (Errors: %v, %v, Config: %v).", unsetError, getError, config)
+	}
+}
+
+func Test_configAction_UnparseableConfigFile_ListAndGetReturnAnError(t *testing.T) {
+	// arrange
+	argumentsSet := [][]string{
+		{"list"},
+		{"get", "ttl"},
+	}
+
+	for _, arguments := range argumentsSet {
+		fs := afero.NewMemMapFs()
+		fs.MkdirAll("/home/user/.dee", 0700)
+		afero.WriteFile(fs, "/home/user/.dee/config.json", []byte(`{"ttl": "60",`), 0600)
+		action := configAction{newFilesystemConfigStore(fs, "/home/user/.dee/config.json"), nil}
+
+		// act
+		_, err := action.Execute(context.Background(), arguments)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("configAction.Execute(%q) should return an error if the config file cannot be parsed.", arguments)
+		}
+	}
+}
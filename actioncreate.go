@@ -6,9 +6,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"github.com/andreaskoch/dee-ns"
 	"net"
 	"os"
 )
@@ -24,8 +24,9 @@ var (
 )
 
 type createAction struct {
-	dnsEditorFactory dnsEditorCreator
-	stdin            *os.File
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	stdin               *os.File
 }
 
 func (action createAction) Name() string {
@@ -40,22 +41,35 @@ func (action createAction) Usage() string {
 	buf := new(bytes.Buffer)
 	createAddressRecordArguments.SetOutput(buf)
 	createAddressRecordArguments.PrintDefaults()
-	return buf.String()
+	return fmt.Sprintf("  [name] [ip] [arguments ...]\n\n%s", buf.String())
 }
 
 // Execute creates the DNS record of the domain given from the supplied arguments.
 // If the create fails an error is returned.
-func (action createAction) Execute(arguments []string) (message, error) {
+func (action createAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	// parse the arguments
-	*createDomain = ""
+	*createDomain = defaults.Domain
 	*createSubdomain = ""
 	*createIP = ""
-	*createTTL = defaultTTL
-	if parseError := createAddressRecordArguments.Parse(arguments); parseError != nil {
+	*createTTL = defaults.TTL
+	positionalArguments, parseError := parseArguments(createAddressRecordArguments, arguments)
+	if parseError != nil {
 		return nil, parseError
 	}
 
+	// positional arguments: name and ip
+	name, positionalError := getPositionalArguments(positionalArguments, createIP)
+	if positionalError != nil {
+		return nil, positionalError
+	}
+
+	var resolveError error
+	*createSubdomain, *createDomain, resolveError = resolveName(ctx, action.infoProviderFactory, name, *createSubdomain, *createDomain)
+	if resolveError != nil {
+		return nil, resolveError
+	}
+
 	// domain
 	if *createDomain == "" {
 		return nil, fmt.Errorf("No domain supplied")
@@ -83,15 +97,16 @@ func (action createAction) Execute(arguments []string) (message, error) {
 	}
 
 	// create a DNS editor
-	var addressRecordCreator deens.DNSRecordCreator
+	var addressRecordCreator dnsRecordCreator
 	addressRecordCreator, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
 	if dnsEditorError != nil {
 		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
 	}
 
-	createError := addressRecordCreator.CreateSubdomain(*createDomain, *createSubdomain, *createTTL, ip)
+	createError := addressRecordCreator.CreateSubdomain(ctx, *createDomain, *createSubdomain, *createTTL, ip)
 	if createError != nil {
-		return nil, fmt.Errorf("%s", createError.Error())
+		change := describeChange("creation", *createSubdomain, *createDomain, getDNSRecordTypeByIP(ip), ip.String())
+		return nil, getChangeError(ctx, change, createError)
 	}
 
 	return successMessage{fmt.Sprintf("Created: %s → %s", getFormattedDomainName(*createSubdomain, *createDomain), ip.String())}, nil
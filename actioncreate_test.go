@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strings"
@@ -148,12 +149,12 @@ func Test_createAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createAction.Execute(arguments)
+		_, err := createAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -217,12 +218,12 @@ func Test_createAction_InvalidArgumentValues_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createAction.Execute(arguments)
+		_, err := createAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -250,7 +251,7 @@ func Test_createAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	for _, invalidIP := range invalidIPs {
 
@@ -263,7 +264,7 @@ func Test_createAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T) {
 			"-ip",
 			invalidIP,
 		}
-		_, err := createAction.Execute(arguments)
+		_, err := createAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -332,12 +333,12 @@ func Test_createAction_ValidArguments_NoErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createAction.Execute(arguments)
+		_, err := createAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err != nil {
@@ -367,10 +368,10 @@ func Test_createAction_ValidArguments_CreateFails_ErrorIsReturned(t *testing.T)
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	// act
-	_, err := createAction.Execute(arguments)
+	_, err := createAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -399,10 +400,10 @@ func Test_createAction_ValidArguments_CreateSucceds_SuccessMessageIsReturned(t *
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	// act
-	response, _ := createAction.Execute(arguments)
+	response, _ := createAction.Execute(context.Background(), arguments)
 
 	// assert
 	if response == nil {
@@ -425,10 +426,10 @@ func Test_createAction_ValidArguments_DNSEditorCreationFails_ErrorIsReturned(t *
 
 	editorFactory := testDNSEditorFactory{nil, fmt.Errorf("Unable to create DNS editor")}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	// act
-	_, err := createAction.Execute(arguments)
+	_, err := createAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -457,10 +458,10 @@ func Test_createAction_ValidArguments_CreateSucceds_SuccessMessageContainsTheSub
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createAction := createAction{editorFactory, nil}
+	createAction := createAction{editorFactory, nil, nil}
 
 	// act
-	response, _ := createAction.Execute(arguments)
+	response, _ := createAction.Execute(context.Background(), arguments)
 
 	// assert
 	containsIP := strings.Contains(response.Text(), "2001:db8:0:42:0:8a2e:370:7334")
@@ -6,9 +6,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"github.com/andreaskoch/dee-ns"
 	"net"
 	"os"
 )
@@ -21,12 +21,20 @@ var (
 	createOrUpdateSubdomain              = createOrUpdateAddressRecordArguments.String("subdomain", "", "Subdomain (e.g. www)")
 	createOrUpdateIP                     = createOrUpdateAddressRecordArguments.String("ip", "", "IP address (e.g. ::1, 127.0.0.1)")
 	createOrUpdateTTL                    = createOrUpdateAddressRecordArguments.Int("ttl", defaultTTL, "The time to live in seconds")
+	createOrUpdateWait                   = createOrUpdateAddressRecordArguments.Bool("wait", false, "Wait until the authoritative nameservers return the new value")
+	createOrUpdateWaitTimeout            = createOrUpdateAddressRecordArguments.Duration("wait-timeout", defaultWaitTimeout, "How long to wait for the new value")
+	createOrUpdateResolvers              = &stringList{}
 )
 
+func init() {
+	createOrUpdateAddressRecordArguments.Var(createOrUpdateResolvers, "resolver", "A resolver which must return the new value as well if -wait is given (e.g. 8.8.8.8); can be given multiple times")
+}
+
 type createOrUpdateAction struct {
 	dnsEditorFactory    dnsEditorCreator
 	infoProviderFactory dnsInfoProviderCreator
 	stdin               *os.File
+	propagationWaiter   propagationWaiter
 }
 
 func (action createOrUpdateAction) Name() string {
@@ -39,32 +47,45 @@ func (action createOrUpdateAction) Description() string {
 
 func (action createOrUpdateAction) Usage() string {
 	buf := new(bytes.Buffer)
-	createAddressRecordArguments.SetOutput(buf)
-	createAddressRecordArguments.PrintDefaults()
-	return buf.String()
+	createOrUpdateAddressRecordArguments.SetOutput(buf)
+	createOrUpdateAddressRecordArguments.PrintDefaults()
+	return fmt.Sprintf("  [name] [ip] [arguments ...]\n\n%s", buf.String())
 }
 
 // Execute creates the DNS record of the domain given from the supplied arguments.
 // If the create fails an error is returned.
-func (action createOrUpdateAction) Execute(arguments []string) (message, error) {
+func (action createOrUpdateAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	// parse the arguments
-	*createOrUpdateDomain = ""
+	*createOrUpdateDomain = defaults.Domain
 	*createOrUpdateSubdomain = ""
 	*createOrUpdateIP = ""
-	*createOrUpdateTTL = defaultTTL
-	if parseError := createOrUpdateAddressRecordArguments.Parse(arguments); parseError != nil {
+	*createOrUpdateTTL = defaults.TTL
+	*createOrUpdateWait = false
+	*createOrUpdateWaitTimeout = defaultWaitTimeout
+	*createOrUpdateResolvers = stringList{}
+	positionalArguments, parseError := parseArguments(createOrUpdateAddressRecordArguments, arguments)
+	if parseError != nil {
 		return nil, parseError
 	}
 
+	// positional arguments: name and ip
+	name, positionalError := getPositionalArguments(positionalArguments, createOrUpdateIP)
+	if positionalError != nil {
+		return nil, positionalError
+	}
+
+	var resolveError error
+	*createOrUpdateSubdomain, *createOrUpdateDomain, resolveError = resolveName(ctx, action.infoProviderFactory, name, *createOrUpdateSubdomain, *createOrUpdateDomain)
+	if resolveError != nil {
+		return nil, resolveError
+	}
+
 	// domain
 	if *createOrUpdateDomain == "" {
 		return nil, fmt.Errorf("No domain supplied")
 	}
 
-	// subdomain
-	noSubdomainGiven := *createOrUpdateSubdomain == ""
-
 	// TTL
 	if *createOrUpdateTTL < 0 {
 		return nil, fmt.Errorf("The given TTL cannot be negative")
@@ -86,49 +107,83 @@ func (action createOrUpdateAction) Execute(arguments []string) (message, error)
 		return nil, fmt.Errorf("Cannot parse IP %q", ip)
 	}
 
+	// determine the record type
+	dnsRecordType := getDNSRecordTypeByIP(ip)
+
+	created, changeError := createOrUpdateAddress(ctx, action.dnsEditorFactory, action.infoProviderFactory, *createOrUpdateDomain, *createOrUpdateSubdomain, *createOrUpdateTTL, ip)
+	if changeError != nil {
+		return nil, changeError
+	}
+
+	result := fmt.Sprintf("Updated: %s → %s", getFormattedDomainName(*createOrUpdateSubdomain, *createOrUpdateDomain), ip.String())
+	if created {
+		result = fmt.Sprintf("Created: %s → %s", getFormattedDomainName(*createOrUpdateSubdomain, *createOrUpdateDomain), ip.String())
+	}
+
+	return action.waitForPropagation(ctx, result, dnsRecordType, ip)
+}
+
+// createOrUpdateAddress updates the address record of the given subdomain with
+// the given IP or creates the record if it does not exist. The address record of
+// the domain itself is always updated. Returns true if the record was created.
+func createOrUpdateAddress(ctx context.Context, dnsEditorFactory dnsEditorCreator, infoProviderFactory dnsInfoProviderCreator, domain, subdomain string, ttl int, ip net.IP) (bool, error) {
+
 	// create a DNS editor
-	var addressRecordEditor deens.DNSRecordEditor
-	addressRecordEditor, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	var addressRecordEditor dnsRecordEditor
+	addressRecordEditor, dnsEditorError := dnsEditorFactory.CreateDNSEditor()
 	if dnsEditorError != nil {
-		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
+		return false, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
 	}
 
 	// info provider
-	infoProvider, infoProviderError := action.getInfoProvider()
+	if infoProviderFactory == nil {
+		return false, fmt.Errorf("No DNS info provider available")
+	}
+
+	infoProvider, infoProviderError := infoProviderFactory.CreateInfoProvider()
 	if infoProviderError != nil {
-		return nil, fmt.Errorf("No DNS info provider available")
+		return false, fmt.Errorf("No DNS info provider available")
 	}
 
 	// determine the record type
 	dnsRecordType := getDNSRecordTypeByIP(ip)
 
-	_, domainRecordError := infoProvider.GetSubdomainRecord(*createOrUpdateDomain, *createOrUpdateSubdomain, dnsRecordType)
-	if domainRecordError == nil || noSubdomainGiven {
+	_, domainRecordError := infoProvider.GetSubdomainRecord(ctx, domain, subdomain, dnsRecordType)
+	if domainRecordError == nil || subdomain == "" {
 
 		// update
-		updateError := addressRecordEditor.UpdateSubdomain(*createOrUpdateDomain, *createOrUpdateSubdomain, ip)
+		updateError := addressRecordEditor.UpdateSubdomain(ctx, domain, subdomain, ip)
 		if updateError != nil {
-			return nil, fmt.Errorf("%s", updateError.Error())
+			change := describeChange("update", subdomain, domain, dnsRecordType, ip.String())
+			return false, getChangeError(ctx, change, updateError)
 		}
 
-		return successMessage{fmt.Sprintf("Updated: %s → %s", getFormattedDomainName(*createOrUpdateSubdomain, *createOrUpdateDomain), ip.String())}, nil
+		return false, nil
 
 	}
 
 	// create
-	createError := addressRecordEditor.CreateSubdomain(*createOrUpdateDomain, *createOrUpdateSubdomain, *createOrUpdateTTL, ip)
+	createError := addressRecordEditor.CreateSubdomain(ctx, domain, subdomain, ttl, ip)
 	if createError != nil {
-		return nil, fmt.Errorf("%s", createError.Error())
+		change := describeChange("creation", subdomain, domain, dnsRecordType, ip.String())
+		return false, getChangeError(ctx, change, createError)
 	}
 
-	return successMessage{fmt.Sprintf("Created: %s → %s", getFormattedDomainName(*createOrUpdateSubdomain, *createOrUpdateDomain), ip.String())}, nil
+	return true, nil
 }
 
-// getInfoProvider returns a DNS info provider instance or an error if the creation of the provider failed.
-func (action createOrUpdateAction) getInfoProvider() (deens.DNSInfoProvider, error) {
-	if action.infoProviderFactory == nil {
-		return nil, fmt.Errorf("No DNS info provider factory available")
+// waitForPropagation waits until the nameservers return the new IP address if -wait is given.
+func (action createOrUpdateAction) waitForPropagation(ctx context.Context, result, recordType string, ip net.IP) (message, error) {
+	if !*createOrUpdateWait {
+		return successMessage{result}, nil
 	}
 
-	return action.infoProviderFactory.CreateInfoProvider()
+	return waitForPropagation(ctx, action.propagationWaiter, result, propagationQuery{
+		Domain:     *createOrUpdateDomain,
+		Name:       getFullyQualifiedName(*createOrUpdateSubdomain, *createOrUpdateDomain),
+		RecordType: recordType,
+		Value:      ip.String(),
+		Resolvers:  *createOrUpdateResolvers,
+		Timeout:    *createOrUpdateWaitTimeout,
+	})
 }
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"net"
@@ -160,12 +161,12 @@ func Test_createOrUpdateAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createOrUpdateAction.Execute(arguments)
+		_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -237,12 +238,12 @@ func Test_createOrUpdateAction_InvalidArgumentValues_ErrorIsReturned(t *testing.
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createOrUpdateAction.Execute(arguments)
+		_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -281,7 +282,7 @@ func Test_createOrUpdateAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T)
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	for _, invalidIP := range invalidIPs {
 
@@ -294,7 +295,7 @@ func Test_createOrUpdateAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T)
 			"-ip",
 			invalidIP,
 		}
-		_, err := createOrUpdateAction.Execute(arguments)
+		_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -374,12 +375,12 @@ func Test_createOrUpdateAction_ValidArguments_NoErrorIsReturned(t *testing.T) {
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := createOrUpdateAction.Execute(arguments)
+		_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err != nil {
@@ -412,10 +413,10 @@ func Test_createOrUpdateAction_ValidArguments_NoInfoProvider_ErrorIsReturned(t *
 
 	editorFactory := testDNSEditorFactory{dnsCreator, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, nil, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, nil, nil, nil}
 
 	// act
-	_, err := createOrUpdateAction.Execute(arguments)
+	_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -455,10 +456,10 @@ func Test_createOrUpdateAction_ValidArguments_CreateFails_ErrorIsReturned(t *tes
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	_, err := createOrUpdateAction.Execute(arguments)
+	_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -505,10 +506,10 @@ func Test_createOrUpdateAction_ValidArguments_RecordAlreadyExists_UpdateIsExecut
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	createOrUpdateAction.Execute(arguments)
+	createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if updateWasCalled == false {
@@ -548,10 +549,10 @@ func Test_createOrUpdateAction_ValidArguments_CreateSucceds_SuccessMessageIsRetu
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	response, _ := createOrUpdateAction.Execute(arguments)
+	response, _ := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if response == nil {
@@ -582,10 +583,10 @@ func Test_createOrUpdateAction_ValidArguments_DNSEditorCreationFails_ErrorIsRetu
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	_, err := createOrUpdateAction.Execute(arguments)
+	_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -625,10 +626,10 @@ func Test_createOrUpdateAction_ValidArguments_UpdateSucceds_SuccessMessageContai
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	response, _ := createOrUpdateAction.Execute(arguments)
+	response, _ := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	containsIP := strings.Contains(response.Text(), "2001:db8:0:42:0:8a2e:370:7334")
@@ -669,10 +670,10 @@ func Test_createOrUpdateAction_ValidArguments_UpdateFails_ErrorIsReturned(t *tes
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	_, err := createOrUpdateAction.Execute(arguments)
+	_, err := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -712,10 +713,10 @@ func Test_createOrUpdateAction_ValidArguments_CreateSucceds_SuccessMessageContai
 
 	infoProviderFactory := testInfoProviderFactory{dnsInfoProvider, nil}
 
-	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil}
+	createOrUpdateAction := createOrUpdateAction{editorFactory, infoProviderFactory, nil, nil}
 
 	// act
-	response, _ := createOrUpdateAction.Execute(arguments)
+	response, _ := createOrUpdateAction.Execute(context.Background(), arguments)
 
 	// assert
 	containsIP := strings.Contains(response.Text(), "2001:db8:0:42:0:8a2e:370:7334")
@@ -6,9 +6,15 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
 )
 
 var (
@@ -16,12 +22,19 @@ var (
 
 	deleteAddressRecordArguments = flag.NewFlagSet(actionNameDelete, flag.ContinueOnError)
 	deleteDomain                 = deleteAddressRecordArguments.String("domain", "", "Domain (e.g. example.com)")
-	deleteSubdomain              = deleteAddressRecordArguments.String("subdomain", "", "Subdomain (e.g. www)")
+	deleteSubdomain              = deleteAddressRecordArguments.String("subdomain", "", "Subdomain or a glob pattern (e.g. www or \"old-*\")")
 	deleteRecordType             = deleteAddressRecordArguments.String("type", "", "The address record type (e.g. \"AAAA\")")
+	deleteID                     = deleteAddressRecordArguments.String("id", "", "The id of the record (instead of -subdomain and -type)")
+	deleteRegex                  = deleteAddressRecordArguments.String("regex", "", "Delete all records whose subdomain matches the regular expression (e.g. \"^test-[0-9]+$\")")
+	deleteContent                = deleteAddressRecordArguments.String("content", "", "Only delete records whose content matches the glob pattern (e.g. \"10.0.*\")")
+	deleteYes                    = deleteAddressRecordArguments.Bool("yes", false, "Delete multiple records without asking for confirmation")
 )
 
 type deleteAction struct {
-	dnsEditorFactory dnsEditorCreator
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	stdin               *os.File
+	stderr              io.Writer
 }
 
 func (action deleteAction) Name() string {
@@ -29,49 +42,237 @@ func (action deleteAction) Name() string {
 }
 
 func (action deleteAction) Description() string {
-	return "Delete an address record"
+	return "Delete an address record or all records matching a pattern"
 }
 
 func (action deleteAction) Usage() string {
 	buf := new(bytes.Buffer)
 	deleteAddressRecordArguments.SetOutput(buf)
 	deleteAddressRecordArguments.PrintDefaults()
-	return buf.String()
+	return fmt.Sprintf("  [name] [type] [arguments ...]\n\n%s", buf.String())
 }
 
 // Execute deletes the DNS record of the domain given from the supplied arguments.
 // If the delete fails an error is returned.
-func (action deleteAction) Execute(arguments []string) (message, error) {
+func (action deleteAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	// parse the arguments
-	*deleteDomain = ""
+	*deleteDomain = defaults.Domain
 	*deleteSubdomain = ""
 	*deleteRecordType = ""
-	if parseError := deleteAddressRecordArguments.Parse(arguments); parseError != nil {
+	*deleteID = ""
+	*deleteRegex = ""
+	*deleteContent = ""
+	*deleteYes = false
+	positionalArguments, parseError := parseArguments(deleteAddressRecordArguments, arguments)
+	if parseError != nil {
 		return nil, parseError
 	}
 
+	// positional arguments: name and record type
+	name, positionalError := getPositionalArguments(positionalArguments, deleteRecordType)
+	if positionalError != nil {
+		return nil, positionalError
+	}
+
+	var resolveError error
+	*deleteSubdomain, *deleteDomain, resolveError = resolveName(ctx, action.infoProviderFactory, name, *deleteSubdomain, *deleteDomain)
+	if resolveError != nil {
+		return nil, resolveError
+	}
+
 	// domain
 	if *deleteDomain == "" {
 		return nil, fmt.Errorf("No domain supplied")
 	}
 
+	// record id
+	if *deleteID != "" {
+		return action.deleteByID(ctx, *deleteDomain, *deleteID)
+	}
+
+	// record filters
+	if *deleteRegex != "" || *deleteContent != "" || isGlobPattern(*deleteSubdomain) {
+		return action.deleteMatching(ctx, *deleteDomain)
+	}
+
 	// subdomain
 	if *deleteRecordType == "" {
 		return nil, fmt.Errorf("No record type supplied")
 	}
 
 	// create a DNS editor
-	var addressRecordDeleter deens.DNSRecordDeleter
+	var addressRecordDeleter dnsRecordDeleter
 	addressRecordDeleter, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
 	if dnsEditorError != nil {
 		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
 	}
 
-	deleteError := addressRecordDeleter.DeleteSubdomain(*deleteDomain, *deleteSubdomain, *deleteRecordType)
+	deleteError := addressRecordDeleter.DeleteSubdomain(ctx, *deleteDomain, *deleteSubdomain, *deleteRecordType)
 	if deleteError != nil {
-		return nil, fmt.Errorf("%s", deleteError.Error())
+		change := describeChange("deletion", *deleteSubdomain, *deleteDomain, *deleteRecordType, "")
+		return nil, getChangeError(ctx, change, deleteError)
 	}
 
 	return successMessage{fmt.Sprintf("Deleted: %s (%s)", getFormattedDomainName(*deleteSubdomain, *deleteDomain), *deleteRecordType)}, nil
 }
+
+// deleteByID deletes the record with the given id.
+func (action deleteAction) deleteByID(ctx context.Context, domain, id string) (message, error) {
+	if *deleteSubdomain != "" || *deleteRecordType != "" {
+		return nil, fmt.Errorf("The -id option cannot be combined with -subdomain or -type")
+	}
+
+	// create a DNS editor
+	var recordDeleter dnsRecordDeleter
+	recordDeleter, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	if dnsEditorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
+	}
+
+	deleteError := recordDeleter.DeleteRecordByID(ctx, domain, id)
+	if deleteError != nil {
+		return nil, getChangeError(ctx, fmt.Sprintf("deletion of record %s of %s", id, domain), deleteError)
+	}
+
+	return successMessage{fmt.Sprintf("Deleted: record %s of %s", id, domain)}, nil
+}
+
+// deleteMatching deletes all records of the given domain which match the
+// subdomain pattern, the regular expression, the record type and the content
+// filter. The matching records are shown before they are deleted and must be
+// confirmed interactively unless -yes is given.
+func (action deleteAction) deleteMatching(ctx context.Context, domain string) (message, error) {
+	filter, filterError := newRecordFilter(*deleteSubdomain, *deleteRegex, *deleteRecordType, *deleteContent)
+	if filterError != nil {
+		return nil, filterError
+	}
+
+	// get the records of the domain
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	records, recordsError := infoProvider.GetDomainRecords(ctx, domain)
+	if recordsError != nil {
+		return nil, recordsError
+	}
+
+	// the SOA and NS records of the apex are managed by DNSimple
+	var matches []dnsimple.Record
+	for _, record := range withoutProviderRecords(records) {
+		if filter.Matches(record) {
+			matches = append(matches, record)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("No records of %s match the given filters", domain)
+	}
+
+	// show the records and ask for confirmation
+	printf(action.stderr, "The following %d record(s) will be deleted:\n%s\n", len(matches), formatDNSRecords(matches, domain))
+	if confirmError := confirmChanges(action.stdin, action.stderr, *deleteYes, len(matches), "Deletion"); confirmError != nil {
+		return nil, confirmError
+	}
+
+	// create a DNS editor
+	var recordDeleter dnsRecordDeleter
+	recordDeleter, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	if dnsEditorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
+	}
+
+	var deleted []string
+	for _, record := range matches {
+		recordName := getFormattedDomainName(record.Name, domain)
+		if deleteError := recordDeleter.DeleteRecordByID(ctx, domain, record.StringId()); deleteError != nil {
+			change := describeChange("deletion", record.Name, domain, record.RecordType, record.Content)
+			changeError := getChangeError(ctx, change, deleteError)
+			if len(deleted) == 0 {
+				return nil, changeError
+			}
+
+			return nil, fmt.Errorf("%s\n%s", strings.Join(deleted, "\n"), changeError.Error())
+		}
+
+		deleted = append(deleted, fmt.Sprintf("Deleted: %s (%s %s)", recordName, record.RecordType, record.Content))
+	}
+
+	return successMessage{strings.Join(deleted, "\n")}, nil
+}
+
+// recordFilter selects DNS records by subdomain, record type and content.
+type recordFilter struct {
+	namePattern    string
+	nameExpression *regexp.Regexp
+	recordType     string
+	contentPattern string
+}
+
+// newRecordFilter creates a new record filter. The subdomain and the content
+// are glob patterns (see path.Match); empty filters match all records. Use the
+// regular expression "^$" to select the records of the domain itself.
+func newRecordFilter(namePattern, nameExpression, recordType, contentPattern string) (recordFilter, error) {
+	filter := recordFilter{
+		namePattern:    strings.ToLower(namePattern),
+		recordType:     strings.ToUpper(recordType),
+		contentPattern: contentPattern,
+	}
+
+	if _, err := path.Match(filter.namePattern, ""); err != nil {
+		return recordFilter{}, fmt.Errorf("Invalid subdomain pattern %q: %s", namePattern, err.Error())
+	}
+
+	if _, err := path.Match(contentPattern, ""); err != nil {
+		return recordFilter{}, fmt.Errorf("Invalid content pattern %q: %s", contentPattern, err.Error())
+	}
+
+	if nameExpression != "" {
+		if namePattern != "" {
+			return recordFilter{}, fmt.Errorf("Please use only one of -subdomain and -regex")
+		}
+
+		expression, err := regexp.Compile(nameExpression)
+		if err != nil {
+			return recordFilter{}, fmt.Errorf("Invalid regular expression %q: %s", nameExpression, err.Error())
+		}
+
+		filter.nameExpression = expression
+	}
+
+	return filter, nil
+}
+
+// Matches returns true if the given record matches all criteria of the filter.
+func (filter recordFilter) Matches(record dnsimple.Record) bool {
+	name := strings.ToLower(record.Name)
+
+	if filter.nameExpression != nil {
+		if !filter.nameExpression.MatchString(name) {
+			return false
+		}
+	} else if filter.namePattern != "" {
+		if isMatch, _ := path.Match(filter.namePattern, name); !isMatch {
+			return false
+		}
+	}
+
+	if filter.recordType != "" && !strings.EqualFold(record.RecordType, filter.recordType) {
+		return false
+	}
+
+	if filter.contentPattern != "" {
+		if isMatch, _ := path.Match(filter.contentPattern, record.Content); !isMatch {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isGlobPattern returns true if the given text contains glob meta characters.
+func isGlobPattern(text string) bool {
+	return strings.ContainsAny(text, "*?[")
+}
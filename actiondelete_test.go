@@ -5,7 +5,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/pearkes/dnsimple"
 	"strings"
 	"testing"
 )
@@ -96,12 +98,12 @@ func Test_deleteAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := deleteAction.Execute(arguments)
+		_, err := deleteAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -141,12 +143,12 @@ func Test_deleteAction_InvalidArgumentValues_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := deleteAction.Execute(arguments)
+		_, err := deleteAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -183,12 +185,12 @@ func Test_deleteAction_ValidArguments_NoErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := deleteAction.Execute(arguments)
+		_, err := deleteAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err != nil {
@@ -218,10 +220,10 @@ func Test_deleteAction_ValidArguments_DeleteRespondsWithError_ErrorIsReturned(t
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	// act
-	_, err := deleteAction.Execute(arguments)
+	_, err := deleteAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -250,10 +252,10 @@ func Test_deleteAction_ValidArguments_DeleteSucceeds_SuccessMessageIsReturned(t
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	// act
-	response, _ := deleteAction.Execute(arguments)
+	response, _ := deleteAction.Execute(context.Background(), arguments)
 
 	// assert
 	if response == nil {
@@ -275,10 +277,10 @@ func Test_deleteAction_ValidArguments_DNSEditorCreationFails_ErrorIsReturned(t *
 	}
 
 	editorFactory := testDNSEditorFactory{nil, fmt.Errorf("Unable to create DNS Editor")}
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	// act
-	_, err := deleteAction.Execute(arguments)
+	_, err := deleteAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -307,10 +309,10 @@ func Test_deleteAction_ValidArguments_DeleteSucceeds_SuccessMessageContainsTheSu
 
 	editorFactory := testDNSEditorFactory{dnsDeleter, nil}
 
-	deleteAction := deleteAction{editorFactory}
+	deleteAction := deleteAction{editorFactory, nil, nil, nil}
 
 	// act
-	response, _ := deleteAction.Execute(arguments)
+	response, _ := deleteAction.Execute(context.Background(), arguments)
 
 	// assert
 	containsSubdomain := strings.Contains(response.Text(), "www")
@@ -322,3 +324,134 @@ func Test_deleteAction_ValidArguments_DeleteSucceeds_SuccessMessageContainsTheSu
 		t.Logf("deleteAction.Execute(%q) should respond with a success message that contains the domain, subdomain and record type but responded with %q instead.", arguments, response.Text())
 	}
 }
+
+// deleteAction.Execute should delete the record with the given id.
+func Test_deleteAction_IDGiven_RecordIsDeletedByID(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-id", "12"}
+
+	deletedID := ""
+	editor := &testDNSEditor{
+		deleteRecordByIDFunc: func(domain, id string) error {
+			deletedID = id
+			return nil
+		},
+	}
+
+	deleteAction := deleteAction{testDNSEditorFactory{editor, nil}, nil, nil, nil}
+
+	// act
+	_, err := deleteAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || deletedID != "12" {
+		t.Fail()
+		t.Logf("deleteAction.Execute(%q) should delete the record 12 (error: %v, deleted: %q)", arguments, err, deletedID)
+	}
+}
+
+// deleteAction.Execute should return an error if -id is combined with -subdomain.
+func Test_deleteAction_IDAndSubdomainGiven_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-id", "12"}
+	deleteAction := deleteAction{testDNSEditorFactory{&testDNSEditor{}, nil}, nil, nil, nil}
+
+	// act
+	_, err := deleteAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("deleteAction.Execute(%q) should return an error because -id cannot be combined with -subdomain", arguments)
+	}
+}
+
+var testDeleteZone = []dnsimple.Record{
+	{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4"},
+	{Id: 2, Name: "old-api", RecordType: "A", Content: "10.0.0.1"},
+	{Id: 3, Name: "old-api", RecordType: "AAAA", Content: "2001:db8::1"},
+	{Id: 4, Name: "old-web", RecordType: "CNAME", Content: "web.example.net"},
+	{Id: 5, Name: "", RecordType: "SOA", Content: "ns1.dnsimple.com admin.dnsimple.com 1 86400 7200 604800 300"},
+	{Id: 6, Name: "", RecordType: "NS", Content: "ns1.dnsimple.com"},
+}
+
+// deleteAction.Execute should delete all records matching the filters if -yes is given.
+func Test_deleteAction_FiltersGiven_MatchingRecordsAreDeleted(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		arguments []string
+		changes   string
+	}{
+		{[]string{"-domain", "example.com", "-subdomain", "old-*", "-yes"}, "delete 2, delete 3, delete 4"},
+		{[]string{"-domain", "example.com", "-subdomain", "old-*", "-type", "A", "-yes"}, "delete 2"},
+		{[]string{"-domain", "example.com", "-regex", "^old-(api|web)$", "-type", "aaaa", "-yes"}, "delete 3"},
+		{[]string{"-domain", "example.com", "-content", "10.0.*", "-yes"}, "delete 2"},
+		{[]string{"-domain", "example.com", "-content", "*", "-yes"}, "delete 1, delete 2, delete 3, delete 4"},
+	}
+
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDeleteZone, nil
+		},
+	}
+
+	for _, input := range inputs {
+		var changes []string
+		action := deleteAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+
+		// act
+		_, err := action.Execute(context.Background(), input.arguments)
+
+		// assert
+		if err != nil || strings.Join(changes, ", ") != input.changes {
+			t.Fail()
+			t.Logf("deleteAction.Execute(%q) should make the changes %q but made %q (error: %v)", input.arguments, input.changes, changes, err)
+		}
+	}
+}
+
+// deleteAction.Execute should not delete multiple records without confirmation in non-interactive runs.
+func Test_deleteAction_FiltersGivenWithoutYes_NonInteractive_NothingIsDeleted(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "old-*"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDeleteZone, nil
+		},
+	}
+
+	action := deleteAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil || len(changes) > 0 {
+		t.Fail()
+		t.Logf("deleteAction.Execute(%q) should refuse to delete records without -yes (changes: %q)", arguments, changes)
+	}
+}
+
+// deleteAction.Execute should return an error if no record matches the filters.
+func Test_deleteAction_NoRecordMatches_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "test-*", "-yes"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDeleteZone, nil
+		},
+	}
+
+	action := deleteAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("deleteAction.Execute(%q) should return an error because no record matches", arguments)
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	actionNameDiff = "diff"
+
+	diffArguments = flag.NewFlagSet(actionNameDiff, flag.ContinueOnError)
+	diffFormat    = diffArguments.String("format", outputFormatText, "The output format (text, json)")
+	diffColor     = diffArguments.String("color", colorAuto, "Colorize the output (auto, always, never)")
+)
+
+// The supported values of the -color option.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// The terminal colors of the diff output.
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorBold  = "\x1b[1m"
+	colorReset = "\x1b[0m"
+)
+
+type diffAction struct {
+	infoProviderFactory dnsInfoProviderCreator
+	fs                  afero.Fs
+	stdout              *os.File
+}
+
+func (action diffAction) Name() string {
+	return actionNameDiff
+}
+
+func (action diffAction) Description() string {
+	return "Compare the records of two domains, snapshots or zone files"
+}
+
+func (action diffAction) Usage() string {
+	buf := new(bytes.Buffer)
+	diffArguments.SetOutput(buf)
+	diffArguments.PrintDefaults()
+	return fmt.Sprintf("  <from> <to> [arguments ...]\n\n%s", buf.String())
+}
+
+// zoneSource contains the records of a live domain, a snapshot or a zone file.
+// The names of the records are relative to the origin.
+type zoneSource struct {
+	Label   string
+	Origin  string
+	Domain  string
+	Records []dnsimple.Record
+}
+
+// zoneDiff contains the records which differ between two zone sources.
+type zoneDiff struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Changes []recordDiff `json:"changes"`
+}
+
+// recordDiff is a record which was added, removed or changed.
+type recordDiff struct {
+	Change string           `json:"change"`
+	From   *dnsimple.Record `json:"from,omitempty"`
+	To     *dnsimple.Record `json:"to,omitempty"`
+}
+
+// Execute compares the records of the two given sources. A source can be a
+// live domain or subdomain (e.g. example.com or staging.example.com), a snapshot
+// or a zone file. If the sources have different origins the records of the
+// first source are rewritten to the origin of the second one.
+func (action diffAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*diffFormat = defaults.Format
+	*diffColor = colorAuto
+	positionalArguments, parseError := parseArguments(diffArguments, arguments)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	if formatError := validateOutputFormat(*diffFormat); formatError != nil {
+		return nil, formatError
+	}
+
+	if *diffColor != colorAuto && *diffColor != colorAlways && *diffColor != colorNever {
+		return nil, fmt.Errorf("Invalid color option %q. Please use auto, always or never", *diffColor)
+	}
+
+	if len(positionalArguments) != 2 {
+		return nil, fmt.Errorf("Please specify the two zones to compare (e.g. staging.example.com example.com)")
+	}
+
+	// load the sources
+	from, fromError := action.loadZoneSource(ctx, positionalArguments[0])
+	if fromError != nil {
+		return nil, fromError
+	}
+
+	to, toError := action.loadZoneSource(ctx, positionalArguments[1])
+	if toError != nil {
+		return nil, toError
+	}
+
+	// a subdomain of the same domain is not part of the other zone
+	if from.Domain != "" && from.Domain == to.Domain {
+		if subtree, isMatch := splitName(from.Origin, to.Origin); isMatch && subtree != "" {
+			to.Records = excludeSubtree(to.Records, subtree)
+		}
+
+		if subtree, isMatch := splitName(to.Origin, from.Origin); isMatch && subtree != "" {
+			from.Records = excludeSubtree(from.Records, subtree)
+		}
+	}
+
+	if from.Origin != to.Origin {
+		from.Records = rewriteRecordContents(from.Records, from.Origin, to.Origin)
+	}
+
+	diff := newZoneDiff(from, to)
+
+	if *diffFormat == outputFormatJSON {
+		return formatJSON(diff)
+	}
+
+	if len(diff.Changes) == 0 {
+		return successMessage{fmt.Sprintf("No differences between %s and %s", from.Label, to.Label)}, nil
+	}
+
+	return successMessage{formatZoneDiff(diff, action.useColors())}, nil
+}
+
+// loadZoneSource returns the records of the given source. Files with the
+// extension .json are read as snapshots, all other files as zone files. The
+// domain of a snapshot or the origin of a zone file can be selected with a
+// hash (e.g. backup.json#example.com). All other sources are live domains.
+func (action diffAction) loadZoneSource(ctx context.Context, source string) (zoneSource, error) {
+	filePath, domain := source, ""
+	if index := strings.LastIndex(source, "#"); index >= 0 {
+		filePath, domain = source[:index], source[index+1:]
+	}
+
+	if info, statError := action.fs.Stat(filePath); statError == nil && !info.IsDir() {
+		domain, domainError := normalizeName(domain)
+		if domainError != nil {
+			return zoneSource{}, domainError
+		}
+
+		if strings.HasSuffix(strings.ToLower(filePath), ".json") {
+			return loadSnapshotSource(action.fs, filePath, domain)
+		}
+
+		return loadZoneFileSource(action.fs, filePath, domain)
+	}
+
+	subdomain, domain, nameError := resolveName(ctx, action.infoProviderFactory, source, "", "")
+	if nameError != nil {
+		return zoneSource{}, fmt.Errorf("%q is neither a file nor one of your domains: %s", source, nameError.Error())
+	}
+
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return zoneSource{}, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	records, recordsError := infoProvider.GetDomainRecords(ctx, domain)
+	if recordsError != nil {
+		return zoneSource{}, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", domain, recordsError.Error())
+	}
+
+	origin := getFullyQualifiedName(subdomain, domain)
+	if subdomain != "" {
+		records = getSubtreeRecords(records, subdomain)
+	}
+
+	return zoneSource{Label: toDisplayName(origin), Origin: origin, Domain: domain, Records: records}, nil
+}
+
+// loadSnapshotSource returns the records of the given domain of the given snapshot.
+// The domain can be omitted if the snapshot contains a single domain.
+func loadSnapshotSource(fs afero.Fs, filePath, domain string) (zoneSource, error) {
+	s, snapshotError := readSnapshot(fs, filePath)
+	if snapshotError != nil {
+		return zoneSource{}, snapshotError
+	}
+
+	if domain == "" && len(s.Zones) != 1 {
+		return zoneSource{}, fmt.Errorf("The snapshot %q contains %d domains. Please select one with %s#<domain>", filePath, len(s.Zones), filePath)
+	}
+
+	zone := s.Zones[0]
+	if domain != "" {
+		var exists bool
+		if zone, exists = s.Zone(domain); !exists {
+			return zoneSource{}, fmt.Errorf("The snapshot %q does not contain the domain %s", filePath, toDisplayName(domain))
+		}
+	}
+
+	return zoneSource{Label: fmt.Sprintf("%s (%s)", filePath, toDisplayName(zone.Domain)), Origin: zone.Domain, Records: zone.Records}, nil
+}
+
+// loadZoneFileSource returns the records of the given zone file.
+func loadZoneFileSource(fs afero.Fs, filePath, origin string) (zoneSource, error) {
+	file, openError := fs.Open(filePath)
+	if openError != nil {
+		return zoneSource{}, openError
+	}
+
+	defer file.Close()
+
+	zone, parseError := parseZoneFile(file, origin, int64(defaults.TTL))
+	if parseError != nil {
+		return zoneSource{}, fmt.Errorf("The zone file %q is invalid: %s", filePath, parseError.Error())
+	}
+
+	return zoneSource{Label: filePath, Origin: zone.Origin, Records: zone.Records}, nil
+}
+
+// newZoneDiff returns the records which differ between the given sources.
+func newZoneDiff(from, to zoneSource) zoneDiff {
+	diff := zoneDiff{From: from.Label, To: to.Label, Changes: []recordDiff{}}
+
+	plan := newZonePlan(to.Origin, from.Records, to.Records)
+	for _, change := range plan.Changes {
+		switch change.Operation {
+		case planOperationCreate:
+			diff.Changes = append(diff.Changes, recordDiff{Change: "added", To: change.Desired})
+
+		case planOperationUpdate:
+			diff.Changes = append(diff.Changes, recordDiff{Change: "changed", From: change.Current, To: change.Desired})
+
+		case planOperationDelete:
+			diff.Changes = append(diff.Changes, recordDiff{Change: "removed", From: change.Current})
+		}
+	}
+
+	return diff
+}
+
+// formatZoneDiff formats the given diff in the unified diff format
+// with one line per record in the zone file format.
+func formatZoneDiff(diff zoneDiff, useColors bool) string {
+	colorize := func(color, text string) string {
+		if !useColors {
+			return text
+		}
+
+		return color + text + colorReset
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%s\n", colorize(colorBold, "--- "+diff.From))
+	fmt.Fprintf(buf, "%s\n", colorize(colorBold, "+++ "+diff.To))
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+
+	var lines []string
+	for _, change := range diff.Changes {
+		if change.From != nil {
+			lines = append(lines, colorize(colorRed, "- "+strings.Join(getZoneFileFields(*change.From), "\t")))
+		}
+
+		if change.To != nil {
+			lines = append(lines, colorize(colorGreen, "+ "+strings.Join(getZoneFileFields(*change.To), "\t")))
+		}
+	}
+
+	fmt.Fprintf(w, "%s", strings.Join(lines, "\n"))
+	w.Flush()
+
+	return buf.String()
+}
+
+// useColors returns true if the diff shall be colorized.
+func (action diffAction) useColors() bool {
+	switch *diffColor {
+	case colorAlways:
+		return true
+
+	case colorNever:
+		return false
+	}
+
+	return isTerminal(action.stdout) && os.Getenv("NO_COLOR") == ""
+}
+
+// getSubtreeRecords returns the records which belong to the given subdomain
+// with names relative to it (e.g. "www.staging" becomes "www").
+func getSubtreeRecords(records []dnsimple.Record, subdomain string) []dnsimple.Record {
+	var subtree []dnsimple.Record
+	for _, record := range records {
+		if name, isMatch := splitName(strings.ToLower(record.Name), subdomain); isMatch {
+			record.Name = name
+			subtree = append(subtree, record)
+		}
+	}
+
+	return subtree
+}
+
+// excludeSubtree returns the given records without the records of the given subdomain.
+func excludeSubtree(records []dnsimple.Record, subdomain string) []dnsimple.Record {
+	var filtered []dnsimple.Record
+	for _, record := range records {
+		if _, isMatch := splitName(strings.ToLower(record.Name), subdomain); !isMatch {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered
+}
+
+// rewriteRecordContents replaces the given origin with the new origin in the
+// contents of all records which point to a name (e.g. the CNAME content
+// api.staging.example.com becomes api.example.com).
+func rewriteRecordContents(records []dnsimple.Record, origin, newOrigin string) []dnsimple.Record {
+	rewriteName := func(name string) string {
+		if prefix, isMatch := splitName(strings.ToLower(name), origin); isMatch {
+			return getFullyQualifiedName(prefix, newOrigin)
+		}
+
+		return name
+	}
+
+	rewritten := make([]dnsimple.Record, len(records))
+	for index, record := range records {
+		switch record.RecordType {
+		case "ALIAS", "CNAME", "MX", "NS", "PTR":
+			record.Content = rewriteName(record.Content)
+
+		case "SRV":
+			fields := strings.Fields(record.Content)
+			if len(fields) > 0 {
+				fields[len(fields)-1] = rewriteName(fields[len(fields)-1])
+				record.Content = strings.Join(fields, " ")
+			}
+		}
+
+		rewritten[index] = record
+	}
+
+	return rewritten
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"testing"
+)
+
+// testDiffZone contains the records of example.com which has a staging subdomain.
+var testDiffZone = []dnsimple.Record{
+	{Id: 1, Name: "", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+	{Id: 2, Name: "api", RecordType: "CNAME", Content: "app.example.com", Ttl: 600},
+	{Id: 3, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+	{Id: 4, Name: "staging", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+	{Id: 5, Name: "api.staging", RecordType: "CNAME", Content: "app.staging.example.com", Ttl: 600},
+	{Id: 6, Name: "www.staging", RecordType: "A", Content: "10.0.0.1", Ttl: 60},
+}
+
+// diffAction.Execute should compare a subdomain with its domain by rewriting the names.
+func Test_diffAction_SubdomainAndDomain_RewrittenRecordsAreCompared(t *testing.T) {
+	// arrange
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDiffZone, nil
+		},
+	}
+
+	action := diffAction{testInfoProviderFactory{infoProvider, nil}, afero.NewMemMapFs(), nil}
+	arguments := []string{"staging.example.com", "example.com", "-format", "text"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	expected := "--- staging.example.com\n" +
+		"+++ example.com\n" +
+		"- www  60   IN  A  10.0.0.1\n" +
+		"+ www  600  IN  A  1.2.3.4"
+	if err != nil || result.Text() != expected {
+		t.Fail()
+		t.Logf("diffAction.Execute(%q) returned\n%v\n(error: %v) but the following diff was expected\n%s", arguments, result, err, expected)
+	}
+}
+
+// diffAction.Execute should compare a zone file with a live domain and return JSON.
+func Test_diffAction_ZoneFileAndDomain_JSONIsReturned(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/zones/example.com.zone", []byte("$ORIGIN example.com.\n@ 600 IN A 1.2.3.4\nwww 600 IN A 1.2.3.4\napi 600 IN CNAME app.example.com.\nftp 600 IN CNAME www.example.com.\n"), 0600)
+
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDiffZone, nil
+		},
+	}
+
+	action := diffAction{testInfoProviderFactory{infoProvider, nil}, fs, nil}
+	arguments := []string{"example.com", "/zones/example.com.zone", "-format", "json"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	var diff zoneDiff
+	if err != nil || json.Unmarshal([]byte(result.Text()), &diff) != nil {
+		t.Fail()
+		t.Logf("diffAction.Execute(%q) should return JSON (result: %v, error: %v)", arguments, result, err)
+		return
+	}
+
+	if len(diff.Changes) != 4 || diff.Changes[0].Change != "removed" || diff.Changes[1].Change != "added" || diff.Changes[1].To.Name != "ftp" {
+		t.Fail()
+		t.Logf("The diff should contain the staging records as removed and ftp as added: %s", result.Text())
+	}
+}
+
+// diffAction.Execute should report that there are no differences between identical zones.
+func Test_diffAction_SameDomain_NoDifferencesAreFound(t *testing.T) {
+	// arrange
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testDiffZone, nil
+		},
+	}
+
+	action := diffAction{testInfoProviderFactory{infoProvider, nil}, afero.NewMemMapFs(), nil}
+	arguments := []string{"example.com", "example.com"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || result.Text() != "No differences between example.com and example.com" {
+		t.Fail()
+		t.Logf("diffAction.Execute(%q) should not find differences (result: %v, error: %v)", arguments, result, err)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+var (
+	actionNameEdit = "edit"
+
+	editArguments = flag.NewFlagSet(actionNameEdit, flag.ContinueOnError)
+	editDomain    = editArguments.String("domain", "", "Domain (e.g. example.com)")
+	editYes       = editArguments.Bool("yes", false, "Apply the changes without asking for confirmation")
+)
+
+// editErrorPrefix marks the lines which report the errors of the last edit.
+const editErrorPrefix = "; ERROR: "
+
+// editHeader is written above the records of the edited zone.
+const editHeader = `; Edit the records of %s and close the editor to review the changes.
+; The SOA and NS records of the domain are managed by DNSimple and not listed.
+; Lines starting with a semicolon are ignored. Remove all records to cancel.
+;
+`
+
+type editAction struct {
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	fs                  afero.Fs
+	openEditor          func(filePath string) error
+	stdin               *os.File
+	stderr              io.Writer
+}
+
+func (action editAction) Name() string {
+	return actionNameEdit
+}
+
+func (action editAction) Description() string {
+	return "Edit the records of a domain in your text editor"
+}
+
+func (action editAction) Usage() string {
+	buf := new(bytes.Buffer)
+	editArguments.SetOutput(buf)
+	editArguments.PrintDefaults()
+	return fmt.Sprintf("  [domain] [arguments ...]\n\n%s", buf.String())
+}
+
+// Execute opens the records of the given domain as a zone file in the
+// editor of the user. The changes are validated, shown and applied after
+// confirmation. If the file is invalid the editor is opened again.
+func (action editAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*editDomain = defaults.Domain
+	*editYes = false
+	positionalArguments, parseError := parseArguments(editArguments, arguments)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	if len(positionalArguments) > 1 {
+		return nil, fmt.Errorf("Too many arguments: %s", strings.Join(positionalArguments[1:], " "))
+	}
+
+	if len(positionalArguments) == 1 {
+		*editDomain = positionalArguments[0]
+	}
+
+	domain, domainError := normalizeName(*editDomain)
+	if domainError != nil {
+		return nil, domainError
+	}
+
+	if domain == "" {
+		return nil, fmt.Errorf("No domain supplied")
+	}
+
+	// get the records
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	records, recordsError := infoProvider.GetDomainRecords(ctx, domain)
+	if recordsError != nil {
+		return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", domain, recordsError.Error())
+	}
+
+	editableRecords := withoutProviderRecords(records)
+	sort.SliceStable(editableRecords, func(i, j int) bool {
+		return compareRecords(editableRecords[i], editableRecords[j]) < 0
+	})
+
+	// edit the records
+	folder, folderError := afero.TempDir(action.fs, "", "dee-edit-")
+	if folderError != nil {
+		return nil, fmt.Errorf("Unable to create a temporary folder: %s", folderError.Error())
+	}
+
+	filePath := filepath.Join(folder, domain+".zone")
+	content := fmt.Sprintf(editHeader, toDisplayName(domain)) + formatZoneFile(domain, editableRecords)
+
+	editedRecords, editError := action.edit(filePath, domain, content)
+	if editError != nil {
+		return nil, editError
+	}
+
+	// the edited file is kept if the changes cannot be applied
+	keepFile := true
+	defer func() {
+		if !keepFile {
+			action.fs.RemoveAll(folder)
+		}
+	}()
+
+	if editedRecords == nil {
+		keepFile = false
+		return successMessage{"Edit cancelled. No records were changed"}, nil
+	}
+
+	plan := newZonePlan(domain, records, editedRecords)
+	if plan.IsEmpty() {
+		keepFile = false
+		return successMessage{fmt.Sprintf("No changes. The records of %s are unchanged", toDisplayName(domain))}, nil
+	}
+
+	printf(action.stderr, "%s\n\n", formatZonePlan(plan))
+
+	if confirmError := confirmChanges(action.stdin, action.stderr, *editYes, len(plan.Changes), "Edit"); confirmError != nil {
+		return nil, fmt.Errorf("%s. Your changes are saved in %s", confirmError.Error(), filePath)
+	}
+
+	// apply the changes
+	editor, editorError := action.dnsEditorFactory.CreateDNSEditor()
+	if editorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", editorError.Error())
+	}
+
+	applied, applyError := plan.Apply(ctx, editor)
+	if applyError != nil {
+		return nil, fmt.Errorf("The edit failed after %d of %d change(s): %s. Your changes are saved in %s", applied, len(plan.Changes), applyError.Error(), filePath)
+	}
+
+	keepFile = false
+	return successMessage{fmt.Sprintf("Applied %d change(s) to %s", applied, toDisplayName(domain))}, nil
+}
+
+// edit writes the given content to the given file and opens it in the editor
+// until it contains valid records or is saved without changes after an error.
+// Returns nil records if all records were removed.
+func (action editAction) edit(filePath, domain, content string) ([]dnsimple.Record, error) {
+	for {
+		if writeError := afero.WriteFile(action.fs, filePath, []byte(content), 0600); writeError != nil {
+			return nil, fmt.Errorf("Unable to write %s: %s", filePath, writeError.Error())
+		}
+
+		if editorError := action.openEditor(filePath); editorError != nil {
+			return nil, fmt.Errorf("The editor failed: %s. Your changes are saved in %s", editorError.Error(), filePath)
+		}
+
+		editedContent, readError := afero.ReadFile(action.fs, filePath)
+		if readError != nil {
+			return nil, fmt.Errorf("Unable to read %s: %s", filePath, readError.Error())
+		}
+
+		records, validationError := parseEditedZone(bytes.NewReader(editedContent), domain)
+		if validationError == nil {
+			return records, nil
+		}
+
+		// give up if an invalid file is saved without changes
+		if string(editedContent) == content && strings.HasPrefix(content, editErrorPrefix) {
+			return nil, fmt.Errorf("Edit cancelled: %s. Your changes are saved in %s", validationError.Error(), filePath)
+		}
+
+		content = addEditError(string(editedContent), validationError)
+	}
+}
+
+// parseEditedZone parses and validates the records of the given zone file.
+// Returns nil records if the file contains no records.
+func parseEditedZone(reader io.Reader, domain string) ([]dnsimple.Record, error) {
+	zone, parseError := parseZoneFile(reader, domain, int64(defaults.TTL))
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	if validationError := validateZoneRecords(domain, zone.Records); validationError != nil {
+		return nil, validationError
+	}
+
+	return zone.Records, nil
+}
+
+// validateZoneRecords returns an error if the given records cannot be saved:
+// invalid addresses, duplicate records, CNAME records which are not the only
+// record of a name or records which are managed by the DNS provider.
+func validateZoneRecords(domain string, records []dnsimple.Record) error {
+	for index, record := range records {
+		name := fmt.Sprintf("%s (%s)", getFormattedDomainName(record.Name, domain), record.RecordType)
+
+		if isProviderRecord(record) {
+			return fmt.Errorf("%s: The SOA and NS records of the domain cannot be edited", name)
+		}
+
+		if record.RecordType == "A" || record.RecordType == "AAAA" {
+			ip := net.ParseIP(record.Content)
+			if ip == nil || getDNSRecordTypeByIP(ip) != record.RecordType {
+				return fmt.Errorf("%s: %q is not a valid %s record address", name, record.Content, record.RecordType)
+			}
+		}
+
+		for _, otherRecord := range records[:index] {
+			if !strings.EqualFold(otherRecord.Name, record.Name) {
+				continue
+			}
+
+			if otherRecord.RecordType == record.RecordType && otherRecord.Content == record.Content {
+				return fmt.Errorf("%s: The record %q is listed twice", name, record.Content)
+			}
+
+			if otherRecord.RecordType != record.RecordType && (otherRecord.RecordType == "CNAME" || record.RecordType == "CNAME") {
+				return fmt.Errorf("%s: A CNAME record cannot be combined with other records of the same name", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addEditError returns the given content with the given error at the top.
+// The errors of previous edits are removed.
+func addEditError(content string, err error) string {
+	lines := strings.Split(content, "\n")
+	for len(lines) > 0 && strings.HasPrefix(lines[0], editErrorPrefix) {
+		lines = lines[1:]
+	}
+
+	return editErrorPrefix + err.Error() + "\n" + strings.Join(lines, "\n")
+}
+
+// openInEditor opens the given file in the editor of the user ($VISUAL
+// or $EDITOR) and waits until the editor is closed.
+func openInEditor(filePath string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// the editor can contain arguments (e.g. "code --wait")
+	fields := strings.Fields(editor)
+	command := exec.Command(fields[0], append(fields[1:], filePath)...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	return command.Run()
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"strings"
+	"testing"
+)
+
+// testEditZone contains the records of example.com.
+var testEditZone = []dnsimple.Record{
+	{Id: 1, Name: "", RecordType: "SOA", Content: "ns1.dnsimple.com admin.dnsimple.com 1 86400 7200 604800 300", Ttl: 3600},
+	{Id: 2, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+	{Id: 3, Name: "old", RecordType: "CNAME", Content: "www.example.com", Ttl: 600},
+}
+
+// editAction.Execute should apply the changes of the edited zone file.
+func Test_editAction_RecordsChanged_ChangesAreApplied(t *testing.T) {
+	// arrange
+	var changes []string
+	fs := afero.NewMemMapFs()
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testEditZone, nil
+		},
+	}
+
+	openEditor := func(filePath string) error {
+		return afero.WriteFile(fs, filePath, []byte("$ORIGIN example.com.\nwww 600 IN A 5.6.7.8\nftp 600 IN CNAME www.example.com.\n"), 0600)
+	}
+
+	action := editAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, openEditor, nil, nil}
+	arguments := []string{"example.com", "-yes"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || strings.Join(changes, ", ") != "delete 3, update 2 5.6.7.8, create ftp 600 IN CNAME www.example.com" {
+		t.Fail()
+		t.Logf("editAction.Execute(%q) applied %v (result: %v, error: %v)", arguments, changes, result, err)
+	}
+}
+
+// editAction.Execute should reopen the editor with the error if the zone file is invalid.
+func Test_editAction_InvalidRecord_EditorIsReopened(t *testing.T) {
+	// arrange
+	var changes []string
+	var secondContent string
+	fs := afero.NewMemMapFs()
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testEditZone, nil
+		},
+	}
+
+	openEditor := func(filePath string) error {
+		if content, _ := afero.ReadFile(fs, filePath); strings.HasPrefix(string(content), editErrorPrefix) {
+			secondContent = string(content)
+			return afero.WriteFile(fs, filePath, []byte("$ORIGIN example.com.\nwww 600 IN A 1.2.3.5\nold 600 IN CNAME www.example.com\n"), 0600)
+		}
+
+		return afero.WriteFile(fs, filePath, []byte("$ORIGIN example.com.\nwww 600 IN A 1.2.3\n"), 0600)
+	}
+
+	action := editAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, openEditor, nil, nil}
+
+	// act
+	_, err := action.Execute(context.Background(), []string{"-domain", "example.com", "-yes"})
+
+	// assert
+	if !strings.HasPrefix(secondContent, "; ERROR: www.example.com (A): \"1.2.3\" is not a valid A record address\n") {
+		t.Fail()
+		t.Logf("The editor should be reopened with the error but the file contained %q", secondContent)
+	}
+
+	if err != nil || strings.Join(changes, ", ") != "update 2 1.2.3.5" {
+		t.Fail()
+		t.Logf("The corrected file should be applied (changes: %v, error: %v)", changes, err)
+	}
+}
+
+// editAction.Execute should give up if an invalid file is saved without changes.
+func Test_editAction_InvalidFileUnchanged_ErrorIsReturned(t *testing.T) {
+	// arrange
+	var changes []string
+	fs := afero.NewMemMapFs()
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testEditZone, nil
+		},
+	}
+
+	// the invalid file is saved again without changes when the editor is reopened
+	openEditor := func(filePath string) error {
+		if content, _ := afero.ReadFile(fs, filePath); strings.HasPrefix(string(content), editErrorPrefix) {
+			return nil
+		}
+
+		return afero.WriteFile(fs, filePath, []byte("$ORIGIN example.com.\nwww 600 IN MX mail.example.com\n"), 0600)
+	}
+
+	action := editAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, openEditor, nil, nil}
+
+	// act
+	_, err := action.Execute(context.Background(), []string{"-domain", "example.com", "-yes"})
+
+	// assert
+	if err == nil || !strings.HasPrefix(err.Error(), "Edit cancelled: Line 3: MX records need a priority and a content") || len(changes) != 0 {
+		t.Fail()
+		t.Logf("editAction.Execute should give up on an unchanged invalid file (changes: %v, error: %v)", changes, err)
+	}
+}
+
+// editAction.Execute should not change anything if the file is saved unchanged.
+func Test_editAction_Unchanged_NoChangesAreApplied(t *testing.T) {
+	// arrange
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testEditZone, nil
+		},
+	}
+
+	openEditor := func(filePath string) error {
+		return nil
+	}
+
+	action := editAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, afero.NewMemMapFs(), openEditor, nil, nil}
+
+	// act
+	result, err := action.Execute(context.Background(), []string{"-domain", "example.com"})
+
+	// assert
+	if err != nil || len(changes) != 0 || result.Text() != "No changes. The records of example.com are unchanged" {
+		t.Fail()
+		t.Logf("editAction.Execute should not apply any changes (changes: %v, result: %v, error: %v)", changes, result, err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+)
+
+var (
+	actionNameGet = "get"
+
+	getArguments = flag.NewFlagSet(actionNameGet, flag.ContinueOnError)
+	getDomain    = getArguments.String("domain", "", "Domain (e.g. example.com)")
+	getID        = getArguments.String("id", "", "The id of the record (see \"list\")")
+	getFormat    = getArguments.String("format", outputFormatText, "Output format (text, json)")
+)
+
+type getAction struct {
+	infoProviderFactory dnsInfoProviderCreator
+}
+
+func (action getAction) Name() string {
+	return actionNameGet
+}
+
+func (action getAction) Description() string {
+	return "Show the DNS record with the given id"
+}
+
+func (action getAction) Usage() string {
+	buf := new(bytes.Buffer)
+	getArguments.SetOutput(buf)
+	getArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute returns the DNS record with the id given in the supplied arguments.
+func (action getAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*getDomain = defaults.Domain
+	*getID = ""
+	*getFormat = defaults.Format
+	if parseError := getArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	var nameError error
+	if *getDomain, nameError = normalizeName(*getDomain); nameError != nil {
+		return nil, nameError
+	}
+
+	if *getDomain == "" {
+		return nil, fmt.Errorf("No domain supplied")
+	}
+
+	if *getID == "" {
+		return nil, fmt.Errorf("No record id supplied")
+	}
+
+	if formatError := validateOutputFormat(*getFormat); formatError != nil {
+		return nil, formatError
+	}
+
+	infoProvider, infoProviderError := action.getInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("No DNS info provider available")
+	}
+
+	record, err := infoProvider.GetRecord(ctx, *getDomain, *getID)
+	if err != nil {
+		return nil, err
+	}
+
+	if *getFormat == outputFormatJSON {
+		return formatJSON(record)
+	}
+
+	return formatDNSRecordList([]dnsimple.Record{record}, *getDomain, *getFormat)
+}
+
+// getInfoProvider returns a DNS info provider instance or an error if the creation of the provider failed.
+func (action getAction) getInfoProvider() (dnsInfoProvider, error) {
+	if action.infoProviderFactory == nil {
+		return nil, fmt.Errorf("No DNS info provider factory available")
+	}
+
+	return action.infoProviderFactory.CreateInfoProvider()
+}
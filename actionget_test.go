@@ -0,0 +1,87 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"strings"
+	"testing"
+)
+
+func Test_getAction_Name_GetIsReturned(t *testing.T) {
+	// arrange
+	getAction := getAction{}
+
+	// act
+	result := getAction.Name()
+
+	// assert
+	if result != "get" {
+		t.Fail()
+		t.Logf("getAction.Name() should have returned %q but returned %q instead.", "get", result)
+	}
+}
+
+// getAction.Execute should return an error if no record id is given.
+func Test_getAction_NoID_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com"}
+	getAction := getAction{testInfoProviderFactory{testDNSInfoProvider{}, nil}}
+
+	// act
+	_, err := getAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("getAction.Execute(%q) should return an error because no record id was given.", arguments)
+	}
+}
+
+// getAction.Execute should print the record with the given id.
+func Test_getAction_ValidID_RecordIsPrinted(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-id", "12"}
+	infoProvider := testDNSInfoProvider{
+		getRecordFunc: func(domain, id string) (dnsimple.Record, error) {
+			return dnsimple.Record{Id: 12, Name: "www", RecordType: "A", Content: "10.0.2.1"}, nil
+		},
+	}
+
+	getAction := getAction{testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	result, err := getAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || result.Text() != "12   www.example.com   A   10.0.2.1" {
+		t.Fail()
+		t.Logf("getAction.Execute(%q) should print the record (error: %v, result: %q)", arguments, err, result)
+	}
+}
+
+// getAction.Execute should return an error if the record does not exist.
+func Test_getAction_UnknownID_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-id", "99"}
+	infoProvider := testDNSInfoProvider{
+		getRecordFunc: func(domain, id string) (dnsimple.Record, error) {
+			return dnsimple.Record{}, fmt.Errorf("No record with id %q found", id)
+		},
+	}
+
+	getAction := getAction{testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := getAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "99") {
+		t.Fail()
+		t.Logf("getAction.Execute(%q) should return an error because the record does not exist.", arguments)
+	}
+}
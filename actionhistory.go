@@ -0,0 +1,233 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	actionNameHistory = "history"
+
+	historyArguments = flag.NewFlagSet(actionNameHistory, flag.ContinueOnError)
+	historyDomain    = historyArguments.String("domain", "", "Only show the changes of the given domain (e.g. example.com)")
+	historyName      = historyArguments.String("name", "", "Only show the changes of the given subdomain (e.g. www)")
+	historySince     = historyArguments.String("since", "", "Only show changes after the given time (e.g. 24h, 2016-02-01 or 2016-02-01T12:00:00Z)")
+	historyUntil     = historyArguments.String("until", "", "Only show changes before the given time (e.g. 1h, 2016-02-08)")
+	historyFormat    = historyArguments.String("format", outputFormatText, "The output format (text, json)")
+)
+
+type historyAction struct {
+	log *historyLog
+}
+
+func (action historyAction) Name() string {
+	return actionNameHistory
+}
+
+func (action historyAction) Description() string {
+	return "Show the history of all changes made with dee"
+}
+
+func (action historyAction) Usage() string {
+	buf := new(bytes.Buffer)
+	historyArguments.SetOutput(buf)
+	historyArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute prints the entries of the history log which match the given filters.
+func (action historyAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*historyDomain = ""
+	*historyName = ""
+	*historySince = ""
+	*historyUntil = ""
+	*historyFormat = defaults.Format
+	if parseError := historyArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	if formatError := validateOutputFormat(*historyFormat); formatError != nil {
+		return nil, formatError
+	}
+
+	filter, filterError := newHistoryFilter(*historyDomain, *historyName, *historySince, *historyUntil, action.log.now())
+	if filterError != nil {
+		return nil, filterError
+	}
+
+	entries, entriesError := action.log.Entries()
+	if entriesError != nil {
+		return nil, fmt.Errorf("Unable to read the history: %s", entriesError.Error())
+	}
+
+	matchingEntries := []historyEntry{}
+	for _, entry := range entries {
+		if filter.Matches(entry) {
+			matchingEntries = append(matchingEntries, entry)
+		}
+	}
+
+	if *historyFormat == outputFormatJSON {
+		return formatJSON(matchingEntries)
+	}
+
+	if len(matchingEntries) == 0 {
+		return successMessage{"No changes found"}, nil
+	}
+
+	return successMessage{formatHistoryEntries(matchingEntries)}, nil
+}
+
+// historyFilter selects history entries by domain, subdomain and time.
+type historyFilter struct {
+	domain string
+	name   string
+	since  time.Time
+	until  time.Time
+}
+
+// newHistoryFilter creates a new history filter. The times can be given as
+// durations relative to the given time (e.g. "24h") or as dates.
+func newHistoryFilter(domain, name, since, until string, now time.Time) (historyFilter, error) {
+	var err error
+	filter := historyFilter{}
+
+	if filter.domain, err = normalizeName(domain); err != nil {
+		return historyFilter{}, err
+	}
+
+	if filter.name, err = normalizeName(name); err != nil {
+		return historyFilter{}, err
+	}
+
+	if filter.since, err = parseHistoryTime(since, now); err != nil {
+		return historyFilter{}, err
+	}
+
+	if filter.until, err = parseHistoryTime(until, now); err != nil {
+		return historyFilter{}, err
+	}
+
+	return filter, nil
+}
+
+// Matches returns true if the given entry matches all criteria of the filter.
+func (filter historyFilter) Matches(entry historyEntry) bool {
+	if filter.domain != "" && !strings.EqualFold(entry.Domain, filter.domain) {
+		return false
+	}
+
+	if filter.name != "" {
+		isBefore := entry.Before != nil && strings.EqualFold(entry.Before.Name, filter.name)
+		isAfter := entry.After != nil && strings.EqualFold(entry.After.Name, filter.name)
+		if !isBefore && !isAfter {
+			return false
+		}
+	}
+
+	if !filter.since.IsZero() && entry.Time.Before(filter.since) {
+		return false
+	}
+
+	if !filter.until.IsZero() && entry.Time.After(filter.until) {
+		return false
+	}
+
+	return true
+}
+
+// historyTimeFormats contains the supported formats of the -since and -until options.
+var historyTimeFormats = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// parseHistoryTime parses the given time which can either be a duration
+// relative to the given time (e.g. "24h" means 24 hours ago) or a date.
+// Returns a zero time if the given value is empty.
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, format := range historyTimeFormats {
+		if parsedTime, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return parsedTime, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Cannot parse the time %q. Please use a duration (e.g. 24h) or a date (e.g. 2016-02-01)", value)
+}
+
+// formatHistoryEntries formats the given entries as a table with the columns
+// time, id, action, name, type, change and outcome.
+func formatHistoryEntries(entries []historyEntry) string {
+	buf := new(bytes.Buffer)
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 3, ' ', 0)
+
+	for index, entry := range entries {
+		outcome := entry.Outcome
+		if entry.Error != "" {
+			outcome = fmt.Sprintf("%s (%s)", entry.Outcome, entry.Error)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.ID,
+			entry.Operation,
+			getFormattedDomainName(entry.Name(), entry.Domain),
+			getHistoryRecordType(entry),
+			formatHistoryChange(entry),
+			outcome)
+
+		if index < len(entries)-1 {
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	w.Flush()
+
+	return buf.String()
+}
+
+// getHistoryRecordType returns the record type of the given change.
+func getHistoryRecordType(entry historyEntry) string {
+	if entry.After != nil {
+		return entry.After.Type
+	}
+
+	if entry.Before != nil {
+		return entry.Before.Type
+	}
+
+	return ""
+}
+
+// formatHistoryChange returns the content of the record before and after
+// the given change (e.g. "1.2.3.4 → 5.6.7.8").
+func formatHistoryChange(entry historyEntry) string {
+	before, after := "∅", "∅"
+	if entry.Before != nil {
+		before = entry.Before.Content
+	}
+
+	if entry.After != nil {
+		after = entry.After.Content
+	}
+
+	return fmt.Sprintf("%s → %s", before, after)
+}
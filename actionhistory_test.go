@@ -0,0 +1,101 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHistoryAction returns a history action whose log contains an update
+// of www.example.com two days ago and a deletion of mail.example.org one hour ago.
+func newTestHistoryAction() historyAction {
+	now := time.Date(2016, 2, 10, 12, 0, 0, 0, time.UTC)
+	log := newTestHistoryLog(now.Add(-48 * time.Hour))
+	log.Record(context.Background(), historyEntry{
+		Operation: historyOperationUpdate,
+		Domain:    "example.com",
+		Before:    &historyRecord{Name: "www", Type: "A", Content: "1.2.3.4"},
+		After:     &historyRecord{Name: "www", Type: "A", Content: "5.6.7.8"},
+		Outcome:   historyOutcomeApplied,
+	})
+
+	log.now = func() time.Time {
+		return now.Add(-time.Hour)
+	}
+
+	log.Record(context.Background(), historyEntry{
+		Operation: historyOperationDelete,
+		Domain:    "example.org",
+		Before:    &historyRecord{Name: "mail", Type: "MX", Content: "mx.example.org"},
+		Outcome:   historyOutcomeApplied,
+	})
+
+	log.now = func() time.Time {
+		return now
+	}
+
+	return historyAction{log}
+}
+
+// historyAction.Execute should list all changes if no filter is given.
+func Test_historyAction_NoFilter_AllChangesAreListed(t *testing.T) {
+	// arrange
+	action := newTestHistoryAction()
+
+	// act
+	result, err := action.Execute(context.Background(), []string{"-format", "text"})
+
+	// assert
+	if err != nil || !strings.Contains(result.Text(), "www.example.com") || !strings.Contains(result.Text(), "1.2.3.4 → 5.6.7.8") || !strings.Contains(result.Text(), "mx.example.org → ∅") {
+		t.Fail()
+		t.Logf("The history should contain both changes (result: %v, error: %v)", result, err)
+	}
+}
+
+// historyAction.Execute should only list the changes of the given domain and time range.
+func Test_historyAction_Filters_MatchingChangesAreListed(t *testing.T) {
+	inputs := []struct {
+		arguments []string
+		expected  string
+		excluded  string
+	}{
+		{[]string{"-format", "text", "-domain", "example.org"}, "mail.example.org", "www.example.com"},
+		{[]string{"-format", "text", "-name", "WWW"}, "www.example.com", "mail.example.org"},
+		{[]string{"-format", "text", "-since", "24h"}, "mail.example.org", "www.example.com"},
+		{[]string{"-format", "text", "-until", "2016-02-09T00:00:00Z"}, "www.example.com", "mail.example.org"},
+	}
+
+	for _, input := range inputs {
+		// arrange
+		action := newTestHistoryAction()
+
+		// act
+		result, err := action.Execute(context.Background(), input.arguments)
+
+		// assert
+		if err != nil || !strings.Contains(result.Text(), input.expected) || strings.Contains(result.Text(), input.excluded) {
+			t.Fail()
+			t.Logf("historyAction.Execute(%q) should only list %q (result: %v, error: %v)", input.arguments, input.expected, result, err)
+		}
+	}
+}
+
+// historyAction.Execute should return an error for times which cannot be parsed.
+func Test_historyAction_InvalidTime_ErrorIsReturned(t *testing.T) {
+	// arrange
+	action := newTestHistoryAction()
+
+	// act
+	_, err := action.Execute(context.Background(), []string{"-since", "yesterday"})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("historyAction.Execute should return an error for an invalid time")
+	}
+}
@@ -6,9 +6,10 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/andreaskoch/dee-ns"
 	"github.com/pearkes/dnsimple"
 	"strings"
 	"text/tabwriter"
@@ -20,6 +21,7 @@ var (
 	listArguments = flag.NewFlagSet(actionNameList, flag.ContinueOnError)
 	listDomain    = listArguments.String("domain", "", "Domain (optional")
 	listSubdomain = listArguments.String("subdomain", "", "Subdomain (optional)")
+	listFormat    = listArguments.String("format", outputFormatText, "Output format (text, json)")
 )
 
 type listAction struct {
@@ -43,16 +45,31 @@ func (action listAction) Usage() string {
 
 // Execute lists the list of all domains, subdomains or DNS records
 // based on the supplied arguments.
-func (action listAction) Execute(arguments []string) (message, error) {
+func (action listAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	// parse the arguments
-	*listDomain = ""
+	*listDomain = defaults.Domain
 	*listSubdomain = ""
+	*listFormat = defaults.Format
 
 	if parseError := listArguments.Parse(arguments); parseError != nil {
 		return nil, parseError
 	}
 
+	if formatError := validateOutputFormat(*listFormat); formatError != nil {
+		return nil, formatError
+	}
+
+	// convert internationalized names to punycode
+	var nameError error
+	if *listDomain, nameError = toASCIIName(*listDomain); nameError != nil {
+		return nil, nameError
+	}
+
+	if *listSubdomain, nameError = toASCIIName(*listSubdomain); nameError != nil {
+		return nil, nameError
+	}
+
 	infoProvider, infoProviderError := action.getInfoProvider()
 	if infoProviderError != nil {
 		return nil, fmt.Errorf("No DNS info provider available")
@@ -63,35 +80,77 @@ func (action listAction) Execute(arguments []string) (message, error) {
 
 	// case: 2 get DNS records for the given subdomain
 	if domainParamIsSet && subdomainParamIsSet {
-		records, err := infoProvider.GetSubdomainRecords(*listDomain, *listSubdomain)
+		records, err := infoProvider.GetSubdomainRecords(ctx, *listDomain, *listSubdomain)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to fetch DNS records for subdomain %s.%s", *listSubdomain, *listDomain)
 		}
 
-		return successMessage{formatDNSRecords(records, *listDomain)}, nil
+		return formatDNSRecordList(records, *listDomain, *listFormat)
 	}
 
 	// case 3: get all subdomains
 	if domainParamIsSet && !subdomainParamIsSet {
-		records, err := infoProvider.GetDomainRecords(*listDomain)
+		records, err := infoProvider.GetDomainRecords(ctx, *listDomain)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to fetch DNS records for domain %s", *listDomain)
 		}
 
-		return successMessage{formatDNSRecords(records, *listDomain)}, nil
+		return formatDNSRecordList(records, *listDomain, *listFormat)
 	}
 
 	// case 1: get all domain names
-	names, err := infoProvider.GetDomainNames()
+	domains, err := infoProvider.GetDomains(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to retrieve domain names: %s", err.Error())
 	}
 
+	names := []string{}
+	for _, domain := range domains {
+		names = append(names, getDomainDisplayName(domain))
+	}
+
+	if *listFormat == outputFormatJSON {
+		return formatJSON(names)
+	}
+
 	return successMessage{strings.Join(names, "\n")}, nil
 }
 
+// getDomainDisplayName returns the unicode name of the given domain
+// unless ASCII labels were requested with the -labels option.
+func getDomainDisplayName(domain dnsimple.Domain) string {
+	if options.Labels == labelsASCII || domain.UnicodeName == "" {
+		return domain.Name
+	}
+
+	return domain.UnicodeName
+}
+
+// formatDNSRecordList formats the given DNS records in the given output format.
+func formatDNSRecordList(records []dnsimple.Record, domainName, format string) (message, error) {
+	if format == outputFormatJSON {
+		if records == nil {
+			records = []dnsimple.Record{}
+		}
+
+		return formatJSON(records)
+	}
+
+	return successMessage{formatDNSRecords(records, domainName)}, nil
+}
+
+// formatJSON returns a message with the JSON representation of the given model.
+func formatJSON(model interface{}) (message, error) {
+	content, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to format the result as JSON: %s", err.Error())
+	}
+
+	return successMessage{string(content)}, nil
+}
+
 // getInfoProvider returns a DNS info provider instance or an error if the creation of the provider failed.
-func (action listAction) getInfoProvider() (deens.DNSInfoProvider, error) {
+func (action listAction) getInfoProvider() (dnsInfoProvider, error) {
 	if action.infoProviderFactory == nil {
 		return nil, fmt.Errorf("No DNS info provider factory available")
 	}
@@ -99,7 +158,8 @@ func (action listAction) getInfoProvider() (deens.DNSInfoProvider, error) {
 	return action.infoProviderFactory.CreateInfoProvider()
 }
 
-// formatDNSRecords takes a list of DNS records and formats them as a table.
+// formatDNSRecords takes a list of DNS records and formats them as a table
+// with the columns id, name, type and content.
 func formatDNSRecords(records []dnsimple.Record, domainName string) string {
 	buf := new(bytes.Buffer)
 
@@ -118,7 +178,7 @@ func formatDNSRecords(records []dnsimple.Record, domainName string) string {
 			domainName = record.Name + "." + domainName
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s", domainName, record.RecordType, record.Content)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s", record.Id, toDisplayName(domainName), record.RecordType, record.Content)
 
 		// append newline if we are not
 		// formatting the last record
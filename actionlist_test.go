@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/pearkes/dnsimple"
 	"strings"
@@ -14,27 +15,37 @@ import (
 // testDNSInfoProvider is a DNS info-provider used for testing.
 type testDNSInfoProvider struct {
 	getDomainNamesFunc      func() ([]string, error)
+	getDomainsFunc          func() ([]dnsimple.Domain, error)
 	getDomainRecordsFunc    func(domain string) ([]dnsimple.Record, error)
 	getSubdomainRecordFunc  func(domain, subdomain, recordType string) (dnsimple.Record, error)
 	getSubdomainRecordsFunc func(domain, subdomain string) ([]dnsimple.Record, error)
+	getRecordFunc           func(domain, id string) (dnsimple.Record, error)
 }
 
-func (infoProvider testDNSInfoProvider) GetDomainNames() ([]string, error) {
+func (infoProvider testDNSInfoProvider) GetDomainNames(ctx context.Context) ([]string, error) {
 	return infoProvider.getDomainNamesFunc()
 }
 
-func (infoProvider testDNSInfoProvider) GetDomainRecords(domain string) ([]dnsimple.Record, error) {
+func (infoProvider testDNSInfoProvider) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+	return infoProvider.getDomainsFunc()
+}
+
+func (infoProvider testDNSInfoProvider) GetDomainRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
 	return infoProvider.getDomainRecordsFunc(domain)
 }
 
-func (infoProvider testDNSInfoProvider) GetSubdomainRecord(domain, subdomain, recordType string) (record dnsimple.Record, err error) {
+func (infoProvider testDNSInfoProvider) GetSubdomainRecord(ctx context.Context, domain, subdomain, recordType string) (record dnsimple.Record, err error) {
 	return infoProvider.getSubdomainRecordFunc(domain, subdomain, recordType)
 }
 
-func (infoProvider testDNSInfoProvider) GetSubdomainRecords(domain, subdomain string) ([]dnsimple.Record, error) {
+func (infoProvider testDNSInfoProvider) GetSubdomainRecords(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error) {
 	return infoProvider.getSubdomainRecordsFunc(domain, subdomain)
 }
 
+func (infoProvider testDNSInfoProvider) GetRecord(ctx context.Context, domain, id string) (dnsimple.Record, error) {
+	return infoProvider.getRecordFunc(domain, id)
+}
+
 // The Name function should return "list"
 func Test_listAction_Name_ResultIsLogin(t *testing.T) {
 	// arrange
@@ -104,7 +115,7 @@ func Test_listAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 		list := listAction{infoProviderFactory}
 
 		// act
-		_, err := list.Execute(arguments)
+		_, err := list.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil || strings.Contains(err.Error(), "flag provided but not") == false {
@@ -127,7 +138,7 @@ func Test_listAction_ValidArguments_NoInfoProviderFactory_ErrorIsReturned(t *tes
 	list := listAction{}
 
 	// act
-	_, err := list.Execute(arguments)
+	_, err := list.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil || strings.Contains(err.Error(), "No DNS info provider") == false {
@@ -170,7 +181,7 @@ func Test_listAction_DomainAndSubDomainAreSet_SubdomainRecordsArePrinted(t *test
 	list := listAction{infoProviderFactory}
 
 	// act
-	result, _ := list.Execute(arguments)
+	result, _ := list.Execute(context.Background(), arguments)
 
 	// assert
 	if isEmpty(result.Text()) {
@@ -200,7 +211,7 @@ func Test_listAction_DomainAndSubDomainAreSet_InfoProviderReturnsError_ErrorIsRe
 	list := listAction{infoProviderFactory}
 
 	// act
-	_, err := list.Execute(arguments)
+	_, err := list.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -241,7 +252,7 @@ func Test_listAction_DomainSet_DNSRecordsArePrinted(t *testing.T) {
 	list := listAction{infoProviderFactory}
 
 	// act
-	result, _ := list.Execute(arguments)
+	result, _ := list.Execute(context.Background(), arguments)
 
 	// assert
 	if isEmpty(result.Text()) {
@@ -269,7 +280,7 @@ func Test_listAction_DomainSet_InfoProviderReturnsError_ErrorIsReturned(t *testi
 	list := listAction{infoProviderFactory}
 
 	// act
-	_, err := list.Execute(arguments)
+	_, err := list.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -284,10 +295,10 @@ func Test_listAction_NoArguments_DomainsArePrinted(t *testing.T) {
 	arguments := []string{}
 
 	dnsInfoProvider := testDNSInfoProvider{
-		getDomainNamesFunc: func() ([]string, error) {
-			return []string{
-				"example.com",
-				"example.co.uk",
+		getDomainsFunc: func() ([]dnsimple.Domain, error) {
+			return []dnsimple.Domain{
+				{Name: "example.com"},
+				{Name: "example.co.uk"},
 			}, nil
 		},
 	}
@@ -297,7 +308,7 @@ func Test_listAction_NoArguments_DomainsArePrinted(t *testing.T) {
 	list := listAction{infoProviderFactory}
 
 	// act
-	result, _ := list.Execute(arguments)
+	result, _ := list.Execute(context.Background(), arguments)
 
 	// assert
 	if isEmpty(result.Text()) {
@@ -312,8 +323,8 @@ func Test_listAction_NoArguments_InfoProviderReturnsError_ErrorIsReturned(t *tes
 	arguments := []string{}
 
 	dnsInfoProvider := testDNSInfoProvider{
-		getDomainNamesFunc: func() ([]string, error) {
-			return []string{}, fmt.Errorf("Domain error")
+		getDomainsFunc: func() ([]dnsimple.Domain, error) {
+			return nil, fmt.Errorf("Domain error")
 		},
 	}
 
@@ -322,7 +333,7 @@ func Test_listAction_NoArguments_InfoProviderReturnsError_ErrorIsReturned(t *tes
 	list := listAction{infoProviderFactory}
 
 	// act
-	_, err := list.Execute(arguments)
+	_, err := list.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -336,11 +347,13 @@ func Test_formatDNSRecords_SubdomainIsSet_ResultContainsSubdomain(t *testing.T)
 	// arrange
 	records := []dnsimple.Record{
 		dnsimple.Record{
+			Id:         1,
 			Name:       "www",
 			Content:    "2001:0db8:0000:0042:0000:8a2e:0370:7334",
 			RecordType: "AAAA",
 		},
 		dnsimple.Record{
+			Id:         12,
 			Name:       "www",
 			Content:    "10.0.2.1",
 			RecordType: "A",
@@ -352,8 +365,8 @@ func Test_formatDNSRecords_SubdomainIsSet_ResultContainsSubdomain(t *testing.T)
 	result := formatDNSRecords(records, domain)
 
 	// assert
-	expectedResult := `www.example.com   AAAA   2001:0db8:0000:0042:0000:8a2e:0370:7334
-www.example.com   A      10.0.2.1`
+	expectedResult := `1    www.example.com   AAAA   2001:0db8:0000:0042:0000:8a2e:0370:7334
+12   www.example.com   A      10.0.2.1`
 
 	if result != expectedResult {
 		t.Fail()
@@ -400,7 +413,7 @@ func Test_listAction_OnlyDomainIsSet_DomainRecordsArePrinted(t *testing.T) {
 	list := listAction{infoProviderFactory}
 
 	// act
-	result, _ := list.Execute(arguments)
+	result, _ := list.Execute(context.Background(), arguments)
 
 	// assert
 	if isEmpty(result.Text()) {
@@ -414,11 +427,13 @@ func Test_formatDNSRecords_SubdomainIsNotSet_ResultDoesNotContainSubdomain(t *te
 	// arrange
 	records := []dnsimple.Record{
 		dnsimple.Record{
+			Id:         1,
 			Name:       "",
 			Content:    "2001:0db8:0000:0042:0000:8a2e:0370:7334",
 			RecordType: "AAAA",
 		},
 		dnsimple.Record{
+			Id:         12,
 			Name:       "",
 			Content:    "10.0.2.1",
 			RecordType: "A",
@@ -430,8 +445,8 @@ func Test_formatDNSRecords_SubdomainIsNotSet_ResultDoesNotContainSubdomain(t *te
 	result := formatDNSRecords(records, domain)
 
 	// assert
-	expectedResult := `example.com   AAAA   2001:0db8:0000:0042:0000:8a2e:0370:7334
-example.com   A      10.0.2.1`
+	expectedResult := `1    example.com   AAAA   2001:0db8:0000:0042:0000:8a2e:0370:7334
+12   example.com   A      10.0.2.1`
 
 	if result != expectedResult {
 		t.Fail()
@@ -496,3 +511,49 @@ func Test_formatDNSRecords_ResultDoesNotEndWithNewline(t *testing.T) {
 		t.Logf("formatDNSRecords(%q, %q) should not end with a newline character", records, domain)
 	}
 }
+
+// The JSON output format should return the records as JSON.
+func Test_listAction_FormatJSON_RecordsArePrintedAsJSON(t *testing.T) {
+	// arrange
+	arguments := []string{
+		"-domain",
+		"example.com",
+		"-format",
+		"json",
+	}
+
+	dnsInfoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{
+				dnsimple.Record{Id: 1, Name: "www", Content: "10.0.2.1", RecordType: "A"},
+			}, nil
+		},
+	}
+
+	list := listAction{testInfoProviderFactory{dnsInfoProvider, nil}}
+
+	// act
+	result, err := list.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || !strings.Contains(result.Text(), `"record_type": "A"`) {
+		t.Fail()
+		t.Logf("list.Execute(%q) should return the records as JSON (Error: %s).", arguments, err)
+	}
+}
+
+// Unsupported output formats should be rejected.
+func Test_listAction_UnsupportedFormat_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-format", "xml"}
+	list := listAction{testInfoProviderFactory{testDNSInfoProvider{}, nil}}
+
+	// act
+	_, err := list.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("list.Execute(%q) should return an error.", arguments)
+	}
+}
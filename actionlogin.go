@@ -6,20 +6,31 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/andreaskoch/dee-ns"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 )
 
 var (
 	actionNameLogin      = "login"
 	loginActionArguments = flag.NewFlagSet(actionNameLogin, flag.ContinueOnError)
 	emailAddress         = loginActionArguments.String("email", "", "The e-mail address of the account to use")
-	apiToken             = loginActionArguments.String("apitoken", "", "The API token")
+	apiToken             = loginActionArguments.String("apitoken", "", "The API token (not recommended: visible in the process list and shell history)")
+	apiTokenFile         = loginActionArguments.String("apitoken-file", "", "Read the API token from the given file")
+	apiTokenStdin        = loginActionArguments.Bool("apitoken-stdin", false, "Read the API token from stdin")
+	noVerify             = loginActionArguments.Bool("no-verify", false, "Save the credentials without verifying them against the API")
 )
 
 type loginAction struct {
-	credentialStore deens.CredentialStore
+	credentialStore            deens.CredentialStore
+	accountInfoProviderFactory accountInfoProviderCreator
+	stdin                      *os.File
+	stderr                     io.Writer
 }
 
 func (action loginAction) Name() string {
@@ -38,29 +49,137 @@ func (action loginAction) Usage() string {
 }
 
 // Execute parses the e-mail address and API token
-// from the given arguments and stores the credentials
-// in the given credential store. If the credentials are
-// invalid or the save failed and error is returned.
-func (action loginAction) Execute(arguments []string) (message, error) {
+// from the given arguments, verifies them against the API
+// and stores the credentials in the given credential store.
+// If the credentials are invalid, rejected by the API or
+// the save failed and error is returned.
+func (action loginAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	if action.credentialStore == nil {
 		return nil, fmt.Errorf("No credential store present")
 	}
 
 	// parse the command line arguments
+	*emailAddress = ""
+	*apiToken = ""
+	*apiTokenFile = ""
+	*apiTokenStdin = false
+	*noVerify = false
 	if parseError := loginActionArguments.Parse(arguments); parseError != nil {
 		return nil, parseError
 	}
 
+	// determine the API token
+	token, tokenError := action.getAPIToken()
+	if tokenError != nil {
+		return nil, tokenError
+	}
+
 	// perform the login action
-	credentials, credentialError := deens.NewAPICredentials(*emailAddress, *apiToken)
+	credentials, credentialError := deens.NewAPICredentials(*emailAddress, token)
 	if credentialError != nil {
 		return nil, credentialError
 	}
 
+	// verify the credentials
+	if !*noVerify {
+		if verifyError := action.verifyCredentials(ctx, credentials); verifyError != nil {
+			return nil, verifyError
+		}
+	}
+
 	if saveErr := action.credentialStore.SaveCredentials(credentials); saveErr != nil {
 		return nil, saveErr
 	}
 
 	return successMessage{"Login succeeded"}, nil
 }
+
+// getAPIToken returns the API token from the command line, the token file,
+// stdin or - if none of them is given and stdin is a terminal - from an
+// interactive prompt. Returns an error if more than one source was given
+// or if the token cannot be read.
+func (action loginAction) getAPIToken() (string, error) {
+
+	numberOfSources := 0
+	for _, sourceIsSet := range []bool{*apiToken != "", *apiTokenFile != "", *apiTokenStdin} {
+		if sourceIsSet {
+			numberOfSources++
+		}
+	}
+
+	if numberOfSources > 1 {
+		return "", fmt.Errorf("Please use only one of -apitoken, -apitoken-file and -apitoken-stdin")
+	}
+
+	// command line
+	if *apiToken != "" {
+		action.printf("Warning: Passing the API token on the command line exposes it in the process list and your shell history. Use -apitoken-file, -apitoken-stdin or the interactive prompt instead.\n")
+		return *apiToken, nil
+	}
+
+	// token file
+	if *apiTokenFile != "" {
+		content, readError := ioutil.ReadFile(*apiTokenFile)
+		if readError != nil {
+			return "", fmt.Errorf("Unable to read the API token from %q: %s", *apiTokenFile, readError.Error())
+		}
+
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	// stdin
+	if *apiTokenStdin {
+		if action.stdin == nil {
+			return "", fmt.Errorf("No stdin available to read the API token from")
+		}
+
+		line, readError := readLine(action.stdin)
+		if readError != nil {
+			return "", fmt.Errorf("Unable to read the API token from stdin: %s", readError.Error())
+		}
+
+		return strings.TrimSpace(string(line)), nil
+	}
+
+	// interactive prompt
+	if isTerminal(action.stdin) {
+		action.printf("API token: ")
+		password, readError := readPassword(action.stdin)
+		action.printf("\n")
+		if readError != nil {
+			return "", fmt.Errorf("Unable to read the API token: %s", readError.Error())
+		}
+
+		return strings.TrimSpace(string(password)), nil
+	}
+
+	return "", nil
+}
+
+// verifyCredentials returns an error if the given credentials are not accepted by the API.
+func (action loginAction) verifyCredentials(ctx context.Context, credentials deens.APICredentials) error {
+	if action.accountInfoProviderFactory == nil {
+		return fmt.Errorf("No account info provider factory available")
+	}
+
+	accountInfoProvider, providerError := action.accountInfoProviderFactory.CreateAccountInfoProvider(credentials)
+	if providerError != nil {
+		return providerError
+	}
+
+	if _, infoError := accountInfoProvider.GetAccountInfo(ctx); infoError != nil {
+		return fmt.Errorf("The credentials were not saved because they could not be verified: %s (use -no-verify to skip the verification)", infoError.Error())
+	}
+
+	return nil
+}
+
+// printf writes the given message to stderr (if available).
+func (action loginAction) printf(format string, args ...interface{}) {
+	if action.stderr == nil {
+		return
+	}
+
+	fmt.Fprintf(action.stderr, format, args...)
+}
@@ -5,9 +5,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/andreaskoch/dee-ns"
 	"github.com/spf13/afero"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +24,7 @@ func Test_loginAction_Name_ResultIsLogin(t *testing.T) {
 		return nil
 	}}
 
-	login := loginAction{credentialStore}
+	login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 	// act
 	result := login.Name()
@@ -39,7 +44,7 @@ func Test_loginAction_Description_ResultIsNotEmpty(t *testing.T) {
 		return nil
 	}}
 
-	login := loginAction{credentialStore}
+	login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 	// act
 	result := login.Description()
@@ -59,7 +64,7 @@ func Test_loginAction_Usage_ResultIsNotEmpty(t *testing.T) {
 		return nil
 	}}
 
-	login := loginAction{credentialStore}
+	login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 	// act
 	result := login.Usage()
@@ -102,10 +107,10 @@ func Test_loginAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 			return nil
 		}}
 
-		login := loginAction{credentialStore}
+		login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 		// act
-		_, err := login.Execute(arguments)
+		_, err := login.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -146,10 +151,10 @@ func Test_loginAction_ValidArguments_NoErrorIsReturned(t *testing.T) {
 			return nil
 		}}
 
-		login := loginAction{credentialStore}
+		login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 		// act
-		_, err := login.Execute(arguments)
+		_, err := login.Execute(context.Background(), arguments)
 
 		// assert
 		if err != nil {
@@ -190,10 +195,10 @@ func Test_loginAction_ValidArguments_ButInvalidValues_ErrorIsReturned(t *testing
 			return nil
 		}}
 
-		login := loginAction{credentialStore}
+		login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 		// act
-		_, err := login.Execute(arguments)
+		_, err := login.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -215,10 +220,10 @@ func Test_loginAction_ValidArguments_CredentialFileIsCreated(t *testing.T) {
 		"123456",
 	}
 
-	login := loginAction{credentialStore}
+	login := loginAction{credentialStore, validAccountInfoProviderFactory(), nil, nil}
 
 	// act
-	login.Execute(arguments)
+	login.Execute(context.Background(), arguments)
 
 	// assert
 	fileInfo, err := filesystem.Stat("/home/testuser/.dee/credentials.json")
@@ -242,7 +247,7 @@ func Test_loginAction_Login_InvalidCredentials_ErrorIsReturned(t *testing.T) {
 	// act
 	for _, arguments := range inputs {
 
-		_, err := loginAction.Execute(arguments)
+		_, err := loginAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -274,10 +279,10 @@ func Test_loginAction_Login_ValidCredentials_CredentialsArePassedToCredentialSto
 				return nil
 			},
 		}
-		loginAction := loginAction{credStore}
+		loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, nil}
 
 		// act
-		loginAction.Execute(arguments)
+		loginAction.Execute(context.Background(), arguments)
 	}
 }
 
@@ -288,10 +293,10 @@ func Test_loginAction_Login_ValidCredentials_CredentialStoreSaveFails_ErrorIsRet
 			return fmt.Errorf("Save failed")
 		},
 	}
-	loginAction := loginAction{credStore}
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, nil}
 
 	// act
-	_, err := loginAction.Execute([]string{"-email", "example@example.com", "-apitoken", "1234"})
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken", "1234"})
 
 	// assert
 	if err == nil {
@@ -299,3 +304,175 @@ func Test_loginAction_Login_ValidCredentials_CredentialStoreSaveFails_ErrorIsRet
 		t.Logf("If the save at the credential store fails Login should return an error.")
 	}
 }
+
+// getTempFileWithContent returns a temporary file with the given content
+// which is positioned at the beginning of the file.
+func getTempFileWithContent(t *testing.T, content string) *os.File {
+	file, err := ioutil.TempFile("", "dee-test")
+	if err != nil {
+		t.Fatalf("Unable to create temp file: %s", err.Error())
+	}
+
+	file.WriteString(content)
+	file.Seek(0, 0)
+
+	return file
+}
+
+func Test_loginAction_Login_APITokenFile_TokenIsReadFromFile(t *testing.T) {
+	// arrange
+	tokenFile := getTempFileWithContent(t, "secret-token\n")
+	defer os.Remove(tokenFile.Name())
+	defer tokenFile.Close()
+
+	var savedCredentials deens.APICredentials
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			savedCredentials = credentials
+			return nil
+		},
+	}
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken-file", tokenFile.Name()})
+
+	// assert
+	if err != nil || savedCredentials.Token != "secret-token" {
+		t.Fail()
+		t.Logf("Login should have saved the token from the token file but saved %q (Error: %s)", savedCredentials.Token, err)
+	}
+}
+
+func Test_loginAction_Login_APITokenFileDoesNotExist_ErrorIsReturned(t *testing.T) {
+	// arrange
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			return nil
+		},
+	}
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken-file", "/non/existing/token/file"})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("Login should return an error if the token file cannot be read.")
+	}
+}
+
+func Test_loginAction_Login_APITokenStdin_TokenIsReadFromStdin(t *testing.T) {
+	// arrange
+	stdin := getTempFileWithContent(t, "stdin-token\nsomething else\n")
+	defer os.Remove(stdin.Name())
+	defer stdin.Close()
+
+	var savedCredentials deens.APICredentials
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			savedCredentials = credentials
+			return nil
+		},
+	}
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), stdin, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken-stdin"})
+
+	// assert
+	if err != nil || savedCredentials.Token != "stdin-token" {
+		t.Fail()
+		t.Logf("Login should have saved the token from stdin but saved %q (Error: %s)", savedCredentials.Token, err)
+	}
+}
+
+func Test_loginAction_Login_MultipleTokenSources_ErrorIsReturned(t *testing.T) {
+	// arrange
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			return nil
+		},
+	}
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken", "1234", "-apitoken-stdin"})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("Login should return an error if more than one token source is given.")
+	}
+}
+
+func Test_loginAction_Login_APITokenOnCommandLine_WarningIsPrinted(t *testing.T) {
+	// arrange
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			return nil
+		},
+	}
+	stderr := new(bytes.Buffer)
+	loginAction := loginAction{credStore, validAccountInfoProviderFactory(), nil, stderr}
+
+	// act
+	loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken", "1234"})
+
+	// assert
+	if !strings.Contains(stderr.String(), "Warning") {
+		t.Fail()
+		t.Logf("Login should print a warning if the token was passed on the command line.")
+	}
+}
+
+func Test_loginAction_Login_CredentialsAreRejectedByTheAPI_CredentialsAreNotSaved(t *testing.T) {
+	// arrange
+	credentialsSaved := false
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			credentialsSaved = true
+			return nil
+		},
+	}
+	accountInfoProviderFactory := testAccountInfoProviderFactory{func(credentials deens.APICredentials) (accountInfo, error) {
+		return accountInfo{}, fmt.Errorf("Authentication failed")
+	}}
+	loginAction := loginAction{credStore, accountInfoProviderFactory, nil, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken", "1234"})
+
+	// assert
+	if err == nil || credentialsSaved {
+		t.Fail()
+		t.Logf("Login should return an error and not save the credentials if the API rejects them.")
+	}
+}
+
+func Test_loginAction_Login_NoVerify_CredentialsAreSavedWithoutVerification(t *testing.T) {
+	// arrange
+	credentialsSaved := false
+	credStore := testCredentialsStore{
+		saveFunc: func(credentials deens.APICredentials) error {
+			credentialsSaved = true
+			return nil
+		},
+	}
+	accountInfoProviderFactory := testAccountInfoProviderFactory{func(credentials deens.APICredentials) (accountInfo, error) {
+		t.Fail()
+		t.Logf("Login should not verify the credentials if -no-verify is given.")
+		return accountInfo{}, fmt.Errorf("Authentication failed")
+	}}
+	loginAction := loginAction{credStore, accountInfoProviderFactory, nil, nil}
+
+	// act
+	_, err := loginAction.Execute(context.Background(), []string{"-email", "example@example.com", "-apitoken", "1234", "-no-verify"})
+
+	// assert
+	if err != nil || !credentialsSaved {
+		t.Fail()
+		t.Logf("Login should save the credentials without verification if -no-verify is given (Error: %s).", err)
+	}
+}
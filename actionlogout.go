@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/andreaskoch/dee-ns"
 )
@@ -30,7 +31,7 @@ func (action logoutAction) Usage() string {
 }
 
 // Execute deletes the API credentials.
-func (action logoutAction) Execute(arguments []string) (message, error) {
+func (action logoutAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	if action.credentialStore == nil {
 		return nil, fmt.Errorf("No credential store present")
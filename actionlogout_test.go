@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -76,7 +77,7 @@ func Test_logoutAction_NoCredentialStore_ErrorIsReturned(t *testing.T) {
 	logoutAction := logoutAction{}
 
 	// act
-	_, err := logoutAction.Execute([]string{})
+	_, err := logoutAction.Execute(context.Background(), []string{})
 
 	// assert
 	if err == nil {
@@ -96,7 +97,7 @@ func Test_logoutAction_CredentialStoreSucceedsInDeletingTheCredentials_NoErrorIs
 	logout := logoutAction{credentialStore}
 
 	// act
-	_, err := logout.Execute([]string{})
+	_, err := logout.Execute(context.Background(), []string{})
 
 	// assert
 	if err != nil {
@@ -116,7 +117,7 @@ func Test_logoutAction_CredentialStoreReturnsNoCredentialsError_NoLogoutRequired
 	logout := logoutAction{credentialStore}
 
 	// act
-	_, err := logout.Execute([]string{})
+	_, err := logout.Execute(context.Background(), []string{})
 
 	// assert
 	if !strings.Contains(err.Error(), "No logout required") {
@@ -136,7 +137,7 @@ func Test_logoutAction_CredentialStoreReturnsGenericError_LogoutFailedErrorIsRet
 	logout := logoutAction{credentialStore}
 
 	// act
-	_, err := logout.Execute([]string{})
+	_, err := logout.Execute(context.Background(), []string{})
 
 	// assert
 	if !strings.Contains(err.Error(), "Logout failed") {
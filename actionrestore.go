@@ -0,0 +1,145 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	actionNameRestore = "restore"
+
+	restoreArguments = flag.NewFlagSet(actionNameRestore, flag.ContinueOnError)
+	restoreSnapshot  = restoreArguments.String("snapshot", "", "The path of the snapshot file (e.g. ~/.dee/backups/example.com-20160210T120000Z.json)")
+	restoreDomain    = restoreArguments.String("domain", "", "Only restore the given domain of the snapshot (default: all domains)")
+	restoreDryRun    = restoreArguments.Bool("dry-run", false, "Only show the changes without applying them")
+	restoreYes       = restoreArguments.Bool("yes", false, "Apply the changes without asking for confirmation")
+)
+
+type restoreAction struct {
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	fs                  afero.Fs
+	stdin               *os.File
+	stderr              io.Writer
+}
+
+func (action restoreAction) Name() string {
+	return actionNameRestore
+}
+
+func (action restoreAction) Description() string {
+	return "Restore the records of one or all domains from a snapshot"
+}
+
+func (action restoreAction) Usage() string {
+	buf := new(bytes.Buffer)
+	restoreArguments.SetOutput(buf)
+	restoreArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute converges the records of the domains in the given snapshot
+// to the records of the snapshot. The changes are shown and must be
+// confirmed before they are applied.
+func (action restoreAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*restoreSnapshot = ""
+	*restoreDomain = ""
+	*restoreDryRun = false
+	*restoreYes = false
+	if parseError := restoreArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	if *restoreSnapshot == "" {
+		return nil, fmt.Errorf("No snapshot supplied")
+	}
+
+	domain, domainError := normalizeName(*restoreDomain)
+	if domainError != nil {
+		return nil, domainError
+	}
+
+	s, snapshotError := readSnapshot(action.fs, *restoreSnapshot)
+	if snapshotError != nil {
+		return nil, snapshotError
+	}
+
+	zones := s.Zones
+	if domain != "" {
+		zone, exists := s.Zone(domain)
+		if !exists {
+			return nil, fmt.Errorf("The snapshot does not contain the domain %s", toDisplayName(domain))
+		}
+
+		zones = []snapshotZone{zone}
+	}
+
+	// plan the changes
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	var plans []zonePlan
+	changeCount := 0
+	for _, zone := range zones {
+		records, recordsError := infoProvider.GetDomainRecords(ctx, zone.Domain)
+		if recordsError != nil {
+			return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", zone.Domain, recordsError.Error())
+		}
+
+		plan := newZonePlan(zone.Domain, records, zone.Records)
+		if !plan.IsEmpty() {
+			plans = append(plans, plan)
+			changeCount += len(plan.Changes)
+		}
+	}
+
+	if changeCount == 0 {
+		return successMessage{fmt.Sprintf("Nothing to restore. The records already match the snapshot of %s", s.Time.Local().Format("2006-01-02 15:04:05"))}, nil
+	}
+
+	var formattedPlans []string
+	for _, plan := range plans {
+		formattedPlans = append(formattedPlans, formatZonePlan(plan))
+	}
+
+	if *restoreDryRun {
+		return successMessage{strings.Join(formattedPlans, "\n")}, nil
+	}
+
+	printf(action.stderr, "%s\n\n", strings.Join(formattedPlans, "\n"))
+
+	if confirmError := confirmChanges(action.stdin, action.stderr, *restoreYes, changeCount, "Restore"); confirmError != nil {
+		return nil, confirmError
+	}
+
+	// apply the changes
+	editor, editorError := action.dnsEditorFactory.CreateDNSEditor()
+	if editorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", editorError.Error())
+	}
+
+	applied := 0
+	for _, plan := range plans {
+		count, applyError := plan.Apply(ctx, editor)
+		applied += count
+		if applyError != nil {
+			return nil, fmt.Errorf("The restore failed after %d of %d change(s): %s", applied, changeCount, applyError.Error())
+		}
+	}
+
+	return successMessage{fmt.Sprintf("Restored %d domain(s) from the snapshot of %s (%d change(s))", len(plans), s.Time.Local().Format("2006-01-02 15:04:05"), applied)}, nil
+}
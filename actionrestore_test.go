@@ -0,0 +1,107 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRestoreSnapshot is a snapshot in which www.example.com points to 1.2.3.4.
+var testRestoreSnapshot = snapshot{
+	Version: snapshotVersion,
+	Time:    time.Date(2016, 2, 10, 12, 0, 0, 0, time.UTC),
+	Zones: []snapshotZone{
+		{Domain: "example.com", Records: []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600}}},
+	},
+}
+
+// restoreAction.Execute should converge the zone to the snapshot if -yes is given.
+func Test_restoreAction_Yes_ChangesAreApplied(t *testing.T) {
+	// arrange
+	var changes []string
+	fs := afero.NewMemMapFs()
+	if _, err := writeSnapshot(fs, "/backups", testRestoreSnapshot); err != nil {
+		t.Fatalf("Unable to write the snapshot: %s", err.Error())
+	}
+
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "5.6.7.8", Ttl: 600}}, nil
+		},
+	}
+
+	action := restoreAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, nil, nil}
+	arguments := []string{"-snapshot", "/backups/example.com-20160210T120000Z.json", "-yes"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || len(changes) != 1 || changes[0] != "update 1 1.2.3.4" {
+		t.Fail()
+		t.Logf("restoreAction.Execute(%q) should restore 1.2.3.4 (changes: %v, result: %v, error: %v)", arguments, changes, result, err)
+	}
+}
+
+// restoreAction.Execute should not apply any changes without confirmation.
+func Test_restoreAction_NotConfirmed_NothingIsApplied(t *testing.T) {
+	// arrange
+	var changes []string
+	fs := afero.NewMemMapFs()
+	if _, err := writeSnapshot(fs, "/backups", testRestoreSnapshot); err != nil {
+		t.Fatalf("Unable to write the snapshot: %s", err.Error())
+	}
+
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "5.6.7.8", Ttl: 600}}, nil
+		},
+	}
+
+	action := restoreAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, nil, nil}
+	arguments := []string{"-snapshot", "/backups/example.com-20160210T120000Z.json"}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "without confirmation") || len(changes) != 0 {
+		t.Fail()
+		t.Logf("restoreAction.Execute(%q) should refuse to apply changes without confirmation (changes: %v, error: %v)", arguments, changes, err)
+	}
+}
+
+// restoreAction.Execute should only show the plan on a dry run.
+func Test_restoreAction_DryRun_PlanIsReturned(t *testing.T) {
+	// arrange
+	var changes []string
+	fs := afero.NewMemMapFs()
+	if _, err := writeSnapshot(fs, "/backups", testRestoreSnapshot); err != nil {
+		t.Fatalf("Unable to write the snapshot: %s", err.Error())
+	}
+
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "5.6.7.8", Ttl: 600}}, nil
+		},
+	}
+
+	action := restoreAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}, fs, nil, nil}
+	arguments := []string{"-snapshot", "/backups/example.com-20160210T120000Z.json", "-dry-run"}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || result.Text() != "~ www.example.com  A  600  5.6.7.8 → 1.2.3.4" || len(changes) != 0 {
+		t.Fail()
+		t.Logf("restoreAction.Execute(%q) should return the plan (changes: %v, result: %v, error: %v)", arguments, changes, result, err)
+	}
+}
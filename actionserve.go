@@ -0,0 +1,422 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/spf13/afero"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	actionNameServe = "serve"
+
+	serveArguments = flag.NewFlagSet(actionNameServe, flag.ContinueOnError)
+	serveListen    = serveArguments.String("listen", defaultServeAddress, "The address to listen on (e.g. 127.0.0.1:8080 or :8245 for all interfaces)")
+	serveUsers     = serveArguments.String("users", "", "The JSON file with the users and the hostnames they may update (default: ~/.dee/serve-users.json)")
+	serveTLSCert   = serveArguments.String("tls-cert", "", "A PEM encoded TLS certificate; serves HTTPS if given")
+	serveTLSKey    = serveArguments.String("tls-key", "", "The PEM encoded private key of the TLS certificate")
+	serveTTL       = serveArguments.Int("ttl", defaultTTL, "The time to live in seconds of created records")
+)
+
+// defaultServeAddress is the default listen address of the serve action.
+// The endpoint is only reachable from other hosts if an address is given.
+const defaultServeAddress = "localhost:8245"
+
+// The timeouts of the connections to the serve action.
+const (
+	serveReadHeaderTimeout = 10 * time.Second
+	serveReadTimeout       = 30 * time.Second
+	serveIdleTimeout       = 2 * time.Minute
+)
+
+// serveShutdownTimeout is the maximum duration of pending requests
+// after the server has been stopped.
+const serveShutdownTimeout = 10 * time.Second
+
+// The responses of the dyndns2 protocol.
+const (
+	dyndnsGood     = "good"
+	dyndnsNoChange = "nochg"
+	dyndnsBadAuth  = "badauth"
+	dyndnsNoHost   = "nohost"
+	dyndnsNotFQDN  = "notfqdn"
+	dyndnsError    = "911"
+)
+
+type serveAction struct {
+	newFactories func() (dnsEditorCreator, dnsInfoProviderCreator)
+	fs           afero.Fs
+	usersFile    string
+	stderr       io.Writer
+}
+
+func (action serveAction) Name() string {
+	return actionNameServe
+}
+
+func (action serveAction) Description() string {
+	return "Serve a dyndns2-compatible update endpoint for routers"
+}
+
+func (action serveAction) Usage() string {
+	buf := new(bytes.Buffer)
+	serveArguments.SetOutput(buf)
+	serveArguments.PrintDefaults()
+	return buf.String()
+}
+
+// Execute serves the dyndns2 endpoint /nic/update until the action is
+// interrupted. Each user may only update the hostnames of the users file.
+func (action serveAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*serveListen = defaultServeAddress
+	*serveUsers = action.usersFile
+	*serveTLSCert = ""
+	*serveTLSKey = ""
+	*serveTTL = defaults.TTL
+	if parseError := serveArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	if (*serveTLSCert == "") != (*serveTLSKey == "") {
+		return nil, fmt.Errorf("Please specify both -tls-cert and -tls-key")
+	}
+
+	if *serveTTL < 0 {
+		return nil, fmt.Errorf("The given TTL cannot be negative")
+	}
+
+	users, usersError := readServeUsers(action.fs, *serveUsers)
+	if usersError != nil {
+		return nil, usersError
+	}
+
+	// serve the update endpoint
+	handler := dyndnsHandler{users, action.newFactories, *serveTTL, action.stderr}
+	mux := http.NewServeMux()
+	mux.Handle("/nic/update", handler)
+
+	server := &http.Server{
+		Addr:              *serveListen,
+		Handler:           mux,
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+		ReadTimeout:       serveReadTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+
+	if *serveTLSCert == "" && !isLoopbackAddress(*serveListen) {
+		handler.printf("Warning: Serving plain HTTP on %s. The passwords can be read by anyone on the network; please use -tls-cert and -tls-key or a reverse proxy with HTTPS\n", *serveListen)
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		if *serveTLSCert != "" {
+			serverErrors <- server.ListenAndServeTLS(*serveTLSCert, *serveTLSKey)
+			return
+		}
+
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	handler.printf("Serving the dyndns2 endpoint /nic/update on %s for %d user(s)\n", *serveListen, len(users))
+
+	select {
+	case err := <-serverErrors:
+		return nil, fmt.Errorf("The server failed: %s", err.Error())
+
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return nil, fmt.Errorf("The server did not stop gracefully: %s", err.Error())
+	}
+
+	return successMessage{"Server stopped"}, nil
+}
+
+// isLoopbackAddress returns true if the given listen address
+// (e.g. "127.0.0.1:8245") is only reachable from the local host.
+func isLoopbackAddress(address string) bool {
+	host, _, splitError := net.SplitHostPort(address)
+	if splitError != nil {
+		return false
+	}
+
+	if strings.ToLower(host) == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveUser is a user of the dyndns2 endpoint.
+type serveUser struct {
+	// Username is the basic auth username.
+	Username string `json:"username"`
+
+	// Password is the basic auth password, either as plain text or
+	// as a hex encoded SHA-256 hash with the prefix "sha256:".
+	Password string `json:"password"`
+
+	// Hostnames contains the names the user may update. A "*" matches
+	// any part of a name (e.g. "*.branch.example.com").
+	Hostnames []string `json:"hostnames"`
+}
+
+// MayUpdate returns true if the user may update the given hostname.
+func (user serveUser) MayUpdate(hostname string) bool {
+	for _, pattern := range user.Hostnames {
+		if isMatch, _ := path.Match(pattern, hostname); isMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readServeUsers reads the users of the dyndns2 endpoint from the given file.
+// The hostname patterns are normalized.
+func readServeUsers(fs afero.Fs, filePath string) ([]serveUser, error) {
+	content, readError := afero.ReadFile(fs, filePath)
+	if readError != nil {
+		return nil, fmt.Errorf("Unable to read the users file %q: %s", filePath, readError.Error())
+	}
+
+	var users []serveUser
+	if err := json.Unmarshal(content, &users); err != nil {
+		return nil, fmt.Errorf("The users file %q is invalid: %s", filePath, err.Error())
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("The users file %q contains no users", filePath)
+	}
+
+	usernames := make(map[string]bool)
+	for index, user := range users {
+		if user.Username == "" || user.Password == "" {
+			return nil, fmt.Errorf("The user %d in %q has no username or password", index+1, filePath)
+		}
+
+		if usernames[user.Username] {
+			return nil, fmt.Errorf("The user %q is listed twice in %q", user.Username, filePath)
+		}
+
+		usernames[user.Username] = true
+
+		if len(user.Hostnames) == 0 {
+			return nil, fmt.Errorf("The user %q in %q may not update any hostnames", user.Username, filePath)
+		}
+
+		for hostnameIndex, pattern := range user.Hostnames {
+			hostname, nameError := normalizeName(pattern)
+			if _, patternError := path.Match(hostname, ""); nameError != nil || patternError != nil || hostname == "" {
+				return nil, fmt.Errorf("The hostname %q of the user %q in %q is invalid", pattern, user.Username, filePath)
+			}
+
+			users[index].Hostnames[hostnameIndex] = hostname
+		}
+	}
+
+	return users, nil
+}
+
+// authenticateServeUser returns the user with the given username and password.
+// Returns false if there is no such user or the password is wrong.
+func authenticateServeUser(users []serveUser, username, password string) (serveUser, bool) {
+	for _, user := range users {
+		if user.Username == username && checkServePassword(user.Password, password) {
+			return user, true
+		}
+	}
+
+	return serveUser{}, false
+}
+
+// checkServePassword returns true if the given password matches the
+// stored password. The passwords are compared in constant time.
+func checkServePassword(storedPassword, password string) bool {
+	hash := sha256.Sum256([]byte(password))
+	if strings.HasPrefix(storedPassword, "sha256:") {
+		storedHash, decodeError := hex.DecodeString(strings.TrimPrefix(storedPassword, "sha256:"))
+		return decodeError == nil && subtle.ConstantTimeCompare(storedHash, hash[:]) == 1
+	}
+
+	storedHash := sha256.Sum256([]byte(storedPassword))
+	return subtle.ConstantTimeCompare(storedHash[:], hash[:]) == 1
+}
+
+// dyndnsHandler handles the update requests of the dyndns2 protocol
+// (e.g. /nic/update?hostname=office.example.com&myip=1.2.3.4).
+type dyndnsHandler struct {
+	users        []serveUser
+	newFactories func() (dnsEditorCreator, dnsInfoProviderCreator)
+	ttl          int
+	stderr       io.Writer
+}
+
+// ServeHTTP updates the address records of the requested hostnames. The
+// response contains one line per hostname. If no IP address is given the
+// address of the client is used. The myip parameter can contain an IPv4
+// and an IPv6 address separated by a comma.
+func (handler dyndnsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	username, password, hasCredentials := r.BasicAuth()
+	user, isAuthenticated := authenticateServeUser(handler.users, username, password)
+	if !hasCredentials || !isAuthenticated {
+		handler.printf("%s: Authentication failed for %q\n", r.RemoteAddr, username)
+		w.Header().Set("WWW-Authenticate", `Basic realm="dee"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, dyndnsBadAuth)
+		return
+	}
+
+	var hostnames []string
+	for _, hostname := range strings.Split(r.URL.Query().Get("hostname"), ",") {
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	if len(hostnames) == 0 {
+		fmt.Fprintln(w, dyndnsNotFQDN)
+		return
+	}
+
+	ips, ipError := getDyndnsIPs(r)
+	if ipError != nil {
+		handler.printf("%s: %s\n", username, ipError.Error())
+		fmt.Fprintln(w, dyndnsError)
+		return
+	}
+
+	ctx := withActionName(r.Context(), actionNameServe)
+	for _, hostname := range hostnames {
+		fmt.Fprintln(w, handler.update(ctx, user, hostname, ips))
+	}
+}
+
+// update sets the given addresses for the given hostname and
+// returns the dyndns2 response (e.g. "good 1.2.3.4").
+func (handler dyndnsHandler) update(ctx context.Context, user serveUser, hostname string, ips []net.IP) string {
+	name, nameError := normalizeName(hostname)
+	if nameError != nil || !strings.Contains(name, ".") {
+		return dyndnsNotFQDN
+	}
+
+	if !user.MayUpdate(name) {
+		handler.printf("%s: Not allowed to update %s\n", user.Username, toDisplayName(name))
+		return dyndnsNoHost
+	}
+
+	// a fresh zone snapshot for every request
+	dnsEditorFactory, infoProviderFactory := handler.newFactories()
+
+	infoProvider, infoProviderError := infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		handler.printf("%s: Cannot create DNS info provider: %s\n", user.Username, infoProviderError.Error())
+		return dyndnsError
+	}
+
+	subdomain, domain, resolveError := resolveName(ctx, infoProviderFactory, name, "", "")
+	if resolveError != nil {
+		handler.printf("%s: %s\n", user.Username, resolveError.Error())
+		return dyndnsNoHost
+	}
+
+	changed := false
+	var addresses []string
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
+
+		record, recordError := infoProvider.GetSubdomainRecord(ctx, domain, subdomain, getDNSRecordTypeByIP(ip))
+		if recordError == nil && record.Content == ip.String() {
+			continue
+		}
+
+		if _, changeError := createOrUpdateAddress(ctx, dnsEditorFactory, infoProviderFactory, domain, subdomain, handler.ttl, ip); changeError != nil {
+			handler.printf("%s: %s\n", user.Username, changeError.Error())
+			return dyndnsError
+		}
+
+		handler.printf("%s: %s → %s\n", user.Username, getFormattedDomainName(subdomain, domain), ip.String())
+		changed = true
+	}
+
+	if !changed {
+		return fmt.Sprintf("%s %s", dyndnsNoChange, strings.Join(addresses, ","))
+	}
+
+	return fmt.Sprintf("%s %s", dyndnsGood, strings.Join(addresses, ","))
+}
+
+// printf writes the given message with a timestamp to stderr (if available).
+func (handler dyndnsHandler) printf(format string, args ...interface{}) {
+	if handler.stderr == nil {
+		return
+	}
+
+	fmt.Fprintf(handler.stderr, "%s %s", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// getDyndnsIPs returns the addresses of the myip and myipv6 parameters of the
+// given request or, if both are empty, the address of the client. Returns an
+// error if an address is invalid or if more than one address per type is given.
+func getDyndnsIPs(r *http.Request) ([]net.IP, error) {
+	var values []string
+	for _, parameter := range []string{"myip", "myipv6"} {
+		for _, value := range strings.Split(r.URL.Query().Get(parameter), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		host, _, splitError := net.SplitHostPort(r.RemoteAddr)
+		if splitError != nil {
+			host = r.RemoteAddr
+		}
+
+		values = []string{host}
+	}
+
+	var ips []net.IP
+	recordTypes := make(map[string]bool)
+	for _, value := range values {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("Cannot parse IP %q", value)
+		}
+
+		recordType := getDNSRecordTypeByIP(ip)
+		if recordTypes[recordType] {
+			return nil, fmt.Errorf("More than one %s address given: %s", recordType, strings.Join(values, ","))
+		}
+
+		recordTypes[recordType] = true
+		ips = append(ips, ip)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testServeUsers contains the users of the dyndns2 endpoint tests.
+var testServeUsers = []serveUser{
+	{Username: "office", Password: "secret", Hostnames: []string{"office.example.com"}},
+	{Username: "branches", Password: "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Hostnames: []string{"*.branch.example.com"}},
+}
+
+// sendDyndnsRequest sends the given update request with the given credentials to the given handler.
+func sendDyndnsRequest(handler dyndnsHandler, url, username, password string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.RemoteAddr = "203.0.113.7:43210"
+	if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+// dyndnsHandler.ServeHTTP should return the dyndns2 response for the given requests.
+func Test_dyndnsHandler_ServeHTTP(t *testing.T) {
+	records := []dnsimple.Record{
+		{Id: 1, Name: "office", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+		{Id: 2, Name: "berlin.branch", RecordType: "A", Content: "5.6.7.8", Ttl: 600},
+	}
+
+	inputs := []struct {
+		url              string
+		username         string
+		password         string
+		expectedStatus   int
+		expectedResponse string
+		expectedChanges  string
+	}{
+		// updated
+		{"/nic/update?hostname=office.example.com&myip=9.9.9.9", "office", "secret", http.StatusOK, "good 9.9.9.9\n", "update office 9.9.9.9"},
+
+		// unchanged
+		{"/nic/update?hostname=office.example.com&myip=1.2.3.4", "office", "secret", http.StatusOK, "nochg 1.2.3.4\n", ""},
+
+		// the address of the client
+		{"/nic/update?hostname=office.example.com", "office", "secret", http.StatusOK, "good 203.0.113.7\n", "update office 203.0.113.7"},
+
+		// IPv4 and IPv6
+		{"/nic/update?hostname=office.example.com&myip=1.2.3.4,2001:db8::1", "office", "secret", http.StatusOK, "good 1.2.3.4,2001:db8::1\n", "create office 2001:db8::1 600"},
+
+		// created, multiple hostnames, hashed password
+		{"/nic/update?hostname=hamburg.branch.example.com,berlin.branch.example.com&myip=5.6.7.8", "branches", "test", http.StatusOK, "good 5.6.7.8\nnochg 5.6.7.8\n", "create hamburg.branch 5.6.7.8 600"},
+
+		// hostname of another user
+		{"/nic/update?hostname=office.example.com&myip=9.9.9.9", "branches", "test", http.StatusOK, "nohost\n", ""},
+
+		// unknown domain
+		{"/nic/update?hostname=office.example.net&myip=9.9.9.9", "office", "secret", http.StatusOK, "nohost\n", ""},
+
+		// no hostname
+		{"/nic/update?myip=9.9.9.9", "office", "secret", http.StatusOK, "notfqdn\n", ""},
+
+		// wrong password
+		{"/nic/update?hostname=office.example.com&myip=9.9.9.9", "office", "wrong", http.StatusUnauthorized, "badauth\n", ""},
+
+		// no credentials
+		{"/nic/update?hostname=office.example.com&myip=9.9.9.9", "", "", http.StatusUnauthorized, "badauth\n", ""},
+
+		// invalid address
+		{"/nic/update?hostname=office.example.com&myip=1.2.3", "office", "secret", http.StatusOK, "911\n", ""},
+	}
+
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		getSubdomainRecordFunc: func(domain, subdomain, recordType string) (dnsimple.Record, error) {
+			for _, record := range records {
+				if record.Name == subdomain && record.RecordType == recordType {
+					return record, nil
+				}
+			}
+
+			return dnsimple.Record{}, fmt.Errorf("Record not found")
+		},
+	}
+
+	for _, input := range inputs {
+		// arrange
+		var changes []string
+		newFactories := func() (dnsEditorCreator, dnsInfoProviderCreator) {
+			return testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}
+		}
+
+		handler := dyndnsHandler{testServeUsers, newFactories, 600, nil}
+
+		// act
+		response := sendDyndnsRequest(handler, input.url, input.username, input.password)
+
+		// assert
+		if response.Code != input.expectedStatus || response.Body.String() != input.expectedResponse || strings.Join(changes, ", ") != input.expectedChanges {
+			t.Fail()
+			t.Logf("%s (user %q) returned %d %q with the changes %q but should have returned %d %q with the changes %q", input.url, input.username, response.Code, response.Body.String(), strings.Join(changes, ", "), input.expectedStatus, input.expectedResponse, input.expectedChanges)
+		}
+	}
+}
+
+// readServeUsers should normalize the hostnames and reject invalid users.
+func Test_readServeUsers(t *testing.T) {
+	inputs := []struct {
+		content       string
+		expectedError string
+	}{
+		{`[{"username": "office", "password": "secret", "hostnames": ["Office.Example.com.", "*.Branch.example.com"]}]`, ""},
+		{`[]`, `The users file "/users.json" contains no users`},
+		{`[{"username": "office", "hostnames": ["office.example.com"]}]`, `The user 1 in "/users.json" has no username or password`},
+		{`[{"username": "office", "password": "a", "hostnames": ["a.example.com"]}, {"username": "office", "password": "b", "hostnames": ["b.example.com"]}]`, `The user "office" is listed twice in "/users.json"`},
+		{`[{"username": "office", "password": "secret", "hostnames": []}]`, `The user "office" in "/users.json" may not update any hostnames`},
+	}
+
+	for _, input := range inputs {
+		// arrange
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/users.json", []byte(input.content), 0600)
+
+		// act
+		users, err := readServeUsers(fs, "/users.json")
+
+		// assert
+		if input.expectedError != "" {
+			if err == nil || err.Error() != input.expectedError {
+				t.Fail()
+				t.Logf("readServeUsers(%s) returned the error %v but should have returned %q", input.content, err, input.expectedError)
+			}
+
+			continue
+		}
+
+		if err != nil || strings.Join(users[0].Hostnames, ",") != "office.example.com,*.branch.example.com" {
+			t.Fail()
+			t.Logf("readServeUsers(%s) returned %+v (error: %v)", input.content, users, err)
+		}
+	}
+}
+
+// isLoopbackAddress should only accept addresses which are not reachable from other hosts.
+func Test_isLoopbackAddress(t *testing.T) {
+	inputs := []struct {
+		address  string
+		expected bool
+	}{
+		{"localhost:8245", true},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8245", true},
+		{":8245", false},
+		{"0.0.0.0:8245", false},
+		{"192.168.1.10:8245", false},
+		{"invalid", false},
+	}
+
+	for _, input := range inputs {
+		// act
+		result := isLoopbackAddress(input.address)
+
+		// assert
+		if result != input.expected {
+			t.Fail()
+			t.Logf("isLoopbackAddress(%q) returned %t but should have returned %t", input.address, result, input.expected)
+		}
+	}
+}
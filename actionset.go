@@ -0,0 +1,255 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var (
+	actionNameSet = "set"
+
+	setOperationAdd     = "add"
+	setOperationRemove  = "remove"
+	setOperationReplace = "replace"
+
+	setArguments = flag.NewFlagSet(actionNameSet, flag.ContinueOnError)
+	setDomain    = setArguments.String("domain", "", "Domain (e.g. example.com)")
+	setName      = setArguments.String("name", "", "Subdomain (e.g. api)")
+	setIPs       = &stringList{}
+	setType      = setArguments.String("type", "", "Only change the records of the given type (A or AAAA); replace empties the set if no -ip is given")
+	setTTL       = setArguments.Int("ttl", defaultTTL, "The time to live of new records in seconds")
+)
+
+func init() {
+	setArguments.Var(setIPs, "ip", "IP address; can be given multiple times or as a comma-separated list (e.g. 10.0.0.1,10.0.0.2)")
+}
+
+type setAction struct {
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+}
+
+func (action setAction) Name() string {
+	return actionNameSet
+}
+
+func (action setAction) Description() string {
+	return "Add, remove or replace the addresses of a round-robin record set"
+}
+
+func (action setAction) Usage() string {
+	buf := new(bytes.Buffer)
+	setArguments.SetOutput(buf)
+	setArguments.PrintDefaults()
+	return fmt.Sprintf("  <%s|%s|%s> [arguments ...]\n\n%s", setOperationAdd, setOperationRemove, setOperationReplace, buf.String())
+}
+
+// Execute adds the given IP addresses to, removes them from or replaces the
+// address record set of the given subdomain. The operation is idempotent.
+func (action setAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	if len(arguments) == 0 {
+		return nil, fmt.Errorf("No operation supplied (%s, %s or %s)", setOperationAdd, setOperationRemove, setOperationReplace)
+	}
+
+	operation := strings.ToLower(arguments[0])
+	if operation != setOperationAdd && operation != setOperationRemove && operation != setOperationReplace {
+		return nil, fmt.Errorf("Unknown operation: %q", arguments[0])
+	}
+
+	// parse the arguments
+	*setDomain = defaults.Domain
+	*setName = ""
+	*setIPs = stringList{}
+	*setType = ""
+	*setTTL = defaults.TTL
+	if parseError := setArguments.Parse(arguments[1:]); parseError != nil {
+		return nil, parseError
+	}
+
+	var nameError error
+	*setName, *setDomain, nameError = resolveName(ctx, action.infoProviderFactory, "", *setName, *setDomain)
+	if nameError != nil {
+		return nil, nameError
+	}
+
+	if *setDomain == "" {
+		return nil, fmt.Errorf("No domain supplied")
+	}
+
+	if *setTTL < 0 {
+		return nil, fmt.Errorf("The given TTL cannot be negative")
+	}
+
+	if len(*setIPs) == 0 && (operation != setOperationReplace || *setType == "") {
+		return nil, fmt.Errorf("No IP address supplied")
+	}
+
+	// group the given addresses by record type
+	addresses := map[string][]net.IP{}
+	for _, value := range *setIPs {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("Cannot parse IP %q", value)
+		}
+
+		recordType := getDNSRecordTypeByIP(ip)
+		addresses[recordType] = append(addresses[recordType], ip)
+	}
+
+	// determine the record sets to change
+	recordTypes, typeError := getRecordSetTypes(operation, strings.ToUpper(*setType), addresses)
+	if typeError != nil {
+		return nil, typeError
+	}
+
+	// info provider
+	if action.infoProviderFactory == nil {
+		return nil, fmt.Errorf("No DNS info provider available")
+	}
+
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("No DNS info provider available")
+	}
+
+	// create a DNS editor
+	var recordSetEditor dnsRecordSetEditor
+	recordSetEditor, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	if dnsEditorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
+	}
+
+	records, recordsError := infoProvider.GetSubdomainRecords(ctx, *setDomain, *setName)
+	if recordsError != nil {
+		return nil, fmt.Errorf("Unable to fetch the records of %s: %s", getFormattedDomainName(*setName, *setDomain), recordsError.Error())
+	}
+
+	var lines []string
+	for _, recordType := range recordTypes {
+
+		// the current addresses of the record set
+		var current []net.IP
+		for _, record := range records {
+			if record.RecordType != recordType {
+				continue
+			}
+
+			if ip := net.ParseIP(record.Content); ip != nil {
+				current = append(current, ip)
+			}
+		}
+
+		target := getTargetAddressSet(operation, current, addresses[recordType])
+
+		changes, setError := recordSetEditor.SetSubdomainAddresses(ctx, *setDomain, *setName, recordType, *setTTL, target)
+		lines = append(lines, formatAddressSetChanges(*setName, *setDomain, recordType, changes))
+
+		if setError != nil {
+			description := describeChange(operation, *setName, *setDomain, recordType, formatIPs(addresses[recordType]))
+			return nil, fmt.Errorf("%s\n%s", strings.Join(lines, "\n"), getChangeError(ctx, description, setError).Error())
+		}
+	}
+
+	return successMessage{strings.Join(lines, "\n")}, nil
+}
+
+// getRecordSetTypes returns the record types of the record sets
+// that are changed by the given operation.
+func getRecordSetTypes(operation, recordType string, addresses map[string][]net.IP) ([]string, error) {
+	if recordType != "" && recordType != "A" && recordType != "AAAA" {
+		return nil, fmt.Errorf("The given record type is invalid: %q", recordType)
+	}
+
+	for addressType := range addresses {
+		if recordType != "" && addressType != recordType {
+			return nil, fmt.Errorf("The given addresses do not match the record type %q", recordType)
+		}
+	}
+
+	// replace can empty a record set if its type is given explicitly
+	if operation == setOperationReplace && recordType != "" {
+		return []string{recordType}, nil
+	}
+
+	var recordTypes []string
+	for _, addressType := range []string{"A", "AAAA"} {
+		if len(addresses[addressType]) > 0 {
+			recordTypes = append(recordTypes, addressType)
+		}
+	}
+
+	return recordTypes, nil
+}
+
+// getTargetAddressSet returns the addresses a record set with the given
+// current addresses should have after the given operation.
+func getTargetAddressSet(operation string, current, given []net.IP) []net.IP {
+	switch operation {
+
+	case setOperationAdd:
+		return append(append([]net.IP{}, current...), given...)
+
+	case setOperationRemove:
+		var target []net.IP
+		for _, ip := range current {
+			if !containsIP(given, ip) {
+				target = append(target, ip)
+			}
+		}
+
+		return target
+
+	}
+
+	return given
+}
+
+// containsIP returns true if the given list contains the given IP address.
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatAddressSetChanges returns a summary of the given record set changes
+// (e.g. "api.example.com (A): added 10.0.0.3; removed 10.0.0.1").
+func formatAddressSetChanges(subdomain, domain, recordType string, changes addressSetChanges) string {
+	name := fmt.Sprintf("%s (%s)", getFormattedDomainName(subdomain, domain), recordType)
+	if changes.IsEmpty() {
+		return fmt.Sprintf("%s: no changes required", name)
+	}
+
+	var parts []string
+	if len(changes.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("added %s", formatIPs(changes.Added)))
+	}
+
+	if len(changes.Removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removed %s", formatIPs(changes.Removed)))
+	}
+
+	return fmt.Sprintf("%s: %s", name, strings.Join(parts, "; "))
+}
+
+// formatIPs returns the given IP addresses as a comma-separated list.
+func formatIPs(ips []net.IP) string {
+	var addresses []string
+	for _, ip := range ips {
+		addresses = append(addresses, ip.String())
+	}
+
+	return strings.Join(addresses, ", ")
+}
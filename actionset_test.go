@@ -0,0 +1,173 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"strings"
+	"testing"
+)
+
+var testRecordSet = []dnsimple.Record{
+	{Id: 1, Name: "api", RecordType: "A", Content: "10.0.0.1"},
+	{Id: 2, Name: "api", RecordType: "A", Content: "10.0.0.2"},
+	{Id: 3, Name: "api", RecordType: "AAAA", Content: "2001:db8::1"},
+}
+
+// setAction.Execute should return an error if the operation is unknown.
+func Test_setAction_UnknownOperation_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"merge", "-domain", "example.com", "-name", "api", "-ip", "10.0.0.3"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return testRecordSet, nil
+		},
+	}
+
+	action := setAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("setAction.Execute(%q) should return an error because the operation is unknown", arguments)
+	}
+}
+
+// setAction.Execute add should keep the existing addresses of the record set.
+func Test_setAction_Add_AddressIsAddedToSet(t *testing.T) {
+	// arrange
+	arguments := []string{"add", "-domain", "example.com", "-name", "api", "-ip", "10.0.0.3"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return testRecordSet, nil
+		},
+	}
+
+	action := setAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || strings.Join(changes, ", ") != "set api A 10.0.0.1,10.0.0.2,10.0.0.3" {
+		t.Fail()
+		t.Logf("setAction.Execute(%q) should add the address to the A record set (error: %v, changes: %v)", arguments, err, changes)
+	}
+}
+
+// setAction.Execute remove should only remove the given addresses.
+func Test_setAction_Remove_AddressIsRemovedFromSet(t *testing.T) {
+	// arrange
+	arguments := []string{"remove", "-domain", "example.com", "-name", "api", "-ip", "10.0.0.1"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return testRecordSet, nil
+		},
+	}
+
+	action := setAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || strings.Join(changes, ", ") != "set api A 10.0.0.2" {
+		t.Fail()
+		t.Logf("setAction.Execute(%q) should remove the address from the A record set (error: %v, changes: %v)", arguments, err, changes)
+	}
+}
+
+// setAction.Execute replace should only converge the record sets of the given address families.
+func Test_setAction_Replace_OnlyGivenAddressFamilyIsReplaced(t *testing.T) {
+	// arrange
+	arguments := []string{"replace", "-domain", "example.com", "-name", "api", "-ip", "10.0.0.5,10.0.0.6"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return testRecordSet, nil
+		},
+	}
+
+	action := setAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || strings.Join(changes, ", ") != "set api A 10.0.0.5,10.0.0.6" {
+		t.Fail()
+		t.Logf("setAction.Execute(%q) should replace the A set and leave the AAAA set untouched (error: %v, changes: %v)", arguments, err, changes)
+	}
+}
+
+// setAction.Execute replace should empty the record set of the given type if no addresses are given.
+func Test_setAction_ReplaceWithTypeAndNoAddresses_SetIsEmptied(t *testing.T) {
+	// arrange
+	arguments := []string{"replace", "-domain", "example.com", "-name", "api", "-type", "AAAA"}
+	var changes []string
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return testRecordSet, nil
+		},
+	}
+
+	action := setAction{testDNSEditorFactory{newRecordingDNSEditor(&changes), nil}, testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || strings.Join(changes, ", ") != "set api AAAA" {
+		t.Fail()
+		t.Logf("setAction.Execute(%q) should empty the AAAA set (error: %v, changes: %v)", arguments, err, changes)
+	}
+}
+
+// DNSEditor.SetSubdomainAddresses should not change anything if the set already has the given addresses.
+func Test_DNSEditor_SetSubdomainAddresses_SetIsUnchanged_NoChangesAreMade(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testRecordSet)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	ips := []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")}
+
+	// act
+	changes, err := editor.SetSubdomainAddresses(context.Background(), "example.com", "api", "A", 600, ips)
+
+	// assert
+	if err != nil || !changes.IsEmpty() || client.calls["CreateRecord"] != 0 || client.calls["DestroyRecord"] != 0 {
+		t.Fail()
+		t.Logf("SetSubdomainAddresses should not change an up-to-date record set (error: %v, changes: %v)", err, changes)
+	}
+}
+
+// DNSEditor.SetSubdomainAddresses should create the missing and delete the surplus records.
+func Test_DNSEditor_SetSubdomainAddresses_SetDiffers_SetIsConverged(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testRecordSet)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	ips := []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}
+
+	// act
+	changes, err := editor.SetSubdomainAddresses(context.Background(), "example.com", "api", "A", 600, ips)
+
+	// assert
+	if err != nil || formatIPs(changes.Added) != "10.0.0.3" || formatIPs(changes.Removed) != "10.0.0.1" {
+		t.Fail()
+		t.Logf("SetSubdomainAddresses should add 10.0.0.3 and remove 10.0.0.1 (error: %v, changes: %v)", err, changes)
+	}
+
+	if client.calls["CreateRecord"] != 1 || client.calls["DestroyRecord"] != 1 {
+		t.Fail()
+		t.Logf("SetSubdomainAddresses should create and delete one record each (%v)", client.calls)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"strings"
+)
+
+var (
+	actionNameUndo = "undo"
+
+	undoArguments = flag.NewFlagSet(actionNameUndo, flag.ContinueOnError)
+	undoID        = undoArguments.String("id", "", "The id of the change in the history (default: the most recent change)")
+)
+
+type undoAction struct {
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	log                 *historyLog
+}
+
+func (action undoAction) Name() string {
+	return actionNameUndo
+}
+
+func (action undoAction) Description() string {
+	return "Revert the most recent change or the change with the given id"
+}
+
+func (action undoAction) Usage() string {
+	buf := new(bytes.Buffer)
+	undoArguments.SetOutput(buf)
+	undoArguments.PrintDefaults()
+	return fmt.Sprintf("  [id] [arguments ...]\n\n%s", buf.String())
+}
+
+// Execute reverts the most recent change of the history or the change with
+// the given id. A change is only reverted if the record still has the values
+// the change left behind.
+func (action undoAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*undoID = ""
+	positionalArguments, parseError := parseArguments(undoArguments, arguments)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	if len(positionalArguments) > 1 {
+		return nil, fmt.Errorf("Too many arguments: %s", strings.Join(positionalArguments[1:], " "))
+	}
+
+	if len(positionalArguments) == 1 {
+		if *undoID != "" {
+			return nil, fmt.Errorf("%q was given twice: as an argument and as a flag", positionalArguments[0])
+		}
+
+		*undoID = positionalArguments[0]
+	}
+
+	// find the change
+	entries, entriesError := action.log.Entries()
+	if entriesError != nil {
+		return nil, fmt.Errorf("Unable to read the history: %s", entriesError.Error())
+	}
+
+	change, changeError := findUndoableChange(entries, *undoID)
+	if changeError != nil {
+		return nil, changeError
+	}
+
+	// revert the change
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	editor, editorError := action.dnsEditorFactory.CreateDNSEditor()
+	if editorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", editorError.Error())
+	}
+
+	ctx = withUndoneChangeID(ctx, change.ID)
+
+	var undoError error
+	switch change.Operation {
+	case historyOperationCreate:
+		undoError = undoCreate(ctx, infoProvider, editor, change)
+
+	case historyOperationUpdate:
+		undoError = undoUpdate(ctx, infoProvider, editor, change)
+
+	case historyOperationDelete:
+		undoError = undoDelete(ctx, infoProvider, editor, change)
+
+	default:
+		undoError = fmt.Errorf("Changes of type %q cannot be undone", change.Operation)
+	}
+
+	if undoError != nil {
+		return nil, fmt.Errorf("Unable to undo change %s: %s", change.ID, undoError.Error())
+	}
+
+	return successMessage{fmt.Sprintf("Undid the %s of %s (%s): %s", change.Operation, getFormattedDomainName(change.Name(), change.Domain), getHistoryRecordType(change), formatHistoryUndo(change))}, nil
+}
+
+// findUndoableChange returns the change with the given id or, if no id is
+// given, the most recent applied change which has not been undone yet.
+// Undos are skipped so that repeated undos walk back through the history.
+func findUndoableChange(entries []historyEntry, id string) (historyEntry, error) {
+	undoneBy := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Undoes != "" && entry.Outcome == historyOutcomeApplied {
+			undoneBy[entry.Undoes] = entry.ID
+		}
+	}
+
+	if id == "" {
+		for index := len(entries) - 1; index >= 0; index-- {
+			entry := entries[index]
+			if entry.Outcome != historyOutcomeApplied || entry.Undoes != "" || undoneBy[entry.ID] != "" {
+				continue
+			}
+
+			return entry, nil
+		}
+
+		return historyEntry{}, fmt.Errorf("No change to undo found in the history")
+	}
+
+	for _, entry := range entries {
+		if entry.ID != id {
+			continue
+		}
+
+		if entry.Outcome != historyOutcomeApplied {
+			return historyEntry{}, fmt.Errorf("The change %s cannot be undone because it was not applied (%s)", id, entry.Outcome)
+		}
+
+		if undoneBy[entry.ID] != "" {
+			return historyEntry{}, fmt.Errorf("The change %s has already been undone by change %s", id, undoneBy[entry.ID])
+		}
+
+		return entry, nil
+	}
+
+	return historyEntry{}, fmt.Errorf("No change with the id %q found in the history", id)
+}
+
+// undoCreate deletes the record created by the given change.
+func undoCreate(ctx context.Context, infoProvider dnsInfoProvider, editor dnsRecordEditor, change historyEntry) error {
+	if change.After == nil || change.RecordID == "" {
+		return fmt.Errorf("The id of the created record is unknown")
+	}
+
+	if err := checkUnchanged(ctx, infoProvider, change); err != nil {
+		return err
+	}
+
+	return editor.DeleteRecordByID(ctx, change.Domain, change.RecordID)
+}
+
+// undoUpdate restores the values the record had before the given change.
+func undoUpdate(ctx context.Context, infoProvider dnsInfoProvider, editor dnsRecordEditor, change historyEntry) error {
+	if change.Before == nil || change.After == nil {
+		return fmt.Errorf("The values of the record before the update are unknown")
+	}
+
+	if err := checkUnchanged(ctx, infoProvider, change); err != nil {
+		return err
+	}
+
+	ttl := int(change.Before.TTL)
+	recordChange := recordUpdate{Content: &change.Before.Content, TTL: &ttl}
+
+	if change.Before.Name != change.After.Name {
+		recordChange.Name = &change.Before.Name
+	}
+
+	if change.Before.Priority != change.After.Priority {
+		priority := int(change.Before.Priority)
+		recordChange.Priority = &priority
+	}
+
+	return editor.UpdateRecordByID(ctx, change.Domain, change.RecordID, recordChange)
+}
+
+// undoDelete recreates the record deleted by the given change.
+func undoDelete(ctx context.Context, infoProvider dnsInfoProvider, editor dnsRecordEditor, change historyEntry) error {
+	if change.Before == nil {
+		return fmt.Errorf("The values of the deleted record are unknown")
+	}
+
+	records, recordsError := infoProvider.GetSubdomainRecords(ctx, change.Domain, change.Before.Name)
+	if recordsError != nil {
+		return recordsError
+	}
+
+	for _, record := range records {
+		if record.RecordType == change.Before.Type && record.Content == change.Before.Content {
+			return fmt.Errorf("The record has already been recreated (id: %d)", record.Id)
+		}
+	}
+
+	_, createError := editor.CreateRecord(ctx, change.Domain, recordFields{
+		Name:       change.Before.Name,
+		Content:    change.Before.Content,
+		RecordType: change.Before.Type,
+		TTL:        change.Before.TTL,
+		Priority:   change.Before.Priority,
+	})
+
+	return createError
+}
+
+// checkUnchanged returns an error if the record of the given change
+// no longer has the values the change left behind.
+func checkUnchanged(ctx context.Context, infoProvider dnsInfoProvider, change historyEntry) error {
+	record, recordError := infoProvider.GetRecord(ctx, change.Domain, change.RecordID)
+	if recordError != nil {
+		return fmt.Errorf("The record %s no longer exists", change.RecordID)
+	}
+
+	if !recordHasValues(record, *change.After) {
+		return fmt.Errorf("The record has been changed since: expected %s but found %s", describeHistoryRecord(*change.After), describeHistoryRecord(*newHistoryRecord(record)))
+	}
+
+	return nil
+}
+
+// recordHasValues returns true if the given record has the given values.
+// The TTL and priority are only compared if they are known.
+func recordHasValues(record dnsimple.Record, values historyRecord) bool {
+	if !strings.EqualFold(record.Name, values.Name) || record.RecordType != values.Type || record.Content != values.Content {
+		return false
+	}
+
+	if values.TTL != 0 && record.Ttl != values.TTL {
+		return false
+	}
+
+	if values.Priority != 0 && record.Prio != values.Priority {
+		return false
+	}
+
+	return true
+}
+
+// describeHistoryRecord returns the content and TTL of the given record (e.g. "1.2.3.4 (TTL 600)").
+func describeHistoryRecord(record historyRecord) string {
+	return fmt.Sprintf("%q (TTL %d)", record.Content, record.TTL)
+}
+
+// formatHistoryUndo returns the content of the record before and after
+// reverting the given change (e.g. "5.6.7.8 → 1.2.3.4").
+func formatHistoryUndo(entry historyEntry) string {
+	return formatHistoryChange(historyEntry{Before: entry.After, After: entry.Before})
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"testing"
+	"time"
+)
+
+// newTestUndoLog returns a history log which contains the given changes.
+func newTestUndoLog(changes ...historyEntry) *historyLog {
+	log := newTestHistoryLog(time.Now())
+	for _, change := range changes {
+		log.Record(withUndoneChangeID(context.Background(), change.Undoes), change)
+	}
+
+	return log
+}
+
+var testUndoUpdate = historyEntry{
+	Operation: historyOperationUpdate,
+	Domain:    "example.com",
+	RecordID:  "1",
+	Before:    &historyRecord{Name: "www", Type: "A", Content: "1.2.3.4", TTL: 600},
+	After:     &historyRecord{Name: "www", Type: "A", Content: "5.6.7.8", TTL: 600},
+	Outcome:   historyOutcomeApplied,
+}
+
+var testUndoDelete = historyEntry{
+	Operation: historyOperationDelete,
+	Domain:    "example.com",
+	RecordID:  "3",
+	Before:    &historyRecord{Name: "", Type: "MX", Content: "mail.example.com", TTL: 3600, Priority: 10},
+	Outcome:   historyOutcomeApplied,
+}
+
+// undoAction.Execute should restore the previous content of the most recently updated record.
+func Test_undoAction_LatestUpdate_PreviousValuesAreRestored(t *testing.T) {
+	// arrange
+	infoProvider := testDNSInfoProvider{
+		getRecordFunc: func(domain, id string) (dnsimple.Record, error) {
+			return dnsimple.Record{Id: 1, Name: "www", RecordType: "A", Content: "5.6.7.8", Ttl: 600}, nil
+		},
+	}
+
+	var restoredContent string
+	editor := testDNSEditor{
+		updateRecordByIDFunc: func(domain, id string, change recordUpdate) error {
+			restoredContent = *change.Content
+			return nil
+		},
+	}
+
+	action := undoAction{testDNSEditorFactory{editor, nil}, testInfoProviderFactory{infoProvider, nil}, newTestUndoLog(testUndoDelete, testUndoUpdate)}
+
+	// act
+	_, err := action.Execute(context.Background(), []string{})
+
+	// assert
+	if err != nil || restoredContent != "1.2.3.4" {
+		t.Fail()
+		t.Logf("undoAction.Execute should restore 1.2.3.4 (restored: %q, error: %v)", restoredContent, err)
+	}
+}
+
+// undoAction.Execute should not revert a change if the record has been changed since.
+func Test_undoAction_RecordChangedSince_ErrorIsReturned(t *testing.T) {
+	// arrange
+	infoProvider := testDNSInfoProvider{
+		getRecordFunc: func(domain, id string) (dnsimple.Record, error) {
+			return dnsimple.Record{Id: 1, Name: "www", RecordType: "A", Content: "9.9.9.9", Ttl: 600}, nil
+		},
+	}
+
+	editor := testDNSEditor{
+		updateRecordByIDFunc: func(domain, id string, change recordUpdate) error {
+			return fmt.Errorf("UpdateRecordByID should not be called")
+		},
+	}
+
+	action := undoAction{testDNSEditorFactory{editor, nil}, testInfoProviderFactory{infoProvider, nil}, newTestUndoLog(testUndoUpdate)}
+
+	// act
+	_, err := action.Execute(context.Background(), []string{})
+
+	// assert
+	if err == nil || err.Error() != fmt.Sprintf("Unable to undo change %s: The record has been changed since: expected \"5.6.7.8\" (TTL 600) but found \"9.9.9.9\" (TTL 600)", getLatestHistoryID(action.log)) {
+		t.Fail()
+		t.Logf("undoAction.Execute should refuse to revert a record which has been changed since (error: %v)", err)
+	}
+}
+
+// undoAction.Execute should recreate a deleted record with all fields.
+func Test_undoAction_Delete_RecordIsRecreated(t *testing.T) {
+	// arrange
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return nil, nil
+		},
+	}
+
+	var createdFields recordFields
+	editor := testDNSEditor{
+		createRecordFunc: func(domain string, fields recordFields) (string, error) {
+			createdFields = fields
+			return "4", nil
+		},
+	}
+
+	log := newTestUndoLog(testUndoDelete)
+	action := undoAction{testDNSEditorFactory{editor, nil}, testInfoProviderFactory{infoProvider, nil}, log}
+
+	// act
+	_, err := action.Execute(context.Background(), []string{getLatestHistoryID(log)})
+
+	// assert
+	expected := recordFields{Name: "", Content: "mail.example.com", RecordType: "MX", TTL: 3600, Priority: 10}
+	if err != nil || createdFields != expected {
+		t.Fail()
+		t.Logf("undoAction.Execute should recreate %v (created: %v, error: %v)", expected, createdFields, err)
+	}
+}
+
+// findUndoableChange should skip undos and changes which have already been undone.
+func Test_findUndoableChange_UndoneChangesAreSkipped(t *testing.T) {
+	// arrange
+	entries := []historyEntry{
+		{ID: "a", Operation: historyOperationUpdate, Outcome: historyOutcomeApplied},
+		{ID: "b", Operation: historyOperationUpdate, Outcome: historyOutcomeApplied},
+		{ID: "c", Operation: historyOperationUpdate, Outcome: historyOutcomeFailed},
+		{ID: "d", Operation: historyOperationUpdate, Outcome: historyOutcomeApplied, Undoes: "b"},
+	}
+
+	// act
+	change, err := findUndoableChange(entries, "")
+
+	// assert
+	if err != nil || change.ID != "a" {
+		t.Fail()
+		t.Logf("findUndoableChange should return change a (change: %v, error: %v)", change, err)
+	}
+
+	if _, err := findUndoableChange(entries, "b"); err == nil {
+		t.Fail()
+		t.Logf("findUndoableChange should not return a change which has already been undone")
+	}
+}
+
+// getLatestHistoryID returns the id of the most recent entry of the given history log.
+func getLatestHistoryID(log *historyLog) string {
+	entries, _ := log.Entries()
+	if len(entries) == 0 {
+		return ""
+	}
+
+	return entries[len(entries)-1].ID
+}
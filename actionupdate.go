@@ -6,11 +6,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"github.com/andreaskoch/dee-ns"
 	"net"
 	"os"
+	"strings"
 )
 
 var (
@@ -20,11 +21,28 @@ var (
 	updateDomain                 = updateAddressRecordArguments.String("domain", "", "Domain (e.g. example.com)")
 	updateSubdomain              = updateAddressRecordArguments.String("subdomain", "", "Subdomain (e.g. www)")
 	updateIP                     = updateAddressRecordArguments.String("ip", "", "IP address (e.g. ::1, 127.0.0.1)")
+	updateType                   = updateAddressRecordArguments.String("type", "", "The type of the record to update (e.g. A, MX, TXT); defaults to the type of the IP address or of the only record of the subdomain")
+	updateContent                = updateAddressRecordArguments.String("content", "", "The new content of the record (e.g. mail.example.com)")
+	updateTTL                    = &optionalInt{}
+	updatePriority               = &optionalInt{}
+	updateRename                 = updateAddressRecordArguments.String("rename", "", "The new subdomain name of the record")
+	updateID                     = updateAddressRecordArguments.String("id", "", "The id of the record (instead of -subdomain and -type)")
+	updateWait                   = updateAddressRecordArguments.Bool("wait", false, "Wait until the authoritative nameservers return the new value")
+	updateWaitTimeout            = updateAddressRecordArguments.Duration("wait-timeout", defaultWaitTimeout, "How long to wait for the new value")
+	updateResolvers              = &stringList{}
 )
 
+func init() {
+	updateAddressRecordArguments.Var(updateTTL, "ttl", "The new time to live in seconds")
+	updateAddressRecordArguments.Var(updatePriority, "priority", "The new priority of the record (e.g. for MX records)")
+	updateAddressRecordArguments.Var(updateResolvers, "resolver", "A resolver which must return the new value as well if -wait is given (e.g. 8.8.8.8); can be given multiple times")
+}
+
 type updateAction struct {
-	dnsEditorFactory dnsEditorCreator
-	stdin            *os.File
+	dnsEditorFactory    dnsEditorCreator
+	infoProviderFactory dnsInfoProviderCreator
+	stdin               *os.File
+	propagationWaiter   propagationWaiter
 }
 
 func (action updateAction) Name() string {
@@ -32,60 +50,282 @@ func (action updateAction) Name() string {
 }
 
 func (action updateAction) Description() string {
-	return "Update the address record for a given sub domain"
+	return "Update the address, TTL, priority or name of a record"
 }
 
 func (action updateAction) Usage() string {
 	buf := new(bytes.Buffer)
 	updateAddressRecordArguments.SetOutput(buf)
 	updateAddressRecordArguments.PrintDefaults()
-	return buf.String()
+	return fmt.Sprintf("  [name] [ip|content] [arguments ...]\n\n%s", buf.String())
 }
 
 // Execute updates the DNS record of the domain given from the supplied arguments.
 // If the update fails an error is returned.
-func (action updateAction) Execute(arguments []string) (message, error) {
+func (action updateAction) Execute(ctx context.Context, arguments []string) (message, error) {
 
 	// parse the arguments
-	*updateDomain = ""
+	*updateDomain = defaults.Domain
 	*updateSubdomain = ""
 	*updateIP = ""
-	if parseError := updateAddressRecordArguments.Parse(arguments); parseError != nil {
+	*updateType = ""
+	*updateContent = ""
+	*updateTTL = optionalInt{}
+	*updatePriority = optionalInt{}
+	*updateRename = ""
+	*updateID = ""
+	*updateWait = false
+	*updateWaitTimeout = defaultWaitTimeout
+	*updateResolvers = stringList{}
+	positionalArguments, parseError := parseArguments(updateAddressRecordArguments, arguments)
+	if parseError != nil {
 		return nil, parseError
 	}
 
+	// positional arguments: name and ip or content
+	value := ""
+	name, positionalError := getPositionalArguments(positionalArguments, &value)
+	if positionalError != nil {
+		return nil, positionalError
+	}
+
+	if value != "" {
+		if *updateIP != "" || *updateContent != "" {
+			return nil, fmt.Errorf("%q was given twice: as an argument and as a flag", value)
+		}
+
+		if net.ParseIP(value) != nil {
+			*updateIP = value
+		} else {
+			*updateContent = value
+		}
+	}
+
+	var resolveError error
+	*updateSubdomain, *updateDomain, resolveError = resolveName(ctx, action.infoProviderFactory, name, *updateSubdomain, *updateDomain)
+	if resolveError != nil {
+		return nil, resolveError
+	}
+
 	// domain
 	if *updateDomain == "" {
 		return nil, fmt.Errorf("No domain supplied")
 	}
 
 	// take ip from stdin
-	if *updateIP == "" && stdinHasData(action.stdin) {
+	if *updateIP == "" && *updateContent == "" && stdinHasData(action.stdin) {
 		ipAddressFromStdin := ""
 		fmt.Fscanf(os.Stdin, "%s", &ipAddressFromStdin)
-		updateIP = &ipAddressFromStdin
+		*updateIP = ipAddressFromStdin
+	}
+
+	// get the changed fields
+	change, changeError := getRecordChange(*updateIP, *updateContent, *updateTTL, *updatePriority, *updateRename)
+	if changeError != nil {
+		return nil, changeError
+	}
+
+	if change.Content == nil && change.TTL == nil && change.Priority == nil && change.Name == nil {
+		return nil, fmt.Errorf("No changes supplied. Please specify an -ip, -content, -ttl, -priority or -rename")
+	}
+
+	if *updateWait && (*updateID != "" || change.Content == nil) {
+		return nil, fmt.Errorf("The -wait option requires a new -ip or -content and cannot be combined with -id")
+	}
+
+	// update by record id
+	if *updateID != "" {
+		return action.updateByID(ctx, *updateDomain, *updateID, change)
+	}
+
+	// determine the record type
+	recordType := strings.ToUpper(*updateType)
+	if recordType == "" && change.Content != nil {
+		if ip := net.ParseIP(*change.Content); ip != nil {
+			recordType = getDNSRecordTypeByIP(ip)
+		}
+	}
+
+	if recordType == "" {
+		var typeError error
+		recordType, typeError = action.getRecordType(ctx, *updateDomain, *updateSubdomain)
+		if typeError != nil {
+			return nil, typeError
+		}
+	}
+
+	// create a DNS editor
+	var recordUpdater dnsRecordUpdater
+	recordUpdater, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	if dnsEditorError != nil {
+		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
+	}
+
+	// only the IP address is changed
+	if *updateIP != "" && change.TTL == nil && change.Priority == nil && change.Name == nil && *updateType == "" {
+		ip := net.ParseIP(*updateIP)
+
+		updateError := recordUpdater.UpdateSubdomain(ctx, *updateDomain, *updateSubdomain, ip)
+		if updateError != nil {
+			change := describeChange("update", *updateSubdomain, *updateDomain, recordType, ip.String())
+			return nil, getChangeError(ctx, change, updateError)
+		}
+
+		result := fmt.Sprintf("Updated: %s → %s", getFormattedDomainName(*updateSubdomain, *updateDomain), ip.String())
+		return action.waitForPropagation(ctx, result, *updateSubdomain, recordType, ip.String())
+	}
+
+	updateError := recordUpdater.UpdateSubdomainRecord(ctx, *updateDomain, *updateSubdomain, recordType, change)
+	if updateError != nil {
+		description := describeChange("update", *updateSubdomain, *updateDomain, recordType, formatRecordChange(change))
+		return nil, getChangeError(ctx, description, updateError)
+	}
+
+	subdomain := *updateSubdomain
+	if change.Name != nil {
+		subdomain = *change.Name
+	}
+
+	result := fmt.Sprintf("Updated: %s (%s) → %s", getFormattedDomainName(*updateSubdomain, *updateDomain), recordType, formatRecordChange(change))
+	if change.Content == nil {
+		return successMessage{result}, nil
+	}
+
+	return action.waitForPropagation(ctx, result, subdomain, recordType, *change.Content)
+}
+
+// getRecordType returns the type of the only record of the given subdomain.
+// Returns an error if the subdomain has no records or records of several types.
+func (action updateAction) getRecordType(ctx context.Context, domain, subdomain string) (string, error) {
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return "", fmt.Errorf("No DNS info provider available")
+	}
+
+	records, recordsError := infoProvider.GetSubdomainRecords(ctx, domain, subdomain)
+	if recordsError != nil {
+		return "", recordsError
+	}
+
+	var recordTypes []string
+	for _, record := range withoutProviderRecords(records) {
+		if !containsRecordType(recordTypes, record.RecordType) {
+			recordTypes = append(recordTypes, record.RecordType)
+		}
 	}
 
-	if *updateIP == "" {
-		return nil, fmt.Errorf("No IP address supplied")
+	switch len(recordTypes) {
+	case 0:
+		return "", fmt.Errorf("No record found for %q", getFormattedDomainName(subdomain, domain))
+
+	case 1:
+		return recordTypes[0], nil
+	}
+
+	return "", fmt.Errorf("%q has records of several types (%s). Please specify a -type", getFormattedDomainName(subdomain, domain), strings.Join(recordTypes, ", "))
+}
+
+// waitForPropagation waits until the nameservers return the new value if -wait is given.
+func (action updateAction) waitForPropagation(ctx context.Context, result, subdomain, recordType, value string) (message, error) {
+	if !*updateWait {
+		return successMessage{result}, nil
 	}
 
-	ip := net.ParseIP(*updateIP)
-	if ip == nil {
-		return nil, fmt.Errorf("Cannot parse IP %q", ip)
+	return waitForPropagation(ctx, action.propagationWaiter, result, propagationQuery{
+		Domain:     *updateDomain,
+		Name:       getFullyQualifiedName(subdomain, *updateDomain),
+		RecordType: recordType,
+		Value:      value,
+		Resolvers:  *updateResolvers,
+		Timeout:    *updateWaitTimeout,
+	})
+}
+
+// updateByID applies the given change to the record with the given id.
+func (action updateAction) updateByID(ctx context.Context, domain, id string, change recordUpdate) (message, error) {
+	if *updateSubdomain != "" || *updateType != "" {
+		return nil, fmt.Errorf("The -id option cannot be combined with -subdomain or -type")
 	}
 
 	// create a DNS editor
-	var addressRecordUpdater deens.DNSRecordUpdater
-	addressRecordUpdater, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
+	var recordUpdater dnsRecordUpdater
+	recordUpdater, dnsEditorError := action.dnsEditorFactory.CreateDNSEditor()
 	if dnsEditorError != nil {
 		return nil, fmt.Errorf("Cannot create DNS editor: %s", dnsEditorError.Error())
 	}
 
-	updateError := addressRecordUpdater.UpdateSubdomain(*updateDomain, *updateSubdomain, ip)
+	updateError := recordUpdater.UpdateRecordByID(ctx, domain, id, change)
 	if updateError != nil {
-		return nil, fmt.Errorf("%s", updateError.Error())
+		description := fmt.Sprintf("update of record %s of %s (%s)", id, domain, formatRecordChange(change))
+		return nil, getChangeError(ctx, description, updateError)
+	}
+
+	return successMessage{fmt.Sprintf("Updated: record %s of %s → %s", id, domain, formatRecordChange(change))}, nil
+}
+
+// getRecordChange returns the record fields which were set on the command line.
+func getRecordChange(ip, content string, ttl, priority optionalInt, rename string) (recordUpdate, error) {
+	change := recordUpdate{}
+
+	if ip != "" && content != "" {
+		return change, fmt.Errorf("The -ip and -content options cannot be combined")
+	}
+
+	if ip != "" {
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			return change, fmt.Errorf("Cannot parse IP %q", ip)
+		}
+
+		ipAddress := parsedIP.String()
+		change.Content = &ipAddress
+	}
+
+	if content != "" {
+		change.Content = &content
+	}
+
+	if ttl.IsSet {
+		if ttl.Value < 0 {
+			return change, fmt.Errorf("The given TTL cannot be negative")
+		}
+
+		change.TTL = &ttl.Value
+	}
+
+	if priority.IsSet {
+		if priority.Value < 0 {
+			return change, fmt.Errorf("The given priority cannot be negative")
+		}
+
+		change.Priority = &priority.Value
+	}
+
+	if rename != "" {
+		change.Name = &rename
+	}
+
+	return change, nil
+}
+
+// formatRecordChange returns a summary of the given change (e.g. "content 1.2.3.4, ttl 60").
+func formatRecordChange(change recordUpdate) string {
+	var fields []string
+	if change.Name != nil {
+		fields = append(fields, fmt.Sprintf("name %s", *change.Name))
+	}
+
+	if change.Content != nil {
+		fields = append(fields, fmt.Sprintf("content %s", *change.Content))
+	}
+
+	if change.TTL != nil {
+		fields = append(fields, fmt.Sprintf("ttl %d", *change.TTL))
+	}
+
+	if change.Priority != nil {
+		fields = append(fields, fmt.Sprintf("priority %d", *change.Priority))
 	}
 
-	return successMessage{fmt.Sprintf("Updated: %s → %s", getFormattedDomainName(*updateSubdomain, *updateDomain), ip.String())}, nil
+	return strings.Join(fields, ", ")
 }
@@ -5,7 +5,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/pearkes/dnsimple"
 	"net"
 	"strings"
 	"testing"
@@ -142,12 +144,12 @@ func Test_updateAction_InvalidArguments_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := updateAction.Execute(arguments)
+		_, err := updateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -187,12 +189,12 @@ func Test_updateAction_ValidArgumentsButEmpty_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := updateAction.Execute(arguments)
+		_, err := updateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -220,7 +222,7 @@ func Test_updateAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	for _, invalidIP := range invalidIPs {
 
@@ -233,7 +235,7 @@ func Test_updateAction_IPAddressIsInvalid_ErrorIsReturned(t *testing.T) {
 			"-ip",
 			invalidIP,
 		}
-		_, err := updateAction.Execute(arguments)
+		_, err := updateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err == nil {
@@ -302,12 +304,12 @@ func Test_updateAction_ValidArguments_NoErrorIsReturned(t *testing.T) {
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	for _, arguments := range validArgumentsSet {
 
 		// act
-		_, err := updateAction.Execute(arguments)
+		_, err := updateAction.Execute(context.Background(), arguments)
 
 		// assert
 		if err != nil {
@@ -337,10 +339,10 @@ func Test_updateAction_ValidArguments_DNSUpdaterRespondsWithError_ErrorIsReturne
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	// act
-	_, err := updateAction.Execute(arguments)
+	_, err := updateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -369,10 +371,10 @@ func Test_updateAction_ValidArguments_DNSUpdaterSucceeds_SuccessMessageIsReturne
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	// act
-	response, _ := updateAction.Execute(arguments)
+	response, _ := updateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if response == nil {
@@ -394,10 +396,10 @@ func Test_updateAction_ValidArguments_DNSEditorCreationFails_ErrorIsReturned(t *
 	}
 
 	editorFactory := testDNSEditorFactory{nil, fmt.Errorf("Unable to create DNS Editor")}
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	// act
-	_, err := updateAction.Execute(arguments)
+	_, err := updateAction.Execute(context.Background(), arguments)
 
 	// assert
 	if err == nil {
@@ -426,10 +428,10 @@ func Test_updateAction_ValidArguments_DNSUpdaterSucceeds_SuccessMessageContainsT
 
 	editorFactory := testDNSEditorFactory{dnsUpdater, nil}
 
-	updateAction := updateAction{editorFactory, nil}
+	updateAction := updateAction{editorFactory, nil, nil, nil}
 
 	// act
-	response, _ := updateAction.Execute(arguments)
+	response, _ := updateAction.Execute(context.Background(), arguments)
 
 	// assert
 	containsIP := strings.Contains(response.Text(), "2001:db8:0:42:0:8a2e:370:7334")
@@ -441,3 +443,197 @@ func Test_updateAction_ValidArguments_DNSUpdaterSucceeds_SuccessMessageContainsT
 		t.Logf("updateAction.Execute(%q) should respond with a success message that contains the domain, subdomain and ip but responded with %q instead.", arguments, response.Text())
 	}
 }
+
+// updateAction.Execute should only change the TTL if no IP address is given.
+func Test_updateAction_OnlyTTLGiven_TTLIsChanged(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-type", "A", "-ttl", "60"}
+
+	var recordType string
+	var change recordUpdate
+	dnsUpdater := &testDNSEditor{
+		updateSubdomainRecordFunc: func(domain, subdomain, t string, c recordUpdate) error {
+			recordType = t
+			change = c
+			return nil
+		},
+	}
+
+	updateAction := updateAction{testDNSEditorFactory{dnsUpdater, nil}, nil, nil, nil}
+
+	// act
+	_, err := updateAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || recordType != "A" || change.TTL == nil || *change.TTL != 60 || change.Content != nil || change.Name != nil || change.Priority != nil {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should only change the TTL of the A record (error: %v, type: %q, change: %+v)", arguments, err, recordType, change)
+	}
+}
+
+// updateAction.Execute should pass all given fields to the DNS updater.
+func Test_updateAction_AllFieldsGiven_AllFieldsAreChanged(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-type", "mx", "-content", "mx.example.com", "-ttl", "0", "-priority", "20", "-rename", "mail"}
+
+	var recordType string
+	var change recordUpdate
+	dnsUpdater := &testDNSEditor{
+		updateSubdomainRecordFunc: func(domain, subdomain, t string, c recordUpdate) error {
+			recordType = t
+			change = c
+			return nil
+		},
+	}
+
+	updateAction := updateAction{testDNSEditorFactory{dnsUpdater, nil}, nil, nil, nil}
+
+	// act
+	result, err := updateAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || recordType != "MX" || *change.Content != "mx.example.com" || *change.TTL != 0 || *change.Priority != 20 || *change.Name != "mail" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should change all given fields of the MX record (error: %v, type: %q)", arguments, err, recordType)
+		return
+	}
+
+	if result.Text() != "Updated: example.com (MX) → name mail, content mx.example.com, ttl 0, priority 20" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) returned an unexpected message: %q", arguments, result.Text())
+	}
+}
+
+// updateAction.Execute should return an error if no record type is given
+// and the subdomain has records of several types.
+func Test_updateAction_ContentWithoutType_SeveralRecordTypes_ErrorIsReturned(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-content", "example.org"}
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{
+				{Name: "www", RecordType: "A", Content: "1.2.3.4"},
+				{Name: "www", RecordType: "TXT", Content: "hello"},
+			}, nil
+		},
+	}
+
+	updateAction := updateAction{testDNSEditorFactory{&testDNSEditor{}, nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+
+	// act
+	_, err := updateAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should return an error because no record type was given", arguments)
+	}
+}
+
+// updateAction.Execute should update the only record of the subdomain if no record type is given.
+func Test_updateAction_TTLWithoutType_SingleRecord_RecordIsUpdated(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-ttl", "300"}
+	infoProvider := testDNSInfoProvider{
+		getSubdomainRecordsFunc: func(domain, subdomain string) ([]dnsimple.Record, error) {
+			return []dnsimple.Record{{Name: "www", RecordType: "CNAME", Content: "example.com"}}, nil
+		},
+	}
+
+	var recordType string
+	dnsUpdater := &testDNSEditor{
+		updateSubdomainRecordFunc: func(domain, subdomain, t string, c recordUpdate) error {
+			recordType = t
+			return nil
+		},
+	}
+
+	updateAction := updateAction{testDNSEditorFactory{dnsUpdater, nil}, testInfoProviderFactory{infoProvider, nil}, nil, nil}
+
+	// act
+	_, err := updateAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || recordType != "CNAME" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should have updated the CNAME record (error: %v, type: %q)", arguments, err, recordType)
+	}
+}
+
+// DNSEditor.UpdateSubdomainRecord should update the TTL even if the IP address did not change.
+func Test_DNSEditor_UpdateSubdomainRecord_OnlyTTLChanged_RecordIsUpdated(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	content := "1.2.3.4"
+	ttl := 60
+
+	// act
+	err := editor.UpdateSubdomainRecord(context.Background(), "example.com", "www", "A", recordUpdate{Content: &content, TTL: &ttl})
+
+	// assert
+	if err != nil || client.calls["UpdateRecord"] != 1 {
+		t.Fail()
+		t.Logf("UpdateSubdomainRecord should update the TTL (error: %v, updates: %d)", err, client.calls["UpdateRecord"])
+	}
+}
+
+// updateAction.Execute should update the record with the given id.
+func Test_updateAction_IDGiven_RecordIsUpdatedByID(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-id", "12", "-ip", "10.0.2.2"}
+
+	updatedID := ""
+	var change recordUpdate
+	editor := &testDNSEditor{
+		updateRecordByIDFunc: func(domain, id string, c recordUpdate) error {
+			updatedID = id
+			change = c
+			return nil
+		},
+	}
+
+	updateAction := updateAction{testDNSEditorFactory{editor, nil}, nil, nil, nil}
+
+	// act
+	_, err := updateAction.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || updatedID != "12" || change.Content == nil || *change.Content != "10.0.2.2" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should update the content of record 12 (error: %v, updated: %q)", arguments, err, updatedID)
+	}
+}
+
+// DNSEditor.UpdateRecordByID should update the record with the given id even if other records have the same name.
+func Test_DNSEditor_UpdateRecordByID_DuplicateNames_GivenRecordIsUpdated(t *testing.T) {
+	// arrange
+	zone := []dnsimple.Record{
+		{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4"},
+		{Id: 2, Name: "www", RecordType: "A", Content: "1.2.3.5"},
+	}
+
+	client := &testRecordingDNSClient{testDNSClient: newTestDNSClient(zone)}
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	content := "1.2.3.6"
+
+	// act
+	err := editor.UpdateRecordByID(context.Background(), "example.com", "2", recordUpdate{Content: &content})
+
+	// assert
+	if err != nil || client.updatedID != "2" {
+		t.Fail()
+		t.Logf("UpdateRecordByID should update record 2 (error: %v, updated: %q)", err, client.updatedID)
+	}
+}
+
+// testRecordingDNSClient is a testDNSClient which records the id of the last updated record.
+type testRecordingDNSClient struct {
+	*testDNSClient
+	updatedID string
+}
+
+func (client *testRecordingDNSClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	client.updatedID = id
+	return client.testDNSClient.UpdateRecord(ctx, domain, id, opts)
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	actionNameVerify = "verify"
+
+	verifyArguments = flag.NewFlagSet(actionNameVerify, flag.ContinueOnError)
+	verifyDomain    = verifyArguments.String("domain", "", "Domain (e.g. example.com)")
+	verifySubdomain = verifyArguments.String("subdomain", "", "Only verify the records of the given subdomain (e.g. www)")
+)
+
+// dnsQueryTimeout defines how long to wait for the answer of a nameserver.
+const dnsQueryTimeout = 2 * time.Second
+
+type verifyAction struct {
+	infoProviderFactory dnsInfoProviderCreator
+	lookupNameservers   func(ctx context.Context, domain string) ([]nameserver, error)
+}
+
+func (action verifyAction) Name() string {
+	return actionNameVerify
+}
+
+func (action verifyAction) Description() string {
+	return "Compare the records with the answers of the authoritative nameservers"
+}
+
+func (action verifyAction) Usage() string {
+	buf := new(bytes.Buffer)
+	verifyArguments.SetOutput(buf)
+	verifyArguments.PrintDefaults()
+	return buf.String()
+}
+
+// recordSet contains the contents of all records with the same name and type.
+type recordSet struct {
+	Name       string
+	RecordType string
+	Values     []string
+}
+
+// Execute queries every record of the given domain on all authoritative
+// nameservers and returns an error if any nameserver returns missing,
+// stale or extra values.
+func (action verifyAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	// parse the arguments
+	*verifyDomain = defaults.Domain
+	*verifySubdomain = ""
+	if parseError := verifyArguments.Parse(arguments); parseError != nil {
+		return nil, parseError
+	}
+
+	var nameError error
+	*verifySubdomain, *verifyDomain, nameError = resolveName(ctx, action.infoProviderFactory, "", *verifySubdomain, *verifyDomain)
+	if nameError != nil {
+		return nil, nameError
+	}
+
+	if *verifyDomain == "" {
+		return nil, fmt.Errorf("No domain supplied")
+	}
+
+	// get the records
+	infoProvider, infoProviderError := action.infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return nil, fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	records, recordsError := infoProvider.GetDomainRecords(ctx, *verifyDomain)
+	if recordsError != nil {
+		return nil, fmt.Errorf("Unable to fetch DNS records for domain %s: %s", *verifyDomain, recordsError.Error())
+	}
+
+	recordSets := getVerifiableRecordSets(records, *verifySubdomain)
+	if len(recordSets) == 0 {
+		return nil, fmt.Errorf("No verifiable records found for %s", getFormattedDomainName(*verifySubdomain, *verifyDomain))
+	}
+
+	// get the nameservers
+	servers, lookupError := action.lookupNameservers(ctx, *verifyDomain)
+	if lookupError != nil {
+		return nil, fmt.Errorf("Unable to determine the nameservers of %s: %s", *verifyDomain, lookupError.Error())
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("No nameservers found for %s", *verifyDomain)
+	}
+
+	// compare the records with the answers of all nameservers
+	var drifts []string
+	for _, set := range recordSets {
+		name := getFullyQualifiedName(set.Name, *verifyDomain)
+		for _, server := range servers {
+			if ctxError := ctx.Err(); ctxError != nil {
+				return nil, ctxError
+			}
+
+			values, queryError := queryDNSServer(ctx, server, name, set.RecordType, dnsQueryTimeout)
+			if drift := getRecordSetDrift(set, values, queryError); drift != "" {
+				drifts = append(drifts, fmt.Sprintf("%s (%s) on %s: %s", toDisplayName(name), set.RecordType, server.Name, drift))
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("Verified %d record set(s) of %s on %d nameserver(s)", len(recordSets), toDisplayName(*verifyDomain), len(servers))
+	if len(drifts) > 0 {
+		return nil, fmt.Errorf("%s: %d difference(s) found\n%s", summary, len(drifts), strings.Join(drifts, "\n"))
+	}
+
+	return successMessage{summary + ": no differences found"}, nil
+}
+
+// getVerifiableRecordSets groups the given records by name and type. Records of
+// types which cannot be queried (e.g. SOA or the DNSimple specific ALIAS records)
+// are skipped. If a subdomain is given only its records are returned.
+func getVerifiableRecordSets(records []dnsimple.Record, subdomain string) []recordSet {
+	setsByKey := make(map[string]*recordSet)
+	var keys []string
+	for _, record := range records {
+		if _, isSupported := dnsQuestionTypes[record.RecordType]; !isSupported {
+			continue
+		}
+
+		if subdomain != "" && !strings.EqualFold(record.Name, subdomain) {
+			continue
+		}
+
+		name := strings.ToLower(record.Name)
+		key := name + " " + record.RecordType
+		set, exists := setsByKey[key]
+		if !exists {
+			set = &recordSet{Name: name, RecordType: record.RecordType}
+			setsByKey[key] = set
+			keys = append(keys, key)
+		}
+
+		set.Values = append(set.Values, normalizeRecordValue(record.RecordType, record.Content))
+	}
+
+	sort.Strings(keys)
+
+	var sets []recordSet
+	for _, key := range keys {
+		sets = append(sets, *setsByKey[key])
+	}
+
+	return sets
+}
+
+// getRecordSetDrift compares the expected values of the given record set with
+// the values returned by a nameserver. Returns an empty string if they match.
+func getRecordSetDrift(set recordSet, values []string, queryError error) string {
+	if queryError != nil {
+		return fmt.Sprintf("no answer (%s)", queryError.Error())
+	}
+
+	missing := getMissingValues(set.Values, values)
+	extra := getMissingValues(values, set.Values)
+
+	switch {
+	case len(missing) == 0 && len(extra) == 0:
+		return ""
+
+	case len(extra) == 0:
+		return fmt.Sprintf("missing %s", strings.Join(missing, ", "))
+
+	case len(missing) == 0:
+		return fmt.Sprintf("extra %s", strings.Join(extra, ", "))
+	}
+
+	return fmt.Sprintf("stale (expected %s, got %s)", strings.Join(missing, ", "), strings.Join(extra, ", "))
+}
+
+// getMissingValues returns the values of the first list
+// which are not contained in the second list.
+func getMissingValues(values, otherValues []string) []string {
+	var missing []string
+	for _, value := range values {
+		found := false
+		for _, otherValue := range otherValues {
+			if value == otherValue {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, value)
+		}
+	}
+
+	return missing
+}
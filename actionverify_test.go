@@ -0,0 +1,114 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"strings"
+	"testing"
+)
+
+var testVerifyRecords = []dnsimple.Record{
+	{Id: 1, Name: "www", RecordType: "A", Content: "5.6.7.8"},
+	{Id: 2, Name: "", RecordType: "SOA", Content: "ns1.dnsimple.com admin.dnsimple.com 1 86400 7200 604800 300"},
+}
+
+// verifyAction.Execute should succeed if all nameservers return the records.
+func Test_verifyAction_NoDrift_SuccessMessageIsReturned(t *testing.T) {
+	// arrange
+	first := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 0)
+	defer first.Close()
+
+	second := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 0)
+	defer second.Close()
+
+	arguments := []string{"-domain", "example.com"}
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testVerifyRecords, nil
+		},
+	}
+
+	lookupNameservers := func(ctx context.Context, domain string) ([]nameserver, error) {
+		return []nameserver{
+			{Name: "ns1.example.net", Address: first.connection.LocalAddr().String()},
+			{Name: "ns2.example.net", Address: second.connection.LocalAddr().String()},
+		}, nil
+	}
+
+	action := verifyAction{testInfoProviderFactory{infoProvider, nil}, lookupNameservers}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || !strings.Contains(result.Text(), "1 record set(s) of example.com on 2 nameserver(s)") {
+		t.Fail()
+		t.Logf("verifyAction.Execute(%q) should not find differences (result: %v, error: %v)", arguments, result, err)
+	}
+}
+
+// verifyAction.Execute should return an error which names the nameserver with stale records.
+func Test_verifyAction_StaleNameserver_ErrorIsReturned(t *testing.T) {
+	// arrange
+	first := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 0)
+	defer first.Close()
+
+	second := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 1000)
+	defer second.Close()
+
+	arguments := []string{"-domain", "example.com"}
+	infoProvider := testDNSInfoProvider{
+		getDomainRecordsFunc: func(domain string) ([]dnsimple.Record, error) {
+			return testVerifyRecords, nil
+		},
+	}
+
+	lookupNameservers := func(ctx context.Context, domain string) ([]nameserver, error) {
+		return []nameserver{
+			{Name: "ns1.example.net", Address: first.connection.LocalAddr().String()},
+			{Name: "ns2.example.net", Address: second.connection.LocalAddr().String()},
+		}, nil
+	}
+
+	action := verifyAction{testInfoProviderFactory{infoProvider, nil}, lookupNameservers}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	expected := "www.example.com (A) on ns2.example.net: stale (expected 5.6.7.8, got 1.2.3.4)"
+	if err == nil || !strings.Contains(err.Error(), expected) || strings.Contains(err.Error(), "ns1.example.net") {
+		t.Fail()
+		t.Logf("verifyAction.Execute(%q) should return an error containing %q (error: %v)", arguments, expected, err)
+	}
+}
+
+// getRecordSetDrift should flag missing, extra and stale values.
+func Test_getRecordSetDrift(t *testing.T) {
+	// arrange
+	set := recordSet{Name: "www", RecordType: "A", Values: []string{"10.0.0.1", "10.0.0.2"}}
+	inputs := []struct {
+		values   []string
+		expected string
+	}{
+		{[]string{"10.0.0.2", "10.0.0.1"}, ""},
+		{[]string{"10.0.0.1"}, "missing 10.0.0.2"},
+		{[]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, "extra 10.0.0.3"},
+		{[]string{"10.0.0.1", "10.0.0.3"}, "stale (expected 10.0.0.2, got 10.0.0.3)"},
+	}
+
+	for _, input := range inputs {
+		// act
+		result := getRecordSetDrift(set, input.values, nil)
+
+		// assert
+		if result != input.expected {
+			t.Fail()
+			t.Logf("getRecordSetDrift(%q) should return %q but returned %q", input.values, input.expected, result)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	actionNameWhoami = "whoami"
+)
+
+type whoamiAction struct {
+	credentialStore            deens.CredentialStore
+	accountInfoProviderFactory accountInfoProviderCreator
+}
+
+func (action whoamiAction) Name() string {
+	return actionNameWhoami
+}
+
+func (action whoamiAction) Description() string {
+	return "Show the DNSimple account of the stored API credentials"
+}
+
+func (action whoamiAction) Usage() string {
+	return "  <no options required>\n"
+}
+
+// Execute prints the account, plan, domain count and the
+// remaining rate limit of the stored credentials.
+func (action whoamiAction) Execute(ctx context.Context, arguments []string) (message, error) {
+
+	if action.credentialStore == nil {
+		return nil, fmt.Errorf("No credential store present")
+	}
+
+	if action.accountInfoProviderFactory == nil {
+		return nil, fmt.Errorf("No account info provider factory available")
+	}
+
+	credentials, credentialError := action.credentialStore.GetCredentials()
+	if credentialError != nil {
+		return nil, fmt.Errorf("Not logged in: %s", credentialError.Error())
+	}
+
+	accountInfoProvider, providerError := action.accountInfoProviderFactory.CreateAccountInfoProvider(credentials)
+	if providerError != nil {
+		return nil, providerError
+	}
+
+	info, infoError := accountInfoProvider.GetAccountInfo(ctx)
+	if infoError != nil {
+		return nil, fmt.Errorf("Unable to fetch the account information for %q: %s", credentials.Email, infoError.Error())
+	}
+
+	return successMessage{formatAccountInfo(info)}, nil
+}
+
+// formatAccountInfo formats the given account information as a table.
+func formatAccountInfo(info accountInfo) string {
+	buf := new(bytes.Buffer)
+
+	w := new(tabwriter.Writer)
+	minWidth := 0
+	tabWidth := 8
+	padding := 3
+	w.Init(buf, minWidth, tabWidth, padding, ' ', 0)
+
+	plan := info.Plan
+	if isEmpty(plan) {
+		plan = "unknown"
+	}
+
+	rateLimit := "unknown"
+	if info.RateLimit.IsKnown() {
+		rateLimit = fmt.Sprintf("%d of %d requests remaining", info.RateLimit.Remaining, info.RateLimit.Limit)
+
+		if !info.RateLimit.Reset.IsZero() {
+			rateLimit += fmt.Sprintf(" (resets at %s)", info.RateLimit.Reset.Format(time.RFC3339))
+		}
+	}
+
+	fmt.Fprintf(w, "Account:\t%s\n", info.Email)
+	fmt.Fprintf(w, "Plan:\t%s\n", plan)
+	fmt.Fprintf(w, "Domains:\t%d\n", info.DomainCount)
+	fmt.Fprintf(w, "Rate limit:\t%s", rateLimit)
+
+	w.Flush()
+
+	return buf.String()
+}
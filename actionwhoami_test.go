@@ -0,0 +1,90 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_whoamiAction_Name_WhoamiIsReturned(t *testing.T) {
+	// arrange
+	whoamiAction := whoamiAction{}
+
+	// act
+	result := whoamiAction.Name()
+
+	// assert
+	if result != "whoami" {
+		t.Fail()
+		t.Logf("whoamiAction.Name() should have returned %q but returned %q instead.", "whoami", result)
+	}
+}
+
+func Test_whoamiAction_Description_ResultIsNotEmpty(t *testing.T) {
+	// arrange
+	whoamiAction := whoamiAction{}
+
+	// act
+	result := whoamiAction.Description()
+
+	// assert
+	if isEmpty(result) {
+		t.Fail()
+		t.Logf("whoamiAction.Description() not be empty.")
+	}
+}
+
+func Test_whoamiAction_NoCredentials_ErrorIsReturned(t *testing.T) {
+	// arrange
+	credentialStore := testCredentialsStore{getFunc: func() (deens.APICredentials, error) {
+		return deens.APICredentials{}, fmt.Errorf("No credentials")
+	}}
+	whoamiAction := whoamiAction{credentialStore, validAccountInfoProviderFactory()}
+
+	// act
+	_, err := whoamiAction.Execute(context.Background(), []string{})
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "Not logged in") {
+		t.Fail()
+		t.Logf("whoamiAction.Execute() should return an error if there are no credentials.")
+	}
+}
+
+func Test_whoamiAction_AccountInfoIsPrinted(t *testing.T) {
+	// arrange
+	credentialStore := testCredentialsStore{getFunc: func() (deens.APICredentials, error) {
+		return deens.APICredentials{Email: "user@example.com", Token: "1234"}, nil
+	}}
+	accountInfoProviderFactory := testAccountInfoProviderFactory{func(credentials deens.APICredentials) (accountInfo, error) {
+		return accountInfo{
+			Email:       credentials.Email,
+			Plan:        "Silver",
+			DomainCount: 7,
+			RateLimit:   rateLimit{3600, 3599, time.Unix(1454972400, 0)},
+		}, nil
+	}}
+	whoamiAction := whoamiAction{credentialStore, accountInfoProviderFactory}
+
+	// act
+	result, err := whoamiAction.Execute(context.Background(), []string{})
+
+	// assert
+	if err != nil {
+		t.Fatalf("whoamiAction.Execute() returned an error: %s", err.Error())
+	}
+
+	for _, expected := range []string{"user@example.com", "Silver", "7", "3599 of 3600"} {
+		if !strings.Contains(result.Text(), expected) {
+			t.Fail()
+			t.Logf("whoamiAction.Execute() should print %q but printed %q", expected, result.Text())
+		}
+	}
+}
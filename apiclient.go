@@ -0,0 +1,154 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultAPIURL defines the default DNSimple API endpoint.
+const defaultAPIURL = "https://api.dnsimple.com/v1"
+
+// apiSettings contains the settings for connecting to the DNSimple API.
+type apiSettings struct {
+	// URL is the base URL of the API (e.g. "https://api.sandbox.dnsimple.com/v1").
+	URL string
+
+	// CAFile is the path of a PEM encoded CA bundle which is
+	// trusted in addition to the system certificate pool.
+	CAFile string
+
+	// ClientCertFile is the path of a PEM encoded client certificate.
+	ClientCertFile string
+
+	// ClientKeyFile is the path of the PEM encoded private key of the
+	// client certificate. If empty the key is read from ClientCertFile.
+	ClientKeyFile string
+
+	// Retry defines how failed requests are retried.
+	Retry retryPolicy
+
+	// Cache defines how API responses are cached.
+	Cache cacheSettings
+}
+
+// newDNSimpleClient creates a DNSimple API client for the given credentials and API settings.
+func newDNSimpleClient(credentials deens.APICredentials, settings apiSettings) (*dnsimple.Client, error) {
+	client, clientError := dnsimple.NewClient(credentials.Email, credentials.Token)
+	if clientError != nil {
+		return nil, fmt.Errorf("Unable to create DNSimple client. Error: %s", clientError.Error())
+	}
+
+	apiURL, urlError := getAPIURL(settings.URL)
+	if urlError != nil {
+		return nil, urlError
+	}
+
+	httpClient, httpClientError := newHTTPClient(settings)
+	if httpClientError != nil {
+		return nil, httpClientError
+	}
+
+	client.URL = apiURL
+	client.Http = httpClient
+
+	return client, nil
+}
+
+// getAPIURL validates the given API URL and returns it without trailing slashes.
+// Returns the default API URL if the given URL is empty.
+func getAPIURL(apiURL string) (string, error) {
+	if isEmpty(apiURL) {
+		return defaultAPIURL, nil
+	}
+
+	parsedURL, parseError := url.Parse(strings.TrimSpace(apiURL))
+	if parseError != nil {
+		return "", fmt.Errorf("The API URL %q is invalid: %s", apiURL, parseError.Error())
+	}
+
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", fmt.Errorf("The API URL %q is invalid: an absolute http or https URL is required", apiURL)
+	}
+
+	return strings.TrimRight(parsedURL.String(), "/"), nil
+}
+
+// newHTTPClient creates a HTTP client which uses the CA bundle, client
+// certificate, retry policy and cache from the given settings.
+func newHTTPClient(settings apiSettings) (*http.Client, error) {
+	transport := cleanhttp.DefaultTransport()
+
+	tlsConfig, tlsError := newTLSConfig(settings)
+	if tlsError != nil {
+		return nil, tlsError
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	var roundTripper http.RoundTripper = newRetryTransport(transport, settings.Retry)
+	if !isEmpty(settings.Cache.Folder) {
+		apiURL, _ := getAPIURL(settings.URL)
+		roundTripper = newCacheTransport(afero.NewOsFs(), roundTripper, apiURL, settings.Cache)
+	}
+
+	return &http.Client{
+		Transport: roundTripper,
+	}, nil
+}
+
+// newTLSConfig creates a TLS configuration from the given settings.
+func newTLSConfig(settings apiSettings) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	// CA bundle
+	if !isEmpty(settings.CAFile) {
+		pem, readError := ioutil.ReadFile(settings.CAFile)
+		if readError != nil {
+			return nil, fmt.Errorf("Unable to read CA bundle: %s", readError.Error())
+		}
+
+		rootCAs, poolError := x509.SystemCertPool()
+		if poolError != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("The CA bundle %q does not contain any PEM encoded certificates", settings.CAFile)
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	// client certificate
+	if !isEmpty(settings.ClientCertFile) {
+		keyFile := settings.ClientKeyFile
+		if isEmpty(keyFile) {
+			keyFile = settings.ClientCertFile
+		}
+
+		certificate, certificateError := tls.LoadX509KeyPair(settings.ClientCertFile, keyFile)
+		if certificateError != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %s", certificateError.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	} else if !isEmpty(settings.ClientKeyFile) {
+		return nil, fmt.Errorf("A client key was given without a client certificate")
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/andreaskoch/dee-ns"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_getAPIURL(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		apiURL         string
+		expectedResult string
+		expectError    bool
+	}{
+		{"", defaultAPIURL, false},
+		{"  ", defaultAPIURL, false},
+		{"https://api.sandbox.dnsimple.com/v1", "https://api.sandbox.dnsimple.com/v1", false},
+		{"https://api.sandbox.dnsimple.com/v1/", "https://api.sandbox.dnsimple.com/v1", false},
+		{"http://localhost:8080", "http://localhost:8080", false},
+		{"api.dnsimple.com/v1", "", true},
+		{"ftp://api.dnsimple.com/v1", "", true},
+		{"https://", "", true},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result, err := getAPIURL(input.apiURL)
+
+		// assert
+		if (err != nil) != input.expectError || result != input.expectedResult {
+			t.Fail()
+			t.Logf("getAPIURL(%q) returned (%q, %v) but should have returned %q (error expected: %t).", input.apiURL, result, err, input.expectedResult, input.expectError)
+		}
+	}
+}
+
+func Test_newTLSConfig_CAFileDoesNotExist_ErrorIsReturned(t *testing.T) {
+	// act
+	_, err := newTLSConfig(apiSettings{CAFile: "/non/existing/ca.pem"})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("newTLSConfig should return an error if the CA file does not exist.")
+	}
+}
+
+func Test_newTLSConfig_CAFileContainsNoCertificates_ErrorIsReturned(t *testing.T) {
+	// arrange
+	caFile := getTempFileWithContent(t, "not a certificate")
+	defer os.Remove(caFile.Name())
+	defer caFile.Close()
+
+	// act
+	_, err := newTLSConfig(apiSettings{CAFile: caFile.Name()})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("newTLSConfig should return an error if the CA file does not contain any certificates.")
+	}
+}
+
+func Test_newTLSConfig_ClientKeyWithoutCertificate_ErrorIsReturned(t *testing.T) {
+	// act
+	_, err := newTLSConfig(apiSettings{ClientKeyFile: "/some/key.pem"})
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("newTLSConfig should return an error if a client key but no certificate is given.")
+	}
+}
+
+// The API client should trust servers whose certificate is signed by the given CA bundle.
+func Test_newDNSimpleClient_CustomCAFile_ServerCertificateIsTrusted(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[]`)
+	}))
+	defer server.Close()
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	caFile := getTempFileWithContent(t, string(certificate))
+	defer os.Remove(caFile.Name())
+	defer caFile.Close()
+
+	credentials := deens.APICredentials{Email: "user@example.com", Token: "1234"}
+
+	// act
+	client, clientError := newDNSimpleClient(credentials, apiSettings{URL: server.URL, CAFile: caFile.Name()})
+	if clientError != nil {
+		t.Fatalf("newDNSimpleClient returned an error: %s", clientError.Error())
+	}
+
+	_, err := client.GetDomains()
+
+	// assert
+	if err != nil {
+		t.Fail()
+		t.Logf("The client should trust the server certificate from the CA file: %s", err.Error())
+	}
+}
+
+// Without the CA bundle the certificate of the test server is not trusted.
+func Test_newDNSimpleClient_NoCAFile_UnknownServerCertificateIsRejected(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[]`)
+	}))
+	defer server.Close()
+
+	credentials := deens.APICredentials{Email: "user@example.com", Token: "1234"}
+
+	// act
+	client, clientError := newDNSimpleClient(credentials, apiSettings{URL: server.URL})
+	if clientError != nil {
+		t.Fatalf("newDNSimpleClient returned an error: %s", clientError.Error())
+	}
+
+	_, err := client.GetDomains()
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("The client should not trust an unknown server certificate.")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// describeChange returns a human readable description of
+// a change to the record of the given type (e.g. "update of www.example.com (A → 1.2.3.4)").
+func describeChange(change, subdomain, domain, recordType, value string) string {
+	if value == "" {
+		return fmt.Sprintf("%s of %s (%s)", change, getFormattedDomainName(subdomain, domain), recordType)
+	}
+
+	return fmt.Sprintf("%s of %s (%s → %s)", change, getFormattedDomainName(subdomain, domain), recordType, value)
+}
+
+// getChangeError returns an error which states whether the described change
+// was applied if the given context was cancelled or timed out while the
+// change was being made. Other errors are returned unchanged.
+func getChangeError(ctx context.Context, change string, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+
+	reason := "Interrupted"
+	if ctx.Err() == context.DeadlineExceeded {
+		reason = "Timed out"
+	}
+
+	var unconfirmedChange *unconfirmedChangeError
+	if errors.As(err, &unconfirmedChange) {
+		return fmt.Errorf("%s: The %s may or may not have been applied. Please check the current records with \"dee list\"", reason, change)
+	}
+
+	return fmt.Errorf("%s: The %s was not applied", reason, change)
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getChangeError should return the original error if the context is not done.
+func Test_getChangeError_ContextNotDone_OriginalErrorIsReturned(t *testing.T) {
+	// arrange
+	originalError := fmt.Errorf("API Error")
+
+	// act
+	err := getChangeError(context.Background(), "update of www.example.com (A → 1.2.3.4)", originalError)
+
+	// assert
+	if err != originalError {
+		t.Fail()
+		t.Logf("getChangeError should return the original error but returned %q", err)
+	}
+}
+
+// getChangeError should report that the change was not applied if
+// the context was cancelled before the change was sent.
+func Test_getChangeError_Cancelled_ChangeWasNotApplied(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	err := getChangeError(ctx, "update of www.example.com (A → 1.2.3.4)", context.Canceled)
+
+	// assert
+	expected := "Interrupted: The update of www.example.com (A → 1.2.3.4) was not applied"
+	if err == nil || err.Error() != expected {
+		t.Fail()
+		t.Logf("getChangeError should return %q but returned %q", expected, err)
+	}
+}
+
+// getChangeError should report that the change may have been applied if
+// the context timed out after the change was sent.
+func Test_getChangeError_TimedOutAfterSending_ChangeIsUnconfirmed(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	// act
+	err := getChangeError(ctx, "deletion of www.example.com (A)", &unconfirmedChangeError{Err: context.DeadlineExceeded})
+
+	// assert
+	if err == nil || !strings.HasPrefix(err.Error(), "Timed out: The deletion of www.example.com (A) may or may not have been applied") {
+		t.Fail()
+		t.Logf("getChangeError should report an unconfirmed change but returned %q", err)
+	}
+}
+
+// update should report which change was not applied if it is interrupted.
+func Test_updateAction_Interrupted_ErrorContainsChange(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	editor := testDNSEditor{
+		updateSubdomainFunc: func(domain, subDomainName string, ip net.IP) error {
+			cancel()
+			return context.Canceled
+		},
+	}
+
+	action := updateAction{testDNSEditorFactory{editor, nil}, nil, nil, nil}
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-ip", "1.2.3.4"}
+
+	// act
+	_, err := action.Execute(ctx, arguments)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "update of www.example.com (A → 1.2.3.4) was not applied") {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should report that the update was not applied but returned %q", arguments, err)
+	}
+}
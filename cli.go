@@ -5,15 +5,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/andreaskoch/dee-ns"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/afero"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // GitInfo is either the empty string (the default)
@@ -33,11 +37,70 @@ func version() string {
 
 var actions []action
 
+// globalOptions contains the options which apply to all actions.
+type globalOptions struct {
+	// Profile is the name of the credential profile.
+	Profile string
+
+	// Backend is the name of the DNS backend.
+	Backend string
+
+	// Timeout is the maximum duration of an action. Zero means no timeout.
+	Timeout time.Duration
+
+	// Labels defines whether internationalized domain names are
+	// displayed with Unicode labels or ASCII labels (punycode).
+	Labels string
+
+	// API contains the settings for connecting to the DNSimple API.
+	API apiSettings
+}
+
+// options contains the global options of the current invocation.
+var options globalOptions
+
+// optionEnvironmentVariables maps global options to the environment
+// variables which can be used to set them.
+var optionEnvironmentVariables = map[string]string{
+	"profile":     "DEE_PROFILE",
+	"api-url":     "DEE_API_URL",
+	"ca-file":     "DEE_CA_FILE",
+	"client-cert": "DEE_CLIENT_CERT",
+	"client-key":  "DEE_CLIENT_KEY",
+}
+
+func init() {
+	flag.StringVar(&options.Profile, "profile", defaultProfile, "The credential profile (env: DEE_PROFILE)")
+	flag.StringVar(&options.Backend, "backend", backendDNSimple, "The DNS backend")
+	flag.StringVar(&options.API.URL, "api-url", defaultAPIURL, "The DNSimple API URL (env: DEE_API_URL)")
+	flag.StringVar(&options.API.CAFile, "ca-file", "", "A PEM encoded CA bundle to trust in addition to the system certificates (env: DEE_CA_FILE)")
+	flag.StringVar(&options.API.ClientCertFile, "client-cert", "", "A PEM encoded TLS client certificate (env: DEE_CLIENT_CERT)")
+	flag.StringVar(&options.API.ClientKeyFile, "client-key", "", "The PEM encoded private key of the TLS client certificate (env: DEE_CLIENT_KEY)")
+	flag.IntVar(&options.API.Retry.MaxRetries, "retries", defaultRetryPolicy.MaxRetries, "The number of retries for failed API requests")
+	flag.DurationVar(&options.API.Retry.MinWait, "retry-wait", defaultRetryPolicy.MinWait, "The initial delay between retries")
+	flag.DurationVar(&options.API.Retry.MaxWait, "retry-max-wait", defaultRetryPolicy.MaxWait, "The maximum delay between retries")
+	flag.DurationVar(&options.Timeout, "timeout", 0, "The maximum duration of the action (e.g. 30s); 0 means no timeout")
+	flag.DurationVar(&options.API.Cache.TTL, "cache-ttl", defaultCacheTTL, "How long cached API responses without an ETag are used by read-only actions")
+	flag.BoolVar(&options.API.Cache.Refresh, "refresh", false, "Bypass the local cache and fetch all data from the API")
+	flag.BoolVar(&options.API.Cache.Offline, "offline", false, "Serve read-only actions from the local cache without contacting the API")
+	flag.StringVar(&options.Labels, "labels", labelsUnicode, "Display internationalized domain names with unicode or ascii (punycode) labels")
+}
+
+// cacheFolder contains the path of the folder in which API responses are cached.
+var cacheFolder string
+
+// readOnlyActions contains the names of the actions
+// which can be served from the local cache.
+var readOnlyActions = []string{actionNameList, actionNameGet, actionNameVerify, actionNameHistory, actionNameDiff}
+
+// configFilePath contains the path of the config file.
+var configFilePath string
+
 type action interface {
 	Name() string
 	Description() string
 	Usage() string
-	Execute(arguments []string) (message, error)
+	Execute(ctx context.Context, arguments []string) (message, error)
 }
 
 func init() {
@@ -54,13 +117,42 @@ func init() {
 
 	// base folder
 	baseFolder := getSettingsFolder(filesystem, userHomeDir)
+	cacheFolder = filepath.Join(baseFolder, "cache")
+
+	// config store
+	configFilePath = locateConfigFile(filesystem, userHomeDir, os.Getenv("XDG_CONFIG_HOME"), os.Getenv("DEE_CONFIG"))
+	configStore := newFilesystemConfigStore(filesystem, configFilePath)
 
 	// credential store
-	credentialFilePath := filepath.Join(baseFolder, "credentials.json")
-	credentialStore := filesystemCredentialStore{filesystem, credentialFilePath}
+	credentialStore := profileCredentialStore{filesystem, baseFolder, &options.Profile}
+
+	// account info provider factory
+	accountInfoProviderFactory := dnsimpleAccountInfoProviderFactory{&options.API}
+
+	// DNS client factory; all info providers and editors share one client
+	// so the zones are fetched at most once per invocation
+	sharedDNSClientFactory := &sharedClientFactory{clientFactory: dnsimpleClientFactory{credentialStore, &options.API}}
+
+	// record all changes in the history log
+	historyLog := newHistoryLog(filesystem, filepath.Join(baseFolder, "history.log"), &options.Profile, os.Stderr)
+	historyDNSClientFactory := historyClientFactory{sharedDNSClientFactory, historyLog}
+
+	// run the hook scripts before and after all changes
+	scriptHooks := newScriptHooks(filesystem, baseFolder, &options.Profile, os.Stderr)
 
-	// DNS client factory
-	dnsClientFactory := dnsimpleClientFactory{credentialStore}
+	// post all applied changes to the webhooks
+	webhookNotifier := newWebhookNotifier(filesystem, baseFolder, &options.Profile, os.Stderr)
+	changeHooks := []changeHook{scriptHooks, webhookNotifier}
+
+	// the long-running serve action uses a fresh zone snapshot for every request
+	newRequestFactories := func() (dnsEditorCreator, dnsInfoProviderCreator) {
+		requestDNSClientFactory := &sharedClientFactory{clientFactory: dnsimpleClientFactory{credentialStore, &options.API}}
+		requestClientFactory := hookClientFactory{historyClientFactory{requestDNSClientFactory, historyLog}, changeHooks}
+		requestInfoProviderFactory := dnsimpleInfoProviderFactory{requestClientFactory}
+		return dnsEditorFactory{requestClientFactory, requestInfoProviderFactory}, requestInfoProviderFactory
+	}
+
+	dnsClientFactory := hookClientFactory{historyDNSClientFactory, changeHooks}
 
 	// create DNSimple info provider
 	dnsInfoProviderFactory := dnsimpleInfoProviderFactory{dnsClientFactory}
@@ -68,21 +160,37 @@ func init() {
 	// create a DNS editor instance
 	dnsEditorFactory := dnsEditorFactory{dnsClientFactory, dnsInfoProviderFactory}
 
+	// waits for changes to become visible on the nameservers
+	propagationWaiter := newDNSPropagationWaiter(os.Stderr)
+
 	actions = []action{
-		loginAction{credentialStore},
+		loginAction{credentialStore, accountInfoProviderFactory, os.Stdin, os.Stderr},
 		logoutAction{credentialStore},
+		whoamiAction{credentialStore, accountInfoProviderFactory},
 		listAction{dnsInfoProviderFactory},
-		createAction{dnsEditorFactory, os.Stdin},
-		updateAction{dnsEditorFactory, os.Stdin},
-		deleteAction{dnsEditorFactory},
-		createOrUpdateAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin},
+		getAction{dnsInfoProviderFactory},
+		createAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin},
+		updateAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin, propagationWaiter},
+		deleteAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin, os.Stderr},
+		createOrUpdateAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin, propagationWaiter},
+		setAction{dnsEditorFactory, dnsInfoProviderFactory},
+		verifyAction{dnsInfoProviderFactory, lookupAuthoritativeNameservers},
+		historyAction{historyLog},
+		undoAction{dnsEditorFactory, dnsInfoProviderFactory, historyLog},
+		backupAction{dnsInfoProviderFactory, filesystem, filepath.Join(baseFolder, "backups")},
+		restoreAction{dnsEditorFactory, dnsInfoProviderFactory, filesystem, os.Stdin, os.Stderr},
+		diffAction{dnsInfoProviderFactory, filesystem, os.Stdout},
+		cloneAction{dnsEditorFactory, dnsInfoProviderFactory, os.Stdin, os.Stderr},
+		editAction{dnsEditorFactory, dnsInfoProviderFactory, filesystem, openInEditor, os.Stdin, os.Stderr},
+		serveAction{newRequestFactories, filesystem, filepath.Join(baseFolder, "serve-users.json"), os.Stderr},
+		configAction{configStore, os.Stderr},
 	}
 
 	// override the help information printer
 	// of the flag package
 	executablePath := os.Args[0]
 	executableName := path.Base(executablePath)
-	usagePrinter := newUsagePrinter(executableName, version(), actions)
+	usagePrinter := newUsagePrinter(executableName, version(), flag.CommandLine, actions)
 
 	flag.Usage = func() {
 		usagePrinter.PrintUsageInformation(os.Stdout)
@@ -92,14 +200,44 @@ func init() {
 
 func main() {
 
+	// parse the global options
+	flag.Parse()
+
 	// get action
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	// get the action name
-	selectedActionName := strings.TrimSpace(strings.ToLower(os.Args[1]))
+	selectedActionName := strings.TrimSpace(strings.ToLower(flag.Arg(0)))
+
+	// apply the settings from the config file. A config file which cannot
+	// be parsed is left to the config action which can replace it.
+	config, configError := newFilesystemConfigStore(afero.NewOsFs(), configFilePath).GetConfiguration()
+	if configError != nil {
+		if !isConfigParseError(configError) || selectedActionName != actionNameConfig {
+			fmt.Fprintf(os.Stderr, "%s\n", configError.Error())
+			os.Exit(1)
+		}
+
+		config = configuration{}
+	}
+
+	// an invalid configuration must not prevent the config action from fixing it
+	if applyError := applyConfiguration(config, flag.CommandLine, &defaults); applyError != nil {
+		if selectedActionName != actionNameConfig {
+			fmt.Fprintf(os.Stderr, "Invalid configuration in %q: %s\n", configFilePath, applyError.Error())
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "Warning: Invalid configuration in %q: %s\n", configFilePath, applyError.Error())
+	}
+
+	if optionsError := validateGlobalOptions(options); optionsError != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", optionsError.Error())
+		os.Exit(1)
+	}
 
 	// find a matching action
 	selectedAction := getActionByName(selectedActionName, actions)
@@ -108,8 +246,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	// cache the API responses of read-only actions
+	options.API.Cache.Folder = filepath.Join(cacheFolder, options.Profile)
+	options.API.Cache.Enabled = isReadOnlyAction(selectedActionName)
+	if options.API.Cache.Offline && !options.API.Cache.Enabled {
+		fmt.Fprintf(os.Stderr, "The %q action cannot be used offline\n", selectedActionName)
+		os.Exit(1)
+	}
+
+	// cancel the action on timeout or interrupt; the server runs until it is interrupted
+	timeout := options.Timeout
+	if selectedActionName == actionNameServe {
+		timeout = 0
+	}
+
+	ctx, cancel := newActionContext(timeout)
+	defer cancel()
+
 	// execute the action
-	message, err := selectedAction.Execute(os.Args[2:])
+	ctx = withActionName(ctx, selectedActionName)
+	message, err := selectedAction.Execute(ctx, flag.Args()[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
@@ -120,6 +276,89 @@ func main() {
 
 }
 
+// validateGlobalOptions returns an error if the given options are invalid.
+func validateGlobalOptions(options globalOptions) error {
+	if err := validateProfileName(options.Profile); err != nil {
+		return err
+	}
+
+	if err := validateBackend(options.Backend); err != nil {
+		return err
+	}
+
+	if options.API.Retry.MaxRetries < 0 {
+		return fmt.Errorf("The number of retries cannot be negative")
+	}
+
+	if options.API.Retry.MinWait < 0 || options.API.Retry.MaxWait < 0 {
+		return fmt.Errorf("The retry delays cannot be negative")
+	}
+
+	if options.Timeout < 0 {
+		return fmt.Errorf("The timeout cannot be negative")
+	}
+
+	if options.API.Cache.TTL < 0 {
+		return fmt.Errorf("The cache TTL cannot be negative")
+	}
+
+	if options.API.Cache.Refresh && options.API.Cache.Offline {
+		return fmt.Errorf("The -refresh and -offline options cannot be combined")
+	}
+
+	if err := validateLabels(options.Labels); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newActionContext returns a context which is cancelled after the given
+// timeout (if not zero) or when the process receives an interrupt or
+// termination signal. A second signal terminates the process immediately.
+func newActionContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+
+		cancelSignal := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelSignal()
+		}
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			fmt.Fprintf(os.Stderr, "Interrupted. Cancelling pending requests (press Ctrl-C again to exit immediately) ...\n")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+
+		<-signals
+		os.Exit(130)
+	}()
+
+	return ctx, cancel
+}
+
+// isReadOnlyAction returns true if the action with the given name does not modify any records.
+func isReadOnlyAction(actionName string) bool {
+	for _, readOnlyAction := range readOnlyActions {
+		if readOnlyAction == actionName {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getActionByName returns the action which matches the given name from the list.
 func getActionByName(actionName string, actions []action) action {
 
@@ -162,16 +401,17 @@ func (m successMessage) Text() string {
 // dnsClientFactory provides the ability to create DNS clients.
 type dnsClientFactory interface {
 	// CreateClient create a new dnsClient client instance.
-	CreateClient() (deens.DNSClient, error)
+	CreateClient() (dnsClient, error)
 }
 
 // dnsimpleClientFactory creates DNSimple clients.
 type dnsimpleClientFactory struct {
 	credentialStore deens.CredentialStore
+	settings        *apiSettings
 }
 
 // CreateClient create a new DNSimple client instance.
-func (clientFactory dnsimpleClientFactory) CreateClient() (deens.DNSClient, error) {
+func (clientFactory dnsimpleClientFactory) CreateClient() (dnsClient, error) {
 
 	// get the credentials
 	credentials, credentialError := clientFactory.credentialStore.GetCredentials()
@@ -180,33 +420,33 @@ func (clientFactory dnsimpleClientFactory) CreateClient() (deens.DNSClient, erro
 	}
 
 	// create a DNSimple client
-	dnsimpleClient, dnsimpleClientError := deens.NewDNSClient(credentials)
-	if dnsimpleClientError != nil {
-		return nil, fmt.Errorf("Unable to create DNSimple client. Error: %s", dnsimpleClientError.Error())
+	client, clientError := newDNSimpleClient(credentials, *clientFactory.settings)
+	if clientError != nil {
+		return nil, clientError
 	}
 
-	return dnsimpleClient, nil
+	return newDNSClient(client), nil
 }
 
 type dnsInfoProviderCreator interface {
-	CreateInfoProvider() (deens.DNSInfoProvider, error)
+	CreateInfoProvider() (dnsInfoProvider, error)
 }
 
 type dnsimpleInfoProviderFactory struct {
 	clientFactory dnsClientFactory
 }
 
-func (infoFactory dnsimpleInfoProviderFactory) CreateInfoProvider() (deens.DNSInfoProvider, error) {
+func (infoFactory dnsimpleInfoProviderFactory) CreateInfoProvider() (dnsInfoProvider, error) {
 	client, err := infoFactory.clientFactory.CreateClient()
 	if err != nil {
 		return nil, err
 	}
 
-	return deens.NewDNSInfoProvider(client), nil
+	return newDNSInfoProvider(client), nil
 }
 
 type dnsEditorCreator interface {
-	CreateDNSEditor() (deens.DNSRecordEditor, error)
+	CreateDNSEditor() (dnsRecordEditor, error)
 }
 
 type dnsEditorFactory struct {
@@ -214,7 +454,7 @@ type dnsEditorFactory struct {
 	infoProviderFactory dnsInfoProviderCreator
 }
 
-func (editorFactory dnsEditorFactory) CreateDNSEditor() (deens.DNSRecordEditor, error) {
+func (editorFactory dnsEditorFactory) CreateDNSEditor() (dnsRecordEditor, error) {
 	client, err := editorFactory.clientFactory.CreateClient()
 	if err != nil {
 		return nil, err
@@ -225,5 +465,5 @@ func (editorFactory dnsEditorFactory) CreateDNSEditor() (deens.DNSRecordEditor,
 		return nil, err
 	}
 
-	return deens.NewDNSEditor(client, infoProvider), nil
+	return newDNSEditor(client, infoProvider), nil
 }
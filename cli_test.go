@@ -1,42 +1,153 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/andreaskoch/dee-ns"
+	"github.com/pearkes/dnsimple"
 	"net"
+	"strings"
 )
 
 type testDNSEditorFactory struct {
-	editor deens.DNSRecordEditor
+	editor dnsRecordEditor
 	err    error
 }
 
-func (editorFactory testDNSEditorFactory) CreateDNSEditor() (deens.DNSRecordEditor, error) {
+func (editorFactory testDNSEditorFactory) CreateDNSEditor() (dnsRecordEditor, error) {
 	return editorFactory.editor, editorFactory.err
 }
 
 type testDNSEditor struct {
-	createSubdomainFunc func(domain, subDomainName string, timeToLive int, ip net.IP) error
-	updateSubdomainFunc func(domain, subDomainName string, ip net.IP) error
-	deleteSubdomainFunc func(domain, subDomainName string, recordType string) error
+	createSubdomainFunc       func(domain, subDomainName string, timeToLive int, ip net.IP) error
+	createRecordFunc          func(domain string, fields recordFields) (string, error)
+	updateSubdomainFunc       func(domain, subDomainName string, ip net.IP) error
+	updateSubdomainRecordFunc func(domain, subDomainName, recordType string, change recordUpdate) error
+	deleteSubdomainFunc       func(domain, subDomainName string, recordType string) error
+	updateRecordByIDFunc      func(domain, id string, change recordUpdate) error
+	deleteRecordByIDFunc      func(domain, id string) error
+	setSubdomainAddressesFunc func(domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (addressSetChanges, error)
 }
 
-func (editor testDNSEditor) CreateSubdomain(domain, subDomainName string, timeToLive int, ip net.IP) error {
+func (editor testDNSEditor) CreateSubdomain(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) error {
 	return editor.createSubdomainFunc(domain, subDomainName, timeToLive, ip)
 }
 
-func (editor testDNSEditor) UpdateSubdomain(domain, subDomainName string, ip net.IP) error {
+func (editor testDNSEditor) CreateRecord(ctx context.Context, domain string, fields recordFields) (string, error) {
+	return editor.createRecordFunc(domain, fields)
+}
+
+func (editor testDNSEditor) UpdateSubdomain(ctx context.Context, domain, subDomainName string, ip net.IP) error {
 	return editor.updateSubdomainFunc(domain, subDomainName, ip)
 }
 
-func (editor testDNSEditor) DeleteSubdomain(domain, subDomainName string, recordType string) error {
+func (editor testDNSEditor) UpdateSubdomainRecord(ctx context.Context, domain, subDomainName, recordType string, change recordUpdate) error {
+	return editor.updateSubdomainRecordFunc(domain, subDomainName, recordType, change)
+}
+
+func (editor testDNSEditor) UpdateRecordByID(ctx context.Context, domain, id string, change recordUpdate) error {
+	return editor.updateRecordByIDFunc(domain, id, change)
+}
+
+func (editor testDNSEditor) DeleteRecordByID(ctx context.Context, domain, id string) error {
+	return editor.deleteRecordByIDFunc(domain, id)
+}
+
+func (editor testDNSEditor) SetSubdomainAddresses(ctx context.Context, domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (addressSetChanges, error) {
+	return editor.setSubdomainAddressesFunc(domain, subDomainName, recordType, timeToLive, ips)
+}
+
+func (editor testDNSEditor) DeleteSubdomain(ctx context.Context, domain, subDomainName string, recordType string) error {
 	return editor.deleteSubdomainFunc(domain, subDomainName, recordType)
 }
 
+// newRecordingDNSEditor returns a testDNSEditor which adds a description of
+// every change to the given list (e.g. "create www 600 IN A 1.2.3.4",
+// "update 3 5.6.7.8 ttl=60", "delete 3" or "set www A 1.2.3.4,1.2.3.5").
+func newRecordingDNSEditor(changes *[]string) testDNSEditor {
+	return testDNSEditor{
+		createSubdomainFunc: func(domain, subDomainName string, timeToLive int, ip net.IP) error {
+			*changes = append(*changes, fmt.Sprintf("create %s %s %d", subDomainName, ip, timeToLive))
+			return nil
+		},
+		createRecordFunc: func(domain string, fields recordFields) (string, error) {
+			record := dnsimple.Record{Name: fields.Name, RecordType: fields.RecordType, Content: fields.Content, Ttl: fields.TTL, Prio: fields.Priority}
+			*changes = append(*changes, "create "+strings.Join(getZoneFileFields(record), " "))
+			return fmt.Sprintf("%d", len(*changes)), nil
+		},
+		updateSubdomainFunc: func(domain, subDomainName string, ip net.IP) error {
+			*changes = append(*changes, fmt.Sprintf("update %s %s", subDomainName, ip))
+			return nil
+		},
+		updateRecordByIDFunc: func(domain, id string, change recordUpdate) error {
+			description := "update " + id
+			if change.Content != nil {
+				description += " " + *change.Content
+			}
+
+			if change.TTL != nil {
+				description += fmt.Sprintf(" ttl=%d", *change.TTL)
+			}
+
+			*changes = append(*changes, description)
+			return nil
+		},
+		deleteSubdomainFunc: func(domain, subDomainName string, recordType string) error {
+			*changes = append(*changes, fmt.Sprintf("delete %s %s", subDomainName, recordType))
+			return nil
+		},
+		deleteRecordByIDFunc: func(domain, id string) error {
+			*changes = append(*changes, "delete "+id)
+			return nil
+		},
+		setSubdomainAddressesFunc: func(domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (addressSetChanges, error) {
+			var addresses []string
+			for _, ip := range ips {
+				addresses = append(addresses, ip.String())
+			}
+
+			description := fmt.Sprintf("set %s %s", subDomainName, recordType)
+			if len(addresses) > 0 {
+				description += " " + strings.Join(addresses, ",")
+			}
+
+			*changes = append(*changes, description)
+			return addressSetChanges{}, nil
+		},
+	}
+}
+
 type testInfoProviderFactory struct {
-	infoProvider deens.DNSInfoProvider
+	infoProvider dnsInfoProvider
 	err          error
 }
 
-func (factory testInfoProviderFactory) CreateInfoProvider() (deens.DNSInfoProvider, error) {
+func (factory testInfoProviderFactory) CreateInfoProvider() (dnsInfoProvider, error) {
 	return factory.infoProvider, factory.err
 }
+
+type testAccountInfoProviderFactory struct {
+	getAccountInfoFunc func(credentials deens.APICredentials) (accountInfo, error)
+}
+
+func (factory testAccountInfoProviderFactory) CreateAccountInfoProvider(credentials deens.APICredentials) (accountInfoProvider, error) {
+	return testAccountInfoProvider{func() (accountInfo, error) {
+		return factory.getAccountInfoFunc(credentials)
+	}}, nil
+}
+
+type testAccountInfoProvider struct {
+	getAccountInfoFunc func() (accountInfo, error)
+}
+
+func (provider testAccountInfoProvider) GetAccountInfo(ctx context.Context) (accountInfo, error) {
+	return provider.getAccountInfoFunc()
+}
+
+// validAccountInfoProviderFactory returns an account info provider
+// factory which accepts all credentials.
+func validAccountInfoProviderFactory() testAccountInfoProviderFactory {
+	return testAccountInfoProviderFactory{func(credentials deens.APICredentials) (accountInfo, error) {
+		return accountInfo{Email: credentials.Email}, nil
+	}}
+}
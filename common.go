@@ -4,5 +4,76 @@
 
 package main
 
-// defaultTTL defines the default time-to-live in seconds.
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// defaultTTL defines the built-in default time-to-live in seconds.
+// It can be overridden with the "ttl" setting in the config file.
 const defaultTTL = 600
+
+// defaultProfile defines the name of the default credential profile.
+const defaultProfile = "default"
+
+// backendDNSimple is the name of the DNSimple backend.
+const backendDNSimple = "dnsimple"
+
+// The supported output formats.
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+// actionDefaults contains the default values for action arguments
+// that are used if an argument is not given on the command line.
+type actionDefaults struct {
+	// Domain is the default domain name.
+	Domain string
+
+	// TTL is the default time-to-live in seconds.
+	TTL int
+
+	// Format is the default output format.
+	Format string
+}
+
+// defaults contains the action defaults of the current invocation.
+var defaults = actionDefaults{
+	TTL:    defaultTTL,
+	Format: outputFormatText,
+}
+
+// confirmChanges asks the user to confirm the given number of changes unless
+// yes is set. The given noun names the operation in the error which is returned
+// if the user declines (e.g. "Restore" → "Restore cancelled").
+func confirmChanges(stdin *os.File, stderr io.Writer, yes bool, count int, noun string) error {
+	if yes {
+		return nil
+	}
+
+	confirmed, confirmError := askForConfirmation(stdin, stderr, fmt.Sprintf("Apply %d change(s)?", count))
+	if confirmError == errNotConfirmable {
+		return fmt.Errorf("Refusing to apply %d change(s) without confirmation. Use -yes to apply them in non-interactive runs", count)
+	}
+
+	if confirmError != nil {
+		return confirmError
+	}
+
+	if !confirmed {
+		return fmt.Errorf("%s cancelled", noun)
+	}
+
+	return nil
+}
+
+// printf writes the given message to the given writer (if available).
+func printf(writer io.Writer, format string, args ...interface{}) {
+	if writer == nil {
+		return
+	}
+
+	fmt.Fprintf(writer, format, args...)
+}
@@ -0,0 +1,457 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configFileNames contains the supported names of the config file
+// in the order in which they are looked up.
+var configFileNames = []string{"config.json", "config.yaml", "config.yml"}
+
+// configuration contains the settings from the config file.
+type configuration map[string]string
+
+// Keys returns the sorted list of all keys in the configuration.
+func (config configuration) Keys() []string {
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// configKey describes a setting which can be stored in the config file.
+type configKey struct {
+	// Name is the name of the setting (e.g. "ttl").
+	Name string
+
+	// Description is a short description of the setting.
+	Description string
+
+	// Validate returns an error if the given value is invalid.
+	Validate func(value string) error
+}
+
+// profileNamePattern defines the pattern for valid profile names.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// configKeys contains all supported config file settings.
+var configKeys = []configKey{
+	{"domain", "The default domain (e.g. example.com)", validateAny},
+	{"ttl", "The default time to live in seconds", validateTTL},
+	{"format", "The default output format (text, json)", validateOutputFormat},
+	{"profile", "The default credential profile", validateProfileName},
+	{"backend", "The DNS backend (dnsimple)", validateBackend},
+	{"api-url", "The DNSimple API URL", validateAPIURL},
+	{"ca-file", "A PEM encoded CA bundle", validateAny},
+	{"client-cert", "A PEM encoded TLS client certificate", validateAny},
+	{"client-key", "The PEM encoded private key of the TLS client certificate", validateAny},
+	{"retries", "The number of retries for failed API requests", validateNonNegativeNumber},
+	{"retry-wait", "The initial delay between retries (e.g. 1s)", validateDuration},
+	{"retry-max-wait", "The maximum delay between retries (e.g. 30s)", validateDuration},
+	{"timeout", "The maximum duration of an action (e.g. 1m)", validateDuration},
+	{"cache-ttl", "How long cached API responses without an ETag are used (e.g. 5m)", validateDuration},
+	{"labels", "Display internationalized domain names with unicode or ascii labels", validateLabels},
+}
+
+// getConfigKey returns the config key with the given name.
+// Returns false if there is no such key.
+func getConfigKey(name string) (configKey, bool) {
+	for _, key := range configKeys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+
+	return configKey{}, false
+}
+
+// validateConfiguration returns an error if the given configuration
+// contains unknown keys or invalid values.
+func validateConfiguration(config configuration) error {
+	for _, name := range config.Keys() {
+		key, exists := getConfigKey(name)
+		if !exists {
+			return fmt.Errorf("Unknown setting %q", name)
+		}
+
+		if err := key.Validate(config[name]); err != nil {
+			return fmt.Errorf("Invalid value for %q: %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateAny(value string) error {
+	return nil
+}
+
+func validateTTL(value string) error {
+	ttl, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+
+	if ttl < 0 {
+		return fmt.Errorf("The TTL cannot be negative")
+	}
+
+	return nil
+}
+
+func validateNonNegativeNumber(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+
+	if number < 0 {
+		return fmt.Errorf("The value cannot be negative")
+	}
+
+	return nil
+}
+
+func validateDuration(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid duration (e.g. 1s, 500ms, 2m)", value)
+	}
+
+	if duration < 0 {
+		return fmt.Errorf("The duration cannot be negative")
+	}
+
+	return nil
+}
+
+func validateOutputFormat(value string) error {
+	if value != outputFormatText && value != outputFormatJSON {
+		return fmt.Errorf("Unsupported output format %q (supported: %s, %s)", value, outputFormatText, outputFormatJSON)
+	}
+
+	return nil
+}
+
+func validateProfileName(value string) error {
+	if !profileNamePattern.MatchString(value) {
+		return fmt.Errorf("%q is not a valid profile name (allowed: letters, digits, \"-\" and \"_\")", value)
+	}
+
+	return nil
+}
+
+func validateBackend(value string) error {
+	if value != backendDNSimple {
+		return fmt.Errorf("Unsupported backend %q (supported: %s)", value, backendDNSimple)
+	}
+
+	return nil
+}
+
+func validateAPIURL(value string) error {
+	_, err := getAPIURL(value)
+	return err
+}
+
+// locateConfigFile returns the path of the config file. If the override
+// path (DEE_CONFIG) is set it will be returned. Otherwise the first
+// existing config file in $XDG_CONFIG_HOME/dee and ~/.dee is returned.
+// If there is no config file yet the path of the JSON config file in the
+// preferred folder is returned.
+func locateConfigFile(fs afero.Fs, homeDir, xdgConfigHome, override string) string {
+	if !isEmpty(override) {
+		return override
+	}
+
+	var folders []string
+	if !isEmpty(xdgConfigHome) {
+		folders = append(folders, filepath.Join(xdgConfigHome, "dee"))
+	}
+
+	folders = append(folders, filepath.Join(homeDir, ".dee"))
+
+	for _, folder := range folders {
+		for _, fileName := range configFileNames {
+			filePath := filepath.Join(folder, fileName)
+			if _, err := fs.Stat(filePath); err == nil {
+				return filePath
+			}
+		}
+	}
+
+	return filepath.Join(folders[0], configFileNames[0])
+}
+
+// newFilesystemConfigStore creates a new filesystem config store instance.
+func newFilesystemConfigStore(filesystem afero.Fs, filePath string) filesystemConfigStore {
+	return filesystemConfigStore{
+		fs:       filesystem,
+		filePath: filePath,
+	}
+}
+
+// configStore reads and persists the configuration.
+type configStore interface {
+	// GetConfiguration returns the stored configuration. An empty
+	// configuration is returned if there is no config file and a
+	// configParseError if the config file cannot be parsed.
+	GetConfiguration() (configuration, error)
+
+	// SaveConfiguration persists the given configuration.
+	SaveConfiguration(config configuration) error
+}
+
+// filesystemConfigStore reads and persists the configuration
+// from and to a JSON or YAML file.
+type filesystemConfigStore struct {
+	fs       afero.Fs
+	filePath string
+}
+
+// configParseError is returned if the config file exists but cannot be parsed.
+type configParseError struct {
+	filePath string
+	err      error
+}
+
+func (err *configParseError) Error() string {
+	return fmt.Sprintf("Unable to parse %q: %s", err.filePath, err.err.Error())
+}
+
+// isConfigParseError returns true if the given error
+// indicates that the config file cannot be parsed.
+func isConfigParseError(err error) bool {
+	var parseError *configParseError
+	return errors.As(err, &parseError)
+}
+
+// isYAML returns true if the config file is a YAML file.
+func (store filesystemConfigStore) isYAML() bool {
+	extension := strings.ToLower(filepath.Ext(store.filePath))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// GetConfiguration reads the configuration from disc.
+func (store filesystemConfigStore) GetConfiguration() (configuration, error) {
+
+	// check if the file system is initialized
+	if store.fs == nil {
+		return nil, fmt.Errorf("No filesystem specified")
+	}
+
+	file, openError := store.fs.Open(store.filePath)
+	if openError != nil {
+		if os.IsNotExist(openError) {
+			return configuration{}, nil
+		}
+
+		return nil, openError
+	}
+
+	defer file.Close()
+
+	content, readError := ioutil.ReadAll(file)
+	if readError != nil {
+		return nil, readError
+	}
+
+	var config configuration
+	var parseError error
+	if store.isYAML() {
+		config, parseError = parseYAMLConfiguration(content)
+	} else {
+		config, parseError = parseJSONConfiguration(content)
+	}
+
+	if parseError != nil {
+		return nil, &configParseError{store.filePath, parseError}
+	}
+
+	return config, nil
+}
+
+// SaveConfiguration writes the given configuration to disc.
+func (store filesystemConfigStore) SaveConfiguration(config configuration) error {
+
+	// check if the file system is initialized
+	if store.fs == nil {
+		return fmt.Errorf("No filesystem provided")
+	}
+
+	if err := store.fs.MkdirAll(filepath.Dir(store.filePath), 0700); err != nil {
+		return err
+	}
+
+	var content []byte
+	if store.isYAML() {
+		content = formatYAMLConfiguration(config)
+	} else {
+		content = formatJSONConfiguration(config)
+	}
+
+	file, openError := store.fs.OpenFile(store.filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if openError != nil {
+		return openError
+	}
+
+	defer file.Close()
+
+	_, writeError := file.Write(content)
+	return writeError
+}
+
+// parseJSONConfiguration parses a flat JSON object. Numbers
+// and booleans are converted to their string representation.
+func parseJSONConfiguration(content []byte) (configuration, error) {
+	config := configuration{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return config, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+
+	for key, value := range values {
+		switch typedValue := value.(type) {
+		case string:
+			config[key] = typedValue
+		case float64:
+			config[key] = strconv.FormatFloat(typedValue, 'f', -1, 64)
+		case bool:
+			config[key] = strconv.FormatBool(typedValue)
+		default:
+			return nil, fmt.Errorf("The value of %q must be a string, number or boolean", key)
+		}
+	}
+
+	return config, nil
+}
+
+// formatJSONConfiguration formats the given configuration as a JSON object.
+func formatJSONConfiguration(config configuration) []byte {
+	content, _ := json.MarshalIndent(config, "", "  ")
+	return append(content, '\n')
+}
+
+// parseYAMLConfiguration parses a flat YAML mapping ("key: value" lines).
+// Nested structures are not supported.
+func parseYAMLConfiguration(content []byte) (configuration, error) {
+	config := configuration{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		separatorIndex := strings.Index(line, ":")
+		if separatorIndex < 1 {
+			return nil, fmt.Errorf("Line %d: expected \"key: value\"", lineNumber)
+		}
+
+		key := strings.TrimSpace(line[:separatorIndex])
+		value := strings.TrimSpace(line[separatorIndex+1:])
+
+		if unquotedValue, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquotedValue
+		} else if len(value) > 1 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = strings.Replace(value[1:len(value)-1], "''", "'", -1)
+		} else if commentIndex := strings.Index(value, " #"); commentIndex >= 0 {
+			value = strings.TrimSpace(value[:commentIndex])
+		}
+
+		config[key] = value
+	}
+
+	return config, scanner.Err()
+}
+
+// formatYAMLConfiguration formats the given configuration as a flat YAML mapping.
+func formatYAMLConfiguration(config configuration) []byte {
+	buf := new(bytes.Buffer)
+	for _, key := range config.Keys() {
+		fmt.Fprintf(buf, "%s: %s\n", key, strconv.Quote(config[key]))
+	}
+
+	return buf.Bytes()
+}
+
+// applyConfiguration sets all global options from the given configuration
+// which have not been set on the command line or via environment variables.
+// The action defaults (domain, TTL, output format) are taken from the
+// configuration as well.
+func applyConfiguration(config configuration, options *flag.FlagSet, defaults *actionDefaults) error {
+
+	if err := validateConfiguration(config); err != nil {
+		return err
+	}
+
+	// global options
+	optionsSetOnCommandLine := make(map[string]bool)
+	options.Visit(func(option *flag.Flag) {
+		optionsSetOnCommandLine[option.Name] = true
+	})
+
+	var applyError error
+	options.VisitAll(func(option *flag.Flag) {
+		if applyError != nil || optionsSetOnCommandLine[option.Name] {
+			return
+		}
+
+		if environmentVariable, exists := optionEnvironmentVariables[option.Name]; exists && os.Getenv(environmentVariable) != "" {
+			applyError = options.Set(option.Name, os.Getenv(environmentVariable))
+			return
+		}
+
+		if value, exists := config[option.Name]; exists {
+			applyError = options.Set(option.Name, value)
+		}
+	})
+
+	if applyError != nil {
+		return applyError
+	}
+
+	// action defaults
+	if domain, exists := config["domain"]; exists {
+		defaults.Domain = domain
+	}
+
+	if ttl, exists := config["ttl"]; exists {
+		defaults.TTL, _ = strconv.Atoi(ttl)
+	}
+
+	if format, exists := config["format"]; exists {
+		defaults.Format = format
+	}
+
+	return nil
+}
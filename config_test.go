@@ -0,0 +1,237 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"github.com/spf13/afero"
+	"os"
+	"testing"
+)
+
+func Test_locateConfigFile(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		existingFiles  []string
+		xdgConfigHome  string
+		override       string
+		expectedResult string
+	}{
+		// no config file
+		{[]string{}, "", "", "/home/user/.dee/config.json"},
+		{[]string{}, "/home/user/.config", "", "/home/user/.config/dee/config.json"},
+
+		// override
+		{[]string{"/home/user/.dee/config.json"}, "", "/etc/dee.yaml", "/etc/dee.yaml"},
+
+		// existing files
+		{[]string{"/home/user/.dee/config.yaml"}, "", "", "/home/user/.dee/config.yaml"},
+		{[]string{"/home/user/.dee/config.yml"}, "/home/user/.config", "", "/home/user/.dee/config.yml"},
+		{[]string{"/home/user/.dee/config.json", "/home/user/.config/dee/config.yaml"}, "/home/user/.config", "", "/home/user/.config/dee/config.yaml"},
+	}
+
+	for _, input := range inputs {
+		fs := afero.NewMemMapFs()
+		for _, existingFile := range input.existingFiles {
+			afero.WriteFile(fs, existingFile, []byte("{}"), 0600)
+		}
+
+		// act
+		result := locateConfigFile(fs, "/home/user", input.xdgConfigHome, input.override)
+
+		// assert
+		if result != input.expectedResult {
+			t.Fail()
+			t.Logf("locateConfigFile(%q, %q, %q) returned %q but should have returned %q.", input.existingFiles, input.xdgConfigHome, input.override, result, input.expectedResult)
+		}
+	}
+}
+
+func Test_parseJSONConfiguration_NumbersAndStringsAreAccepted(t *testing.T) {
+	// act
+	config, err := parseJSONConfiguration([]byte(`{"domain": "example.com", "ttl": 60}`))
+
+	// assert
+	if err != nil || config["domain"] != "example.com" || config["ttl"] != "60" {
+		t.Fail()
+		t.Logf("parseJSONConfiguration returned %q (Error: %s)", config, err)
+	}
+}
+
+func Test_parseJSONConfiguration_NestedValues_ErrorIsReturned(t *testing.T) {
+	// act
+	_, err := parseJSONConfiguration([]byte(`{"domain": {"name": "example.com"}}`))
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("parseJSONConfiguration should not accept nested values.")
+	}
+}
+
+func Test_parseYAMLConfiguration(t *testing.T) {
+	// arrange
+	content := `---
+# defaults
+domain: example.com
+ttl: 60 # one minute
+profile: "work"
+api-url: 'https://api.sandbox.dnsimple.com/v1'
+`
+
+	// act
+	config, err := parseYAMLConfiguration([]byte(content))
+
+	// assert
+	expected := configuration{
+		"domain":  "example.com",
+		"ttl":     "60",
+		"profile": "work",
+		"api-url": "https://api.sandbox.dnsimple.com/v1",
+	}
+
+	if err != nil || len(config) != len(expected) {
+		t.Fatalf("parseYAMLConfiguration returned %q (Error: %s)", config, err)
+	}
+
+	for key, value := range expected {
+		if config[key] != value {
+			t.Fail()
+			t.Logf("parseYAMLConfiguration returned %q for %q but should have returned %q", config[key], key, value)
+		}
+	}
+}
+
+func Test_parseYAMLConfiguration_InvalidLine_ErrorIsReturned(t *testing.T) {
+	// act
+	_, err := parseYAMLConfiguration([]byte("domain example.com\n"))
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("parseYAMLConfiguration should return an error for lines without a key.")
+	}
+}
+
+func Test_filesystemConfigStore_SaveAndGetConfiguration_ConfigurationIsPreserved(t *testing.T) {
+	for _, filePath := range []string{"/home/user/.dee/config.json", "/home/user/.dee/config.yaml"} {
+		// arrange
+		store := newFilesystemConfigStore(afero.NewMemMapFs(), filePath)
+		config := configuration{"domain": "example.com", "ttl": "60"}
+
+		// act
+		saveError := store.SaveConfiguration(config)
+		result, getError := store.GetConfiguration()
+
+		// assert
+		if saveError != nil || getError != nil || result["domain"] != "example.com" || result["ttl"] != "60" {
+			t.Fail()
+			t.Logf("The configuration stored at %q was not preserved: %q (Errors: %v, %v)", filePath, result, saveError, getError)
+		}
+	}
+}
+
+func Test_filesystemConfigStore_GetConfiguration_NoConfigFile_EmptyConfigurationIsReturned(t *testing.T) {
+	// arrange
+	store := newFilesystemConfigStore(afero.NewMemMapFs(), "/home/user/.dee/config.json")
+
+	// act
+	config, err := store.GetConfiguration()
+
+	// assert
+	if err != nil || len(config) != 0 {
+		t.Fail()
+		t.Logf("GetConfiguration should return an empty configuration if there is no config file.")
+	}
+}
+
+// newTestOptions returns a flag set with the given string options.
+func newTestOptions(names ...string) *flag.FlagSet {
+	options := flag.NewFlagSet("dee", flag.ContinueOnError)
+	for _, name := range names {
+		options.String(name, "", "")
+	}
+
+	return options
+}
+
+func Test_applyConfiguration_CommandLineTakesPrecedence(t *testing.T) {
+	// arrange
+	options := newTestOptions("profile", "api-url")
+	options.Parse([]string{"-profile", "private"})
+	config := configuration{"profile": "work", "api-url": "https://api.sandbox.dnsimple.com/v1"}
+	actionDefaults := actionDefaults{TTL: defaultTTL}
+
+	// act
+	err := applyConfiguration(config, options, &actionDefaults)
+
+	// assert
+	if err != nil || options.Lookup("profile").Value.String() != "private" || options.Lookup("api-url").Value.String() != "https://api.sandbox.dnsimple.com/v1" {
+		t.Fail()
+		t.Logf("applyConfiguration should only apply settings which are not set on the command line (Error: %s).", err)
+	}
+}
+
+func Test_applyConfiguration_EnvironmentTakesPrecedenceOverConfigFile(t *testing.T) {
+	// arrange
+	os.Setenv("DEE_PROFILE", "environment")
+	defer os.Unsetenv("DEE_PROFILE")
+
+	options := newTestOptions("profile")
+	options.Parse([]string{})
+	config := configuration{"profile": "work"}
+	actionDefaults := actionDefaults{TTL: defaultTTL}
+
+	// act
+	applyConfiguration(config, options, &actionDefaults)
+
+	// assert
+	if options.Lookup("profile").Value.String() != "environment" {
+		t.Fail()
+		t.Logf("applyConfiguration should prefer environment variables over the config file.")
+	}
+}
+
+func Test_applyConfiguration_ActionDefaultsAreSet(t *testing.T) {
+	// arrange
+	options := newTestOptions()
+	config := configuration{"domain": "example.com", "ttl": "60", "format": "json"}
+	actionDefaults := actionDefaults{TTL: defaultTTL, Format: outputFormatText}
+
+	// act
+	err := applyConfiguration(config, options, &actionDefaults)
+
+	// assert
+	if err != nil || actionDefaults.Domain != "example.com" || actionDefaults.TTL != 60 || actionDefaults.Format != "json" {
+		t.Fail()
+		t.Logf("applyConfiguration did not set the action defaults: %#v (Error: %s)", actionDefaults, err)
+	}
+}
+
+func Test_applyConfiguration_InvalidConfiguration_ErrorIsReturned(t *testing.T) {
+	// arrange
+	configurations := []configuration{
+		{"unknown": "value"},
+		{"ttl": "-1"},
+		{"ttl": "one minute"},
+		{"format": "xml"},
+		{"backend": "route53"},
+		{"profile": "../../etc"},
+		{"api-url": "ftp://example.com"},
+	}
+
+	for _, config := range configurations {
+		actionDefaults := actionDefaults{}
+
+		// act
+		err := applyConfiguration(config, newTestOptions(), &actionDefaults)
+
+		// assert
+		if err == nil {
+			t.Fail()
+			t.Logf("applyConfiguration(%q) should return an error.", config)
+		}
+	}
+}
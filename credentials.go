@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/afero"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 // newFilesystemCredentialStore creates a new filesystem credential store instance.
@@ -124,6 +125,58 @@ func (c filesystemCredentialStore) GetCredentials() (deens.APICredentials, error
 	return credentials, nil
 }
 
+// getCredentialFilePath returns the path of the credential file of the given profile.
+// The credentials of the default profile are stored in the base folder, the
+// credentials of all other profiles in a sub folder of the "profiles" folder.
+func getCredentialFilePath(baseFolder, profile string) string {
+	if profile == "" || profile == defaultProfile {
+		return filepath.Join(baseFolder, "credentials.json")
+	}
+
+	return filepath.Join(baseFolder, "profiles", profile, "credentials.json")
+}
+
+// profileCredentialStore reads and persists the credentials
+// of the selected profile from and to disc.
+type profileCredentialStore struct {
+	fs         afero.Fs
+	baseFolder string
+	profile    *string
+}
+
+// getStore returns the filesystem credential store of the selected profile.
+func (c profileCredentialStore) getStore() filesystemCredentialStore {
+	profile := defaultProfile
+	if c.profile != nil {
+		profile = *c.profile
+	}
+
+	return newFilesystemCredentialStore(c.fs, getCredentialFilePath(c.baseFolder, profile))
+}
+
+// SaveCredentials saves the given credentials for the selected profile.
+func (c profileCredentialStore) SaveCredentials(credentials deens.APICredentials) error {
+	store := c.getStore()
+
+	if c.fs != nil {
+		if err := c.fs.MkdirAll(filepath.Dir(store.filePath), 0700); err != nil {
+			return err
+		}
+	}
+
+	return store.SaveCredentials(credentials)
+}
+
+// DeleteCredentials removes the saved credentials of the selected profile.
+func (c profileCredentialStore) DeleteCredentials() error {
+	return c.getStore().DeleteCredentials()
+}
+
+// GetCredentials returns the stored credentials of the selected profile.
+func (c profileCredentialStore) GetCredentials() (deens.APICredentials, error) {
+	return c.getStore().GetCredentials()
+}
+
 type noCredentialsError struct {
 	message string
 }
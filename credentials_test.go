@@ -348,3 +348,47 @@ func Test_filesystemCredentialStore_SourceFileExists_DeleteCredentials_FileIsDel
 		t.Logf("The file %q should be deleted after DeleteCredentials is executed.", credentialFilePath)
 	}
 }
+
+func Test_getCredentialFilePath(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		profile        string
+		expectedResult string
+	}{
+		{"", "/home/user/.dee/credentials.json"},
+		{"default", "/home/user/.dee/credentials.json"},
+		{"work", "/home/user/.dee/profiles/work/credentials.json"},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result := getCredentialFilePath("/home/user/.dee", input.profile)
+
+		// assert
+		if result != input.expectedResult {
+			t.Fail()
+			t.Logf("getCredentialFilePath(%q) returned %q but should have returned %q.", input.profile, result, input.expectedResult)
+		}
+	}
+}
+
+func Test_profileCredentialStore_CredentialsAreStoredPerProfile(t *testing.T) {
+	// arrange
+	filesystem := afero.NewMemMapFs()
+	profile := "work"
+	store := profileCredentialStore{filesystem, "/home/user/.dee", &profile}
+
+	// act
+	saveError := store.SaveCredentials(deens.APICredentials{Email: "work@example.com", Token: "1234"})
+	profile = defaultProfile
+	_, defaultProfileError := store.GetCredentials()
+	profile = "work"
+	credentials, workProfileError := store.GetCredentials()
+
+	// assert
+	if saveError != nil || defaultProfileError == nil || workProfileError != nil || credentials.Email != "work@example.com" {
+		t.Fail()
+		t.Logf("The credentials should only be available for the profile they were saved for (Errors: %v, %v, %v).", saveError, defaultProfileError, workProfileError)
+	}
+}
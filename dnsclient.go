@@ -0,0 +1,289 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net/http"
+	"net/url"
+)
+
+// newDNSClient creates a new DNS client which uses the given DNSimple client
+// and attaches the context of each call to its API requests.
+func newDNSClient(client *dnsimple.Client) dnsClient {
+	return &dnsimpleClient{client}
+}
+
+// dnsClient provides functions for updating DNS records. Unlike deens.DNSClient
+// all API requests of a call are cancelled if the given context is done.
+type dnsClient interface {
+	// UpdateRecord update the DNS record with the given id.
+	UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error)
+
+	// GetRecords returns all DNS records for the given domain.
+	GetRecords(ctx context.Context, domain string) ([]dnsimple.Record, error)
+
+	// RetrieveRecord returns the DNS record with the given id.
+	RetrieveRecord(ctx context.Context, domain string, id string) (dnsimple.Record, error)
+
+	// GetDomains returns a list of domain.
+	GetDomains(ctx context.Context) ([]dnsimple.Domain, error)
+
+	// CreateRecord creates a new DNS record for the given domain.
+	CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error)
+
+	// DestroyRecord deletes the DNS record with the given id.
+	DestroyRecord(ctx context.Context, domain string, id string) error
+}
+
+// dnsRecordFinder is implemented by DNS clients which can
+// filter the DNS records of a domain by name and type.
+type dnsRecordFinder interface {
+	// FindRecords returns the DNS records of the given domain that have the given
+	// name and record type. An empty record type matches all types.
+	FindRecords(ctx context.Context, domain, name, recordType string) ([]dnsimple.Record, error)
+}
+
+// recordFields contains all fields of a DNS record which can be updated.
+type recordFields struct {
+	Name       string
+	Content    string
+	RecordType string
+	TTL        int64
+	Priority   int64
+}
+
+// dnsRecordFieldUpdater is implemented by DNS clients which can
+// update all fields of a DNS record including the priority.
+type dnsRecordFieldUpdater interface {
+	// UpdateRecordFields sets the fields of the DNS record with the given id.
+	UpdateRecordFields(ctx context.Context, domain string, id string, fields recordFields) (string, error)
+}
+
+// dnsRecordFieldCreator is implemented by DNS clients which can
+// create DNS records with all fields including the priority.
+type dnsRecordFieldCreator interface {
+	// CreateRecordFields creates a new DNS record with the given fields and returns its id.
+	CreateRecordFields(ctx context.Context, domain string, fields recordFields) (string, error)
+}
+
+// dnsimpleClient is a dnsClient which uses the DNSimple API.
+type dnsimpleClient struct {
+	client *dnsimple.Client
+}
+
+// UpdateRecord update the DNS record with the given id.
+func (c *dnsimpleClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	return c.withContext(ctx).UpdateRecord(domain, id, opts)
+}
+
+// GetRecords returns all DNS records for the given domain.
+func (c *dnsimpleClient) GetRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return c.withContext(ctx).GetRecords(domain)
+}
+
+// RetrieveRecord returns the DNS record with the given id.
+func (c *dnsimpleClient) RetrieveRecord(ctx context.Context, domain string, id string) (dnsimple.Record, error) {
+	record, err := c.withContext(ctx).RetrieveRecord(domain, id)
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	return *record, nil
+}
+
+// GetDomains returns a list of domain.
+func (c *dnsimpleClient) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+	return c.withContext(ctx).GetDomains()
+}
+
+// CreateRecord creates a new DNS record for the given domain.
+func (c *dnsimpleClient) CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	return c.withContext(ctx).CreateRecord(domain, opts)
+}
+
+// DestroyRecord deletes the DNS record with the given id.
+func (c *dnsimpleClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	return c.withContext(ctx).DestroyRecord(domain, id)
+}
+
+// FindRecords returns the DNS records of the given domain that have the given name and record type.
+func (c *dnsimpleClient) FindRecords(ctx context.Context, domain, name, recordType string) ([]dnsimple.Record, error) {
+	query := url.Values{}
+	query.Set("name", name)
+	if recordType != "" {
+		query.Set("type", recordType)
+	}
+
+	client := c.withContext(ctx)
+	request, requestError := client.NewRequest(nil, "GET", fmt.Sprintf("/domains/%s/records?%s", domain, query.Encode()))
+	if requestError != nil {
+		return nil, requestError
+	}
+
+	response, responseError := client.Http.Do(request)
+	if responseError != nil {
+		return nil, responseError
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to fetch the records of %q: %s", domain, response.Status)
+	}
+
+	var recordResponses []dnsimple.RecordResponse
+	if decodeError := json.NewDecoder(response.Body).Decode(&recordResponses); decodeError != nil {
+		return nil, decodeError
+	}
+
+	records := make([]dnsimple.Record, len(recordResponses))
+	for index, recordResponse := range recordResponses {
+		records[index] = recordResponse.Record
+	}
+
+	return records, nil
+}
+
+// UpdateRecordFields sets the fields of the DNS record with the given id.
+func (c *dnsimpleClient) UpdateRecordFields(ctx context.Context, domain string, id string, fields recordFields) (string, error) {
+	params := map[string]interface{}{
+		"name":        fields.Name,
+		"content":     fields.Content,
+		"record_type": fields.RecordType,
+		"ttl":         fields.TTL,
+		"prio":        fields.Priority,
+	}
+
+	client := c.withContext(ctx)
+	request, requestError := client.NewRequest(params, "PUT", fmt.Sprintf("/domains/%s/records/%s", domain, id))
+	if requestError != nil {
+		return "", requestError
+	}
+
+	response, responseError := client.Http.Do(request)
+	if responseError != nil {
+		return "", fmt.Errorf("Error updating record: %s", responseError)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("Error updating record: %s", response.Status)
+	}
+
+	var recordResponse dnsimple.RecordResponse
+	if decodeError := json.NewDecoder(response.Body).Decode(&recordResponse); decodeError != nil {
+		return "", fmt.Errorf("Error parsing record response: %s", decodeError)
+	}
+
+	return recordResponse.Record.StringId(), nil
+}
+
+// CreateRecordFields creates a new DNS record with the given fields and returns its id.
+func (c *dnsimpleClient) CreateRecordFields(ctx context.Context, domain string, fields recordFields) (string, error) {
+	params := map[string]interface{}{
+		"name":        fields.Name,
+		"content":     fields.Content,
+		"record_type": fields.RecordType,
+		"ttl":         fields.TTL,
+		"prio":        fields.Priority,
+	}
+
+	client := c.withContext(ctx)
+	request, requestError := client.NewRequest(params, "POST", fmt.Sprintf("/domains/%s/records", domain))
+	if requestError != nil {
+		return "", requestError
+	}
+
+	response, responseError := client.Http.Do(request)
+	if responseError != nil {
+		return "", fmt.Errorf("Error creating record: %s", responseError)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("Error creating record: %s", response.Status)
+	}
+
+	var recordResponse dnsimple.RecordResponse
+	if decodeError := json.NewDecoder(response.Body).Decode(&recordResponse); decodeError != nil {
+		return "", fmt.Errorf("Error parsing record response: %s", decodeError)
+	}
+
+	return recordResponse.Record.StringId(), nil
+}
+
+// withContext returns a copy of the DNSimple client
+// which attaches the given context to all requests.
+func (c *dnsimpleClient) withContext(ctx context.Context) *dnsimple.Client {
+	httpClient := http.Client{}
+	if c.client.Http != nil {
+		httpClient = *c.client.Http
+	}
+
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	httpClient.Transport = contextTransport{ctx, transport}
+
+	clientWithContext := *c.client
+	clientWithContext.Http = &httpClient
+	return &clientWithContext
+}
+
+// contextTransport is a http.RoundTripper which attaches a context to all requests.
+type contextTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+// RoundTrip executes the given request with the context of the transport.
+func (t contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return t.transport.RoundTrip(request.WithContext(t.ctx))
+}
+
+// dnsClientDecorator passes all queries to the decorated DNS client.
+// It is embedded by the clients which only intercept changes.
+type dnsClientDecorator struct {
+	client dnsClient
+}
+
+// GetDomains returns all domains of the account.
+func (c dnsClientDecorator) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+	return c.client.GetDomains(ctx)
+}
+
+// GetRecords returns all records of the given domain.
+func (c dnsClientDecorator) GetRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	return c.client.GetRecords(ctx, domain)
+}
+
+// RetrieveRecord returns the record with the given id.
+func (c dnsClientDecorator) RetrieveRecord(ctx context.Context, domain string, id string) (dnsimple.Record, error) {
+	return c.client.RetrieveRecord(ctx, domain, id)
+}
+
+// FindRecords returns the records with the given name and type if the
+// decorated client supports name-filtered queries.
+func (c dnsClientDecorator) FindRecords(ctx context.Context, domain, name, recordType string) ([]dnsimple.Record, error) {
+	finder, isFinder := c.client.(dnsRecordFinder)
+	if !isFinder {
+		records, err := c.client.GetRecords(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		return filterRecords(records, name, recordType), nil
+	}
+
+	return finder.FindRecords(ctx, domain, name, recordType)
+}
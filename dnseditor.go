@@ -0,0 +1,584 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+)
+
+// The dnsRecordCreator interface offers functions for creating domain records.
+type dnsRecordCreator interface {
+
+	// CreateSubdomain creates a new subdomain address record.
+	CreateSubdomain(ctx context.Context, domain, subDomainName string, timeToLive int, ip net.IP) error
+
+	// CreateRecord creates a record of any type with the given fields and returns its id.
+	CreateRecord(ctx context.Context, domain string, fields recordFields) (string, error)
+}
+
+// The dnsRecordUpdater interface offers functions for updating domain records.
+type dnsRecordUpdater interface {
+
+	// UpdateSubdomain sets ip address of the given subdomain.
+	UpdateSubdomain(ctx context.Context, domain, subDomainName string, ip net.IP) error
+
+	// UpdateSubdomainRecord changes the given fields of the subdomain record of the given type.
+	UpdateSubdomainRecord(ctx context.Context, domain, subDomainName, recordType string, change recordUpdate) error
+
+	// UpdateRecordByID changes the given fields of the record with the given id.
+	UpdateRecordByID(ctx context.Context, domain, id string, change recordUpdate) error
+}
+
+// recordUpdate contains the fields of a DNS record which shall be changed.
+// Fields which are nil are not changed.
+type recordUpdate struct {
+	Name     *string
+	Content  *string
+	TTL      *int
+	Priority *int
+}
+
+// The dnsRecordDeleter interface offers functions for creating domain records.
+type dnsRecordDeleter interface {
+
+	// DeleteSubdomain removes subdomain address record of the given type.
+	DeleteSubdomain(ctx context.Context, domain, subDomainName string, recordType string) error
+
+	// DeleteRecordByID removes the record with the given id.
+	DeleteRecordByID(ctx context.Context, domain, id string) error
+}
+
+// The dnsRecordSetEditor interface offers functions for editing all
+// address records of a subdomain and type as a set.
+type dnsRecordSetEditor interface {
+
+	// SetSubdomainAddresses creates and deletes address records of the given type
+	// until the subdomain has exactly one record for each of the given IP addresses.
+	SetSubdomainAddresses(ctx context.Context, domain, subDomainName, recordType string, timeToLive int, ips []net.IP) (addressSetChanges, error)
+}
+
+// addressSetChanges contains the IP addresses which were added
+// to and removed from an address record set.
+type addressSetChanges struct {
+	Added   []net.IP
+	Removed []net.IP
+}
+
+// IsEmpty returns true if no address was added or removed.
+func (changes addressSetChanges) IsEmpty() bool {
+	return len(changes.Added) == 0 && len(changes.Removed) == 0
+}
+
+// The dnsRecordEditor interface provides functions for editing DNS records.
+type dnsRecordEditor interface {
+	dnsRecordCreator
+	dnsRecordUpdater
+	dnsRecordDeleter
+	dnsRecordSetEditor
+}
+
+// newDNSEditor creates an new dnsRecordEditor instance.
+func newDNSEditor(client dnsClient, infoProvider dnsInfoProvider) dnsRecordEditor {
+	return &dnsEditor{client, infoProvider}
+}
+
+// dnsEditor updates DNSimple domain records.
+type dnsEditor struct {
+	client       dnsClient
+	infoProvider dnsInfoProvider
+}
+
+// CreateSubdomain creates an address record for the given domain
+func (editor *dnsEditor) CreateSubdomain(ctx context.Context, domain, subdomain string, timeToLive int, ip net.IP) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return fmt.Errorf("The subdomain name is invalid: %q", subdomain)
+	}
+
+	if ip == nil {
+		return fmt.Errorf("No ip supplied")
+	}
+
+	// check if the record already exists
+	recordType := getDNSRecordTypeByIP(ip)
+	if _, err := editor.infoProvider.GetSubdomainRecord(ctx, domain, subdomain, recordType); err == nil {
+		return fmt.Errorf("There is already an %q record available for %q", recordType, getFormattedDomainName(subdomain, domain))
+	}
+
+	// create record
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  subdomain,
+		Value: ip.String(),
+		Type:  recordType,
+		Ttl:   fmt.Sprintf("%d", timeToLive),
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, createError := editor.client.CreateRecord(ctx, domain, changeRecord)
+	if createError != nil {
+		return getWriteError(ctx, createError)
+	}
+
+	return nil
+}
+
+// CreateRecord creates a record of any type with the given fields and returns its id.
+// Unlike CreateSubdomain it does not check whether a record with the same name and type exists.
+func (editor *dnsEditor) CreateRecord(ctx context.Context, domain string, fields recordFields) (string, error) {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return "", fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidRecordName(fields.Name) == false {
+		return "", fmt.Errorf("The record name is invalid: %q", fields.Name)
+	}
+
+	if isEmpty(fields.RecordType) {
+		return "", fmt.Errorf("No record type supplied")
+	}
+
+	if err := validateRecordChange(fields.RecordType, recordUpdate{Content: &fields.Content}); err != nil {
+		return "", err
+	}
+
+	if fields.TTL < 0 {
+		return "", fmt.Errorf("The TTL cannot be negative")
+	}
+
+	if fields.Priority < 0 {
+		return "", fmt.Errorf("The priority cannot be negative")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// the priority can only be set by clients which support all fields
+	if fields.Priority != 0 {
+		fieldCreator, isFieldCreator := editor.client.(dnsRecordFieldCreator)
+		if !isFieldCreator {
+			return "", fmt.Errorf("The DNS client does not support setting the priority of a record")
+		}
+
+		id, createError := fieldCreator.CreateRecordFields(ctx, domain, fields)
+		if createError != nil {
+			return "", getWriteError(ctx, createError)
+		}
+
+		return id, nil
+	}
+
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  fields.Name,
+		Value: fields.Content,
+		Type:  fields.RecordType,
+		Ttl:   fmt.Sprintf("%d", fields.TTL),
+	}
+
+	id, createError := editor.client.CreateRecord(ctx, domain, changeRecord)
+	if createError != nil {
+		return "", getWriteError(ctx, createError)
+	}
+
+	return id, nil
+}
+
+// UpdateSubdomain updates the IP address of the given domain/subdomain.
+func (editor *dnsEditor) UpdateSubdomain(ctx context.Context, domain, subdomain string, ip net.IP) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return fmt.Errorf("The subdomain name is invalid: %q", subdomain)
+	}
+
+	if ip == nil {
+		return fmt.Errorf("No ip supplied")
+	}
+
+	// get the subdomain record
+	recordType := getDNSRecordTypeByIP(ip)
+	subdomainRecord, err := editor.infoProvider.GetSubdomainRecord(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return fmt.Errorf("No address record of type %q found for %q", recordType, getFormattedDomainName(subdomain, domain))
+	}
+
+	// check if an update is necessary
+	if subdomainRecord.Content == ip.String() {
+		return fmt.Errorf("No update required. IP address did not change (%s).", subdomainRecord.Content)
+	}
+
+	// update the record
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  subdomainRecord.Name,
+		Value: ip.String(),
+		Type:  subdomainRecord.RecordType,
+		Ttl:   fmt.Sprintf("%d", subdomainRecord.Ttl),
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, updateError := editor.client.UpdateRecord(ctx, domain, fmt.Sprintf("%v", subdomainRecord.Id), changeRecord)
+	if updateError != nil {
+		return getWriteError(ctx, updateError)
+	}
+
+	return nil
+}
+
+// UpdateSubdomainRecord changes the given fields of the record with the given domain, subdomain and type.
+// Returns an error if the record already has the given values.
+func (editor *dnsEditor) UpdateSubdomainRecord(ctx context.Context, domain, subdomain, recordType string, change recordUpdate) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return fmt.Errorf("The subdomain name is invalid: %q", subdomain)
+	}
+
+	if isEmpty(recordType) {
+		return fmt.Errorf("No record type supplied")
+	}
+
+	if err := validateRecordChange(recordType, change); err != nil {
+		return err
+	}
+
+	// get the subdomain record
+	subdomainRecord, err := editor.infoProvider.GetSubdomainRecord(ctx, domain, subdomain, recordType)
+	if err != nil {
+		return fmt.Errorf("No record of type %q found for %q", recordType, getFormattedDomainName(subdomain, domain))
+	}
+
+	return editor.updateRecord(ctx, domain, subdomainRecord, change)
+}
+
+// UpdateRecordByID changes the given fields of the record with the given id.
+// Returns an error if the record already has the given values.
+func (editor *dnsEditor) UpdateRecordByID(ctx context.Context, domain, id string, change recordUpdate) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isEmpty(id) {
+		return fmt.Errorf("No record id supplied")
+	}
+
+	// get the record
+	record, err := editor.infoProvider.GetRecord(ctx, domain, id)
+	if err != nil {
+		return err
+	}
+
+	if err := validateRecordChange(record.RecordType, change); err != nil {
+		return err
+	}
+
+	return editor.updateRecord(ctx, domain, record, change)
+}
+
+// updateRecord applies the given change to the given record.
+func (editor *dnsEditor) updateRecord(ctx context.Context, domain string, record dnsimple.Record, change recordUpdate) error {
+
+	// apply the changes
+	fields := recordFields{
+		Name:       record.Name,
+		Content:    record.Content,
+		RecordType: record.RecordType,
+		TTL:        record.Ttl,
+		Priority:   record.Prio,
+	}
+
+	if change.Name != nil {
+		fields.Name = *change.Name
+	}
+
+	if change.Content != nil {
+		fields.Content = *change.Content
+	}
+
+	if change.TTL != nil {
+		fields.TTL = int64(*change.TTL)
+	}
+
+	if change.Priority != nil {
+		fields.Priority = int64(*change.Priority)
+	}
+
+	// check if an update is necessary
+	if fields.Name == record.Name && fields.Content == record.Content && fields.TTL == record.Ttl && fields.Priority == record.Prio {
+		return fmt.Errorf("No update required. The record of type %q for %q already has the given values.", record.RecordType, getFormattedDomainName(record.Name, domain))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	recordID := fmt.Sprintf("%d", record.Id)
+
+	// the priority can only be changed by clients which support all fields
+	if fields.Priority != record.Prio {
+		fieldUpdater, isFieldUpdater := editor.client.(dnsRecordFieldUpdater)
+		if !isFieldUpdater {
+			return fmt.Errorf("The DNS client does not support changing the priority of a record")
+		}
+
+		if _, updateError := fieldUpdater.UpdateRecordFields(ctx, domain, recordID, fields); updateError != nil {
+			return getWriteError(ctx, updateError)
+		}
+
+		return nil
+	}
+
+	changeRecord := &dnsimple.ChangeRecord{
+		Name:  fields.Name,
+		Value: fields.Content,
+		Type:  fields.RecordType,
+		Ttl:   fmt.Sprintf("%d", fields.TTL),
+	}
+
+	if _, updateError := editor.client.UpdateRecord(ctx, domain, recordID, changeRecord); updateError != nil {
+		return getWriteError(ctx, updateError)
+	}
+
+	return nil
+}
+
+// validateRecordChange returns an error if the given change
+// cannot be applied to a record of the given type.
+func validateRecordChange(recordType string, change recordUpdate) error {
+	if change.Name != nil && (isEmpty(*change.Name) || isValidSubdomain(*change.Name) == false) {
+		return fmt.Errorf("The new subdomain name is invalid: %q", *change.Name)
+	}
+
+	if change.Content != nil && isEmpty(*change.Content) {
+		return fmt.Errorf("The new content cannot be empty")
+	}
+
+	if change.Content != nil && (recordType == "A" || recordType == "AAAA") {
+		ip := net.ParseIP(*change.Content)
+		if ip == nil || getDNSRecordTypeByIP(ip) != recordType {
+			return fmt.Errorf("The content of an %q record must be an %s address: %q", recordType, getIPVersionByRecordType(recordType), *change.Content)
+		}
+	}
+
+	if change.TTL != nil && *change.TTL < 0 {
+		return fmt.Errorf("The TTL cannot be negative")
+	}
+
+	if change.Priority != nil && *change.Priority < 0 {
+		return fmt.Errorf("The priority cannot be negative")
+	}
+
+	return nil
+}
+
+// DeleteSubdomain deletes the address record of the given domain
+func (editor *dnsEditor) DeleteSubdomain(ctx context.Context, domain, subdomain string, recordType string) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return fmt.Errorf("The subdomain name is invalid: %q", subdomain)
+	}
+
+	if recordType != "AAAA" && recordType != "A" {
+		return fmt.Errorf("The given record type is invalid: %q", subdomain)
+	}
+
+	// check if the record already exists
+	subdomainRecord, subdomainError := editor.infoProvider.GetSubdomainRecord(ctx, domain, subdomain, recordType)
+	if subdomainError != nil {
+		return fmt.Errorf("No address record of type %q found for %q", recordType, getFormattedDomainName(subdomain, domain))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deleteError := editor.client.DestroyRecord(ctx, domain, fmt.Sprintf("%d", subdomainRecord.Id))
+	if deleteError != nil {
+		return getWriteError(ctx, deleteError)
+	}
+
+	return nil
+}
+
+// DeleteRecordByID deletes the record with the given id.
+func (editor *dnsEditor) DeleteRecordByID(ctx context.Context, domain, id string) error {
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isEmpty(id) {
+		return fmt.Errorf("No record id supplied")
+	}
+
+	// check if the record exists
+	if _, err := editor.infoProvider.GetRecord(ctx, domain, id); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deleteError := editor.client.DestroyRecord(ctx, domain, id)
+	if deleteError != nil {
+		return getWriteError(ctx, deleteError)
+	}
+
+	return nil
+}
+
+// SetSubdomainAddresses converges the address records of the given domain, subdomain and type
+// to the given IP addresses. Missing records are created with the given TTL before surplus
+// records are deleted. Returns the changes that were applied, even if an error occurred.
+func (editor *dnsEditor) SetSubdomainAddresses(ctx context.Context, domain, subdomain, recordType string, timeToLive int, ips []net.IP) (addressSetChanges, error) {
+
+	changes := addressSetChanges{}
+
+	// validate parameters
+	if isValidDomain(domain) == false {
+		return changes, fmt.Errorf("The domain name is invalid: %q", domain)
+	}
+
+	if isValidSubdomain(subdomain) == false {
+		return changes, fmt.Errorf("The subdomain name is invalid: %q", subdomain)
+	}
+
+	if recordType != "AAAA" && recordType != "A" {
+		return changes, fmt.Errorf("The given record type is invalid: %q", recordType)
+	}
+
+	targetAddresses := make(map[string]net.IP)
+	for _, ip := range ips {
+		if ip == nil || getDNSRecordTypeByIP(ip) != recordType {
+			return changes, fmt.Errorf("The address %q is not an %s address", ip, getIPVersionByRecordType(recordType))
+		}
+
+		targetAddresses[ip.String()] = ip
+	}
+
+	// get the current record set
+	records, err := editor.infoProvider.GetSubdomainRecords(ctx, domain, subdomain)
+	if err != nil {
+		return changes, err
+	}
+
+	currentAddresses := make(map[string]bool)
+	var surplusRecords []dnsimple.Record
+	for _, record := range records {
+		if record.RecordType != recordType {
+			continue
+		}
+
+		address := record.Content
+		if ip := net.ParseIP(record.Content); ip != nil {
+			address = ip.String()
+		}
+
+		// remove records which are not in the target set and duplicates
+		if _, isTarget := targetAddresses[address]; !isTarget || currentAddresses[address] {
+			surplusRecords = append(surplusRecords, record)
+			continue
+		}
+
+		currentAddresses[address] = true
+	}
+
+	// add the missing addresses
+	for _, ip := range ips {
+		if currentAddresses[ip.String()] {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+
+		changeRecord := &dnsimple.ChangeRecord{
+			Name:  subdomain,
+			Value: ip.String(),
+			Type:  recordType,
+			Ttl:   fmt.Sprintf("%d", timeToLive),
+		}
+
+		if _, createError := editor.client.CreateRecord(ctx, domain, changeRecord); createError != nil {
+			return changes, getWriteError(ctx, createError)
+		}
+
+		currentAddresses[ip.String()] = true
+		changes.Added = append(changes.Added, ip)
+	}
+
+	// remove the surplus records
+	for _, record := range surplusRecords {
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+
+		if deleteError := editor.client.DestroyRecord(ctx, domain, fmt.Sprintf("%d", record.Id)); deleteError != nil {
+			return changes, getWriteError(ctx, deleteError)
+		}
+
+		changes.Removed = append(changes.Removed, net.ParseIP(record.Content))
+	}
+
+	return changes, nil
+}
+
+// unconfirmedChangeError is returned if a write request was interrupted
+// after it had been sent. The change may or may not have been applied.
+type unconfirmedChangeError struct {
+	Err error
+}
+
+func (err *unconfirmedChangeError) Error() string {
+	return fmt.Sprintf("The change may or may not have been applied: %s", err.Err)
+}
+
+// Unwrap returns the error which interrupted the change.
+func (err *unconfirmedChangeError) Unwrap() error {
+	return err.Err
+}
+
+// getWriteError returns an unconfirmedChangeError if the given write error
+// was caused by the cancellation of the given context.
+func getWriteError(ctx context.Context, writeError error) error {
+	if ctx.Err() == nil {
+		return writeError
+	}
+
+	return &unconfirmedChangeError{writeError}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// DNSEditor.CreateRecord should create records of any type with the given fields.
+func Test_DNSEditor_CreateRecord_RecordIsCreated(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	fields := recordFields{Name: "_dmarc", Content: "v=DMARC1; p=none", RecordType: "TXT", TTL: 600}
+
+	// act
+	_, err := editor.CreateRecord(context.Background(), "example.com", fields)
+
+	// assert
+	if err != nil || client.calls["CreateRecord"] != 1 {
+		t.Fail()
+		t.Logf("CreateRecord should create the TXT record (error: %v, calls: %v)", err, client.calls)
+	}
+}
+
+// DNSEditor.UpdateRecordByID should update the record with the given id.
+func Test_DNSEditor_UpdateRecordByID_RecordIsUpdated(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	content := "::2"
+
+	// act
+	err := editor.UpdateRecordByID(context.Background(), "example.com", "2", recordUpdate{Content: &content})
+
+	// assert
+	if err != nil || client.calls["UpdateRecord"] != 1 {
+		t.Fail()
+		t.Logf("UpdateRecordByID should update the AAAA record (error: %v, calls: %v)", err, client.calls)
+	}
+}
+
+// DNSEditor.UpdateRecordByID should return an error and not update anything
+// if there is no record with the given id.
+func Test_DNSEditor_UpdateRecordByID_UnknownID_ErrorIsReturned(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+	content := "1.2.3.5"
+
+	// act
+	err := editor.UpdateRecordByID(context.Background(), "example.com", "99", recordUpdate{Content: &content})
+
+	// assert
+	if err == nil || client.calls["UpdateRecord"] != 0 {
+		t.Fail()
+		t.Logf("UpdateRecordByID should return an error for an unknown id (error: %v, calls: %v)", err, client.calls)
+	}
+}
+
+// DNSEditor.DeleteRecordByID should delete the record with the given id.
+func Test_DNSEditor_DeleteRecordByID_RecordIsDeleted(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+
+	// act
+	err := editor.DeleteRecordByID(context.Background(), "example.com", "3")
+
+	// assert
+	if err != nil || client.calls["DestroyRecord"] != 1 {
+		t.Fail()
+		t.Logf("DeleteRecordByID should delete the MX record (error: %v, calls: %v)", err, client.calls)
+	}
+}
+
+// DNSEditor.DeleteRecordByID should return an error and not delete anything
+// if there is no record with the given id.
+func Test_DNSEditor_DeleteRecordByID_UnknownID_ErrorIsReturned(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	editor := newDNSEditor(client, newDNSInfoProvider(client))
+
+	// act
+	err := editor.DeleteRecordByID(context.Background(), "example.com", "99")
+
+	// assert
+	if err == nil || client.calls["DestroyRecord"] != 0 {
+		t.Fail()
+		t.Logf("DeleteRecordByID should return an error for an unknown id (error: %v, calls: %v)", err, client.calls)
+	}
+}
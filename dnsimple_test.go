@@ -4,6 +4,8 @@
 
 package main
 
+import "context"
+
 type testAction struct {
 	name           string
 	description    string
@@ -23,6 +25,6 @@ func (action testAction) Usage() string {
 	return action.usage
 }
 
-func (action testAction) Execute(arguments []string) (message, error) {
+func (action testAction) Execute(ctx context.Context, arguments []string) (message, error) {
 	return successMessage{action.executeMessage}, nil
 }
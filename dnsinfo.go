@@ -0,0 +1,171 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"strings"
+)
+
+// The dnsInfoProvider interface offer DNS info functions.
+type dnsInfoProvider interface {
+
+	// GetDomainNames returns a list of domain names.
+	// Returns an error if the domain names cannot be fetched.
+	GetDomainNames(ctx context.Context) ([]string, error)
+
+	// GetDomains returns a list of domains.
+	// Returns an error if the domains cannot be fetched.
+	GetDomains(ctx context.Context) ([]dnsimple.Domain, error)
+
+	// GetDomainRecords returns all DNS records for the given domain.
+	// Returns an error of the DNS records cannot be fetched or the
+	// given domain was not found.
+	GetDomainRecords(ctx context.Context, domain string) ([]dnsimple.Record, error)
+
+	// GetSubdomainRecord returns the DNS record for the given domain, subdomain and record type.
+	// Returns an error if no DNS record was found.
+	GetSubdomainRecord(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error)
+
+	// GetSubdomainRecords returns a list of all available DNS records for the
+	// given domain and subdomain.
+	GetSubdomainRecords(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error)
+
+	// GetRecord returns the DNS record with the given id.
+	// Returns an error if no DNS record was found.
+	GetRecord(ctx context.Context, domain, id string) (dnsimple.Record, error)
+}
+
+// newDNSInfoProvider creates a new DNS info provider instance.
+func newDNSInfoProvider(client dnsClient) dnsInfoProvider {
+	return &dnsimpleInfoProvider{client}
+}
+
+// dnsimpleInfoProvider returns DNS records from the DNSimple API.
+type dnsimpleInfoProvider struct {
+	client dnsClient
+}
+
+// GetDomainNames returns a list of all available domain names.
+func (infoProvider *dnsimpleInfoProvider) GetDomainNames(ctx context.Context) ([]string, error) {
+
+	domains, err := infoProvider.client.GetDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var domainNames []string
+	for _, domain := range domains {
+		domainNames = append(domainNames, domain.Name)
+	}
+
+	return domainNames, nil
+}
+
+// GetDomains returns a list of all domains available in the current DNSimple account.
+func (infoProvider *dnsimpleInfoProvider) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+
+	return infoProvider.client.GetDomains(ctx)
+
+}
+
+// GetDomainRecords returns all DNS records for the given domain.
+func (infoProvider *dnsimpleInfoProvider) GetDomainRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+
+	return infoProvider.getDNSRecords(ctx, domain, func(record dnsimple.Record) bool {
+		return true
+	})
+
+}
+
+// GetSubdomainRecord return the subdomain record that matches the given name and record type.
+// If no matching subdomain was found or an error occurred while fetching the available records
+// an error will be returned.
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecord(ctx context.Context, domain, subdomain, recordType string) (dnsimple.Record, error) {
+
+	// get all records that have matching subdomain name and record type
+	records, err := infoProvider.findDNSRecords(ctx, domain, subdomain, recordType, func(record dnsimple.Record) bool {
+		return strings.EqualFold(record.Name, subdomain) && record.RecordType == recordType
+	})
+
+	// error while fetching DNS records
+	if err != nil {
+		return dnsimple.Record{}, err
+	}
+
+	// no records found
+	if len(records) == 0 {
+		return dnsimple.Record{}, fmt.Errorf("No record found for %s.%s", subdomain, domain)
+	}
+
+	// return the first record found
+	return records[0], nil
+}
+
+// GetSubdomainRecords returns all DNS records for the given subdomain.
+func (infoProvider *dnsimpleInfoProvider) GetSubdomainRecords(ctx context.Context, domain, subdomain string) ([]dnsimple.Record, error) {
+
+	return infoProvider.findDNSRecords(ctx, domain, subdomain, "", func(record dnsimple.Record) bool {
+		return strings.EqualFold(record.Name, subdomain)
+	})
+
+}
+
+// GetRecord returns the DNS record of the given domain with the given id.
+func (infoProvider *dnsimpleInfoProvider) GetRecord(ctx context.Context, domain, id string) (dnsimple.Record, error) {
+
+	record, err := infoProvider.client.RetrieveRecord(ctx, domain, id)
+	if err != nil {
+		return dnsimple.Record{}, fmt.Errorf("No record with id %q found for %s: %s", id, domain, err.Error())
+	}
+
+	return record, nil
+}
+
+// findDNSRecords returns the DNS records for the given domain, name and record type
+// that pass the given filter expression. The records are filtered by the API if the
+// client supports it; otherwise all records of the domain are fetched.
+func (infoProvider *dnsimpleInfoProvider) findDNSRecords(ctx context.Context, domain, name, recordType string, includeInResult func(record dnsimple.Record) bool) ([]dnsimple.Record, error) {
+
+	finder, isFinder := infoProvider.client.(dnsRecordFinder)
+	if !isFinder {
+		return infoProvider.getDNSRecords(ctx, domain, includeInResult)
+	}
+
+	records, err := finder.FindRecords(ctx, domain, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDNSRecords(records, includeInResult), nil
+}
+
+// getDNSRecords returns all DNS records for the given domain that pass the given filter expression.
+func (infoProvider *dnsimpleInfoProvider) getDNSRecords(ctx context.Context, domain string, includeInResult func(record dnsimple.Record) bool) ([]dnsimple.Record, error) {
+
+	// get all DNS records for the given domain
+	records, err := infoProvider.client.GetRecords(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDNSRecords(records, includeInResult), nil
+}
+
+// filterDNSRecords returns the DNS records that pass the given filter expression.
+func filterDNSRecords(records []dnsimple.Record, includeInResult func(record dnsimple.Record) bool) []dnsimple.Record {
+	var filteredRecords []dnsimple.Record
+	for _, record := range records {
+		if !includeInResult(record) {
+			continue
+		}
+
+		filteredRecords = append(filteredRecords, record)
+	}
+
+	return filteredRecords
+}
@@ -0,0 +1,445 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The operations recorded in the history.
+const (
+	historyOperationCreate = "create"
+	historyOperationUpdate = "update"
+	historyOperationDelete = "delete"
+)
+
+// The outcomes of a recorded change.
+const (
+	// historyOutcomeApplied is recorded if the API confirmed the change.
+	historyOutcomeApplied = "applied"
+
+	// historyOutcomeFailed is recorded if the API rejected the change.
+	historyOutcomeFailed = "failed"
+
+	// historyOutcomeUnconfirmed is recorded if the action was interrupted
+	// before the API confirmed the change.
+	historyOutcomeUnconfirmed = "unconfirmed"
+)
+
+// historyEntry is a single change in the history log.
+type historyEntry struct {
+	ID        string         `json:"id"`
+	Time      time.Time      `json:"time"`
+	Profile   string         `json:"profile"`
+	User      string         `json:"user"`
+	Host      string         `json:"host"`
+	Action    string         `json:"action"`
+	Operation string         `json:"operation"`
+	Domain    string         `json:"domain"`
+	RecordID  string         `json:"record_id,omitempty"`
+	Before    *historyRecord `json:"before,omitempty"`
+	After     *historyRecord `json:"after,omitempty"`
+	Outcome   string         `json:"outcome"`
+	Error     string         `json:"error,omitempty"`
+	Undoes    string         `json:"undoes,omitempty"`
+}
+
+// historyRecord contains the fields of a DNS record before or after a change.
+type historyRecord struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	TTL      int64  `json:"ttl"`
+	Priority int64  `json:"priority,omitempty"`
+}
+
+// Name returns the subdomain name of the changed record.
+func (entry historyEntry) Name() string {
+	if entry.After != nil {
+		return entry.After.Name
+	}
+
+	if entry.Before != nil {
+		return entry.Before.Name
+	}
+
+	return ""
+}
+
+// newHistoryRecord returns the history representation of the given DNS record.
+func newHistoryRecord(record dnsimple.Record) *historyRecord {
+	return &historyRecord{
+		Name:     record.Name,
+		Type:     record.RecordType,
+		Content:  record.Content,
+		TTL:      record.Ttl,
+		Priority: record.Prio,
+	}
+}
+
+// newHistoryLog creates a new history log which appends the changes to the
+// given file. The profile is read when a change is recorded. Errors are
+// written to the given writer because a failing history log must not fail
+// a change which has already been applied.
+func newHistoryLog(fs afero.Fs, filePath string, profile *string, errors io.Writer) *historyLog {
+	return &historyLog{
+		fs:       fs,
+		filePath: filePath,
+		profile:  profile,
+		errors:   errors,
+		now:      time.Now,
+	}
+}
+
+// historyLog is an append-only JSONL log of all DNS changes.
+type historyLog struct {
+	fs       afero.Fs
+	filePath string
+	profile  *string
+	errors   io.Writer
+	now      func() time.Time
+}
+
+// Record appends the given change to the history log and
+// completes the id, time, profile, user and host of the entry.
+func (log *historyLog) Record(ctx context.Context, entry historyEntry) {
+	entry.ID = newHistoryID()
+	entry.Time = log.now().UTC()
+	entry.Action = getActionName(ctx)
+	entry.Undoes = getUndoneChangeID(ctx)
+	entry.User = getCurrentUserName()
+	entry.Host, _ = os.Hostname()
+	if log.profile != nil {
+		entry.Profile = *log.profile
+	}
+
+	if err := log.append(entry); err != nil && log.errors != nil {
+		fmt.Fprintf(log.errors, "Warning: Unable to write the change to the history log %q: %s\n", log.filePath, err.Error())
+	}
+}
+
+// append writes the given entry as a single line to the end of the log.
+func (log *historyLog) append(entry historyEntry) error {
+	line, marshalError := json.Marshal(entry)
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if mkdirError := log.fs.MkdirAll(filepath.Dir(log.filePath), 0700); mkdirError != nil {
+		return mkdirError
+	}
+
+	file, openError := log.fs.OpenFile(log.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if openError != nil {
+		return openError
+	}
+
+	if _, writeError := file.Write(append(line, '\n')); writeError != nil {
+		file.Close()
+		return writeError
+	}
+
+	return file.Close()
+}
+
+// Entries returns all entries of the history log in the order they were recorded.
+// Returns an empty list if the log does not exist yet.
+func (log *historyLog) Entries() ([]historyEntry, error) {
+	file, openError := log.fs.Open(log.filePath)
+	if os.IsNotExist(openError) {
+		return nil, nil
+	}
+
+	if openError != nil {
+		return nil, openError
+	}
+
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("Invalid entry in line %d of %q: %s", lineNumber, log.filePath, err.Error())
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// newHistoryID returns a new random id for a history entry (e.g. "3f2a9c1b").
+func newHistoryID() string {
+	id := make([]byte, 4)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// getCurrentUserName returns the name of the user running dee.
+func getCurrentUserName() string {
+	if currentUser, err := user.Current(); err == nil {
+		return currentUser.Username
+	}
+
+	return os.Getenv("USER")
+}
+
+// actionNameKey is the context key of the name of the running action.
+type actionNameKey struct{}
+
+// withActionName returns a copy of the given context which carries the given action name.
+func withActionName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, actionNameKey{}, name)
+}
+
+// getActionName returns the name of the running action or an empty string.
+func getActionName(ctx context.Context) string {
+	name, _ := ctx.Value(actionNameKey{}).(string)
+	return name
+}
+
+// undoneChangeKey is the context key of the id of the change which is being undone.
+type undoneChangeKey struct{}
+
+// withUndoneChangeID returns a copy of the given context which marks
+// all changes as the undo of the change with the given id.
+func withUndoneChangeID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, undoneChangeKey{}, id)
+}
+
+// getUndoneChangeID returns the id of the change which is being undone or an empty string.
+func getUndoneChangeID(ctx context.Context) string {
+	id, _ := ctx.Value(undoneChangeKey{}).(string)
+	return id
+}
+
+// recordBeforeKey is the context key of the fields of a record before it is changed.
+type recordBeforeKey struct{}
+
+// recordBefore contains the fields of the record with the given id before it is changed.
+type recordBefore struct {
+	domain string
+	id     string
+	record *historyRecord
+}
+
+// withRecordBefore returns a copy of the given context which carries the fields
+// of the given record before the change, so that they are only fetched once.
+func withRecordBefore(ctx context.Context, domain, id string, record *historyRecord) context.Context {
+	return context.WithValue(ctx, recordBeforeKey{}, recordBefore{domain, id, record})
+}
+
+// getRecordBefore returns the fields of the given record before the change
+// or nil if they are not known.
+func getRecordBefore(ctx context.Context, domain, id string) *historyRecord {
+	before, _ := ctx.Value(recordBeforeKey{}).(recordBefore)
+	if before.domain != domain || before.id != id {
+		return nil
+	}
+
+	return before.record
+}
+
+// historyClientFactory creates DNS clients which record all changes in the history log.
+type historyClientFactory struct {
+	clientFactory dnsClientFactory
+	log           *historyLog
+}
+
+// CreateClient returns a DNS client which records all changes in the history log.
+func (factory historyClientFactory) CreateClient() (dnsClient, error) {
+	client, err := factory.clientFactory.CreateClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &historyClient{dnsClientDecorator{client}, factory.log}, nil
+}
+
+// historyClient is a dnsClient which records all
+// created, updated and deleted records in the history log.
+type historyClient struct {
+	dnsClientDecorator
+	log *historyLog
+}
+
+// CreateRecord creates a new record and records the creation.
+func (c *historyClient) CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	id, err := c.client.CreateRecord(ctx, domain, opts)
+
+	ttl, _ := strconv.ParseInt(opts.Ttl, 10, 64)
+	c.record(ctx, err, historyEntry{
+		Operation: historyOperationCreate,
+		Domain:    domain,
+		RecordID:  id,
+		After:     &historyRecord{Name: opts.Name, Type: opts.Type, Content: opts.Value, TTL: ttl},
+	})
+
+	return id, err
+}
+
+// CreateRecordFields creates a new record with all fields and records the creation.
+func (c *historyClient) CreateRecordFields(ctx context.Context, domain string, fields recordFields) (string, error) {
+	fieldCreator, isFieldCreator := c.client.(dnsRecordFieldCreator)
+	if !isFieldCreator {
+		return "", fmt.Errorf("The DNS client does not support setting the priority of a record")
+	}
+
+	id, err := fieldCreator.CreateRecordFields(ctx, domain, fields)
+
+	c.record(ctx, err, historyEntry{
+		Operation: historyOperationCreate,
+		Domain:    domain,
+		RecordID:  id,
+		After: &historyRecord{
+			Name:     fields.Name,
+			Type:     fields.RecordType,
+			Content:  fields.Content,
+			TTL:      fields.TTL,
+			Priority: fields.Priority,
+		},
+	})
+
+	return id, err
+}
+
+// UpdateRecord updates the given record and records the fields before and after the update.
+func (c *historyClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	before := c.getRecord(ctx, domain, id)
+
+	result, err := c.client.UpdateRecord(ctx, domain, id, opts)
+
+	c.record(ctx, err, historyEntry{
+		Operation: historyOperationUpdate,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    before,
+		After:     getChangedRecord(before, opts),
+	})
+
+	return result, err
+}
+
+// UpdateRecordFields updates all fields of the given record and
+// records the fields before and after the update.
+func (c *historyClient) UpdateRecordFields(ctx context.Context, domain string, id string, fields recordFields) (string, error) {
+	fieldUpdater, isFieldUpdater := c.client.(dnsRecordFieldUpdater)
+	if !isFieldUpdater {
+		return "", fmt.Errorf("The DNS client does not support changing the priority of a record")
+	}
+
+	before := c.getRecord(ctx, domain, id)
+
+	result, err := fieldUpdater.UpdateRecordFields(ctx, domain, id, fields)
+
+	c.record(ctx, err, historyEntry{
+		Operation: historyOperationUpdate,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    before,
+		After: &historyRecord{
+			Name:     fields.Name,
+			Type:     fields.RecordType,
+			Content:  fields.Content,
+			TTL:      fields.TTL,
+			Priority: fields.Priority,
+		},
+	})
+
+	return result, err
+}
+
+// DestroyRecord deletes the given record and records the deleted fields.
+func (c *historyClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	before := c.getRecord(ctx, domain, id)
+
+	err := c.client.DestroyRecord(ctx, domain, id)
+
+	c.record(ctx, err, historyEntry{
+		Operation: historyOperationDelete,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    before,
+	})
+
+	return err
+}
+
+// getRecord returns the current fields of the given record or nil
+// if the record cannot be fetched. A record which was already fetched
+// by a hook client is not fetched again.
+func (c *historyClient) getRecord(ctx context.Context, domain, id string) *historyRecord {
+	if before := getRecordBefore(ctx, domain, id); before != nil {
+		return before
+	}
+
+	record, err := c.client.RetrieveRecord(ctx, domain, id)
+	if err != nil {
+		return nil
+	}
+
+	return newHistoryRecord(record)
+}
+
+// getChangedRecord returns the fields of the given record after the given
+// change or nil if the fields before the change are unknown.
+func getChangedRecord(before *historyRecord, opts *dnsimple.ChangeRecord) *historyRecord {
+	if before == nil {
+		return nil
+	}
+
+	changed := *before
+	if opts.Name != "" {
+		changed.Name = opts.Name
+	}
+
+	if opts.Type != "" {
+		changed.Type = opts.Type
+	}
+
+	if opts.Value != "" {
+		changed.Content = opts.Value
+	}
+
+	if ttl, parseError := strconv.ParseInt(opts.Ttl, 10, 64); parseError == nil {
+		changed.TTL = ttl
+	}
+
+	return &changed
+}
+
+// record writes the given change and the outcome to the history log.
+func (c *historyClient) record(ctx context.Context, err error, entry historyEntry) {
+	entry.Outcome = historyOutcomeApplied
+	if err != nil {
+		entry.Outcome = historyOutcomeFailed
+		entry.Error = err.Error()
+		if ctx.Err() != nil {
+			entry.Outcome = historyOutcomeUnconfirmed
+		}
+	}
+
+	c.log.Record(ctx, entry)
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"testing"
+	"time"
+)
+
+// newTestHistoryLog returns a history log in an in-memory file system
+// which records all entries at the given time.
+func newTestHistoryLog(now time.Time) *historyLog {
+	profile := "default"
+	log := newHistoryLog(afero.NewMemMapFs(), "/home/user/.dee/history.log", &profile, nil)
+	log.now = func() time.Time {
+		return now
+	}
+
+	return log
+}
+
+// failingDNSClient is a testDNSClient whose changes are rejected by the API.
+type failingDNSClient struct {
+	*testDNSClient
+}
+
+func (client failingDNSClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	return fmt.Errorf("Record is locked")
+}
+
+// historyClient.UpdateRecord should record the record before and after the update.
+func Test_historyClient_UpdateRecord_BeforeAndAfterAreRecorded(t *testing.T) {
+	// arrange
+	log := newTestHistoryLog(time.Now())
+	client := historyClient{dnsClientDecorator{newTestDNSClient(testZone)}, log}
+
+	// act
+	client.UpdateRecord(withActionName(context.Background(), "update"), "example.com", "1", &dnsimple.ChangeRecord{Value: "5.6.7.8"})
+
+	// assert
+	entries, err := log.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fail()
+		t.Logf("The update should be recorded once (entries: %v, error: %v)", entries, err)
+		return
+	}
+
+	entry := entries[0]
+	if entry.Action != "update" || entry.Operation != historyOperationUpdate || entry.Outcome != historyOutcomeApplied || entry.Profile != "default" || entry.ID == "" {
+		t.Fail()
+		t.Logf("The entry %#v does not describe the applied update", entry)
+	}
+
+	if entry.Before == nil || entry.Before.Content != "1.2.3.4" || entry.After == nil || entry.After.Content != "5.6.7.8" || entry.After.Name != "www" {
+		t.Fail()
+		t.Logf("The entry should contain the content before (1.2.3.4) and after (5.6.7.8) the update but contained %v → %v", entry.Before, entry.After)
+	}
+}
+
+// historyClient.DestroyRecord should record rejected deletes as failed.
+func Test_historyClient_DestroyRecordFails_FailedOutcomeIsRecorded(t *testing.T) {
+	// arrange
+	log := newTestHistoryLog(time.Now())
+	client := historyClient{dnsClientDecorator{failingDNSClient{newTestDNSClient(testZone)}}, log}
+
+	// act
+	err := client.DestroyRecord(context.Background(), "example.com", "3")
+
+	// assert
+	entries, _ := log.Entries()
+	if err == nil || len(entries) != 1 || entries[0].Outcome != historyOutcomeFailed || entries[0].Error != "Record is locked" || entries[0].Before == nil || entries[0].Before.Type != "MX" {
+		t.Fail()
+		t.Logf("The rejected delete should be recorded as failed (entries: %v, error: %v)", entries, err)
+	}
+}
+
+// historyClient.DestroyRecord should record changes which were interrupted as unconfirmed.
+func Test_historyClient_ContextCanceled_UnconfirmedOutcomeIsRecorded(t *testing.T) {
+	// arrange
+	log := newTestHistoryLog(time.Now())
+	client := historyClient{dnsClientDecorator{failingDNSClient{newTestDNSClient(testZone)}}, log}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	client.DestroyRecord(ctx, "example.com", "1")
+
+	// assert
+	entries, _ := log.Entries()
+	if len(entries) != 1 || entries[0].Outcome != historyOutcomeUnconfirmed {
+		t.Fail()
+		t.Logf("The interrupted delete should be recorded as unconfirmed (entries: %v)", entries)
+	}
+}
+
+// historyLog.Entries should return an empty list if nothing has been recorded yet.
+func Test_historyLog_NoLogFile_NoEntriesAreReturned(t *testing.T) {
+	// arrange
+	log := newTestHistoryLog(time.Now())
+
+	// act
+	entries, err := log.Entries()
+
+	// assert
+	if err != nil || len(entries) != 0 {
+		t.Fail()
+		t.Logf("Entries() should not return entries if the log does not exist (entries: %v, error: %v)", entries, err)
+	}
+}
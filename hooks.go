@@ -0,0 +1,450 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The names of the hook scripts.
+const (
+	hookPreChange  = "pre-change"
+	hookPostChange = "post-change"
+)
+
+// hookTimeout is the maximum duration of a single hook script.
+const hookTimeout = time.Minute
+
+// recordChange describes a record which is created, updated or deleted.
+type recordChange struct {
+	Operation string
+	Domain    string
+	RecordID  string
+	Before    *historyRecord
+	After     *historyRecord
+}
+
+// changeHook is notified before and after each record change.
+type changeHook interface {
+	// BeforeChange is called before the given change is sent to the API.
+	// The change is aborted if an error is returned.
+	BeforeChange(ctx context.Context, change recordChange) error
+
+	// AfterChange is called after the given change has been applied.
+	AfterChange(ctx context.Context, change recordChange)
+}
+
+// hookClientFactory creates DNS clients which notify the given hooks about all changes.
+type hookClientFactory struct {
+	clientFactory dnsClientFactory
+	hooks         []changeHook
+}
+
+// CreateClient returns a DNS client which notifies the hooks about all changes.
+func (factory hookClientFactory) CreateClient() (dnsClient, error) {
+	client, err := factory.clientFactory.CreateClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookClient{dnsClientDecorator{client}, factory.hooks}, nil
+}
+
+// hookClient is a dnsClient which notifies the hooks
+// before and after each created, updated or deleted record.
+type hookClient struct {
+	dnsClientDecorator
+	hooks []changeHook
+}
+
+// CreateRecord creates a new record if the hooks accept the change.
+func (c *hookClient) CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	ttl, _ := strconv.ParseInt(opts.Ttl, 10, 64)
+	change := recordChange{
+		Operation: historyOperationCreate,
+		Domain:    domain,
+		After:     &historyRecord{Name: opts.Name, Type: opts.Type, Content: opts.Value, TTL: ttl},
+	}
+
+	return c.apply(ctx, &change, func(ctx context.Context) (string, error) {
+		return c.client.CreateRecord(ctx, domain, opts)
+	})
+}
+
+// CreateRecordFields creates a new record with all fields if the hooks accept the change.
+func (c *hookClient) CreateRecordFields(ctx context.Context, domain string, fields recordFields) (string, error) {
+	fieldCreator, isFieldCreator := c.client.(dnsRecordFieldCreator)
+	if !isFieldCreator {
+		return "", fmt.Errorf("The DNS client does not support setting the priority of a record")
+	}
+
+	change := recordChange{
+		Operation: historyOperationCreate,
+		Domain:    domain,
+		After:     getFieldsRecord(fields),
+	}
+
+	return c.apply(ctx, &change, func(ctx context.Context) (string, error) {
+		return fieldCreator.CreateRecordFields(ctx, domain, fields)
+	})
+}
+
+// UpdateRecord updates the given record if the hooks accept the change.
+func (c *hookClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	before := c.getRecord(ctx, domain, id)
+	change := recordChange{
+		Operation: historyOperationUpdate,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    before,
+		After:     getChangedRecord(before, opts),
+	}
+
+	return c.apply(ctx, &change, func(ctx context.Context) (string, error) {
+		return c.client.UpdateRecord(ctx, domain, id, opts)
+	})
+}
+
+// UpdateRecordFields updates all fields of the given record if the hooks accept the change.
+func (c *hookClient) UpdateRecordFields(ctx context.Context, domain string, id string, fields recordFields) (string, error) {
+	fieldUpdater, isFieldUpdater := c.client.(dnsRecordFieldUpdater)
+	if !isFieldUpdater {
+		return "", fmt.Errorf("The DNS client does not support changing the priority of a record")
+	}
+
+	change := recordChange{
+		Operation: historyOperationUpdate,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    c.getRecord(ctx, domain, id),
+		After:     getFieldsRecord(fields),
+	}
+
+	return c.apply(ctx, &change, func(ctx context.Context) (string, error) {
+		return fieldUpdater.UpdateRecordFields(ctx, domain, id, fields)
+	})
+}
+
+// DestroyRecord deletes the given record if the hooks accept the change.
+func (c *hookClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	change := recordChange{
+		Operation: historyOperationDelete,
+		Domain:    domain,
+		RecordID:  id,
+		Before:    c.getRecord(ctx, domain, id),
+	}
+
+	_, err := c.apply(ctx, &change, func(ctx context.Context) (string, error) {
+		return "", c.client.DestroyRecord(ctx, domain, id)
+	})
+
+	return err
+}
+
+// apply notifies the hooks before the given change, applies it and
+// notifies the hooks after the change if it succeeded. The fields before
+// the change are passed on to the decorated client with the context.
+func (c *hookClient) apply(ctx context.Context, change *recordChange, applyChange func(ctx context.Context) (string, error)) (string, error) {
+	for _, hook := range c.hooks {
+		if err := hook.BeforeChange(ctx, *change); err != nil {
+			return "", err
+		}
+	}
+
+	result, err := applyChange(withRecordBefore(ctx, change.Domain, change.RecordID, change.Before))
+	if err != nil {
+		return result, err
+	}
+
+	if change.Operation == historyOperationCreate {
+		change.RecordID = result
+	}
+
+	for _, hook := range c.hooks {
+		hook.AfterChange(ctx, *change)
+	}
+
+	return result, nil
+}
+
+// getRecord returns the current fields of the given record or nil
+// if the record cannot be fetched.
+func (c *hookClient) getRecord(ctx context.Context, domain, id string) *historyRecord {
+	record, err := c.client.RetrieveRecord(ctx, domain, id)
+	if err != nil {
+		return nil
+	}
+
+	return newHistoryRecord(record)
+}
+
+// getFieldsRecord returns the history representation of the given record fields.
+func getFieldsRecord(fields recordFields) *historyRecord {
+	return &historyRecord{
+		Name:     fields.Name,
+		Type:     fields.RecordType,
+		Content:  fields.Content,
+		TTL:      fields.TTL,
+		Priority: fields.Priority,
+	}
+}
+
+// hookEvent is the JSON document which is passed to the hook scripts on stdin.
+type hookEvent struct {
+	Hook      string         `json:"hook"`
+	Action    string         `json:"action"`
+	Profile   string         `json:"profile"`
+	Operation string         `json:"operation"`
+	Domain    string         `json:"domain"`
+	RecordID  string         `json:"record_id,omitempty"`
+	Before    *historyRecord `json:"before,omitempty"`
+	After     *historyRecord `json:"after,omitempty"`
+}
+
+// newScriptHooks creates change hooks which run the executables in the hooks
+// folder of the base folder and of the selected profile. The profile is read
+// when a hook is run.
+func newScriptHooks(fs afero.Fs, baseFolder string, profile *string, stderr io.Writer) *scriptHooks {
+	return &scriptHooks{
+		fs:         fs,
+		baseFolder: baseFolder,
+		profile:    profile,
+		stderr:     stderr,
+		run:        runHookScript,
+	}
+}
+
+// scriptHooks runs the pre-change and post-change hook scripts. A hook is
+// either an executable file named after the hook (e.g. hooks/pre-change) or a
+// folder with that name whose executables are run in alphabetical order.
+type scriptHooks struct {
+	fs         afero.Fs
+	baseFolder string
+	profile    *string
+	stderr     io.Writer
+	run        func(ctx context.Context, filePath string, env []string, stdin []byte, output io.Writer) error
+}
+
+// BeforeChange runs the pre-change hooks. Returns an error if one of them fails.
+func (hooks *scriptHooks) BeforeChange(ctx context.Context, change recordChange) error {
+	for _, filePath := range hooks.findHooks(hookPreChange) {
+		if err := hooks.runHook(ctx, hookPreChange, filePath, change); err != nil {
+			return fmt.Errorf("The pre-change hook %q failed: %s. The change of %s was not applied", filePath, err.Error(), describeRecordChange(change))
+		}
+	}
+
+	return nil
+}
+
+// AfterChange runs the post-change hooks. Failures are only reported because
+// the change has already been applied.
+func (hooks *scriptHooks) AfterChange(ctx context.Context, change recordChange) {
+	for _, filePath := range hooks.findHooks(hookPostChange) {
+		if err := hooks.runHook(ctx, hookPostChange, filePath, change); err != nil {
+			hooks.printf("Warning: The post-change hook %q failed: %s\n", filePath, err.Error())
+		}
+	}
+}
+
+// runHook runs the given hook script with the details of the given change
+// in environment variables and as JSON on stdin.
+func (hooks *scriptHooks) runHook(ctx context.Context, hook, filePath string, change recordChange) error {
+	event := hookEvent{
+		Hook:      hook,
+		Action:    getActionName(ctx),
+		Profile:   hooks.getProfile(),
+		Operation: change.Operation,
+		Domain:    change.Domain,
+		RecordID:  change.RecordID,
+		Before:    change.Before,
+		After:     change.After,
+	}
+
+	stdin, marshalError := json.Marshal(event)
+	if marshalError != nil {
+		return marshalError
+	}
+
+	hookCtx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	return hooks.run(hookCtx, filePath, getHookEnvironment(event), stdin, hooks.stderr)
+}
+
+// findHooks returns the paths of the executables of the given hook. The
+// hooks of the base folder are run before the hooks of the selected profile.
+func (hooks *scriptHooks) findHooks(hook string) []string {
+	var filePaths []string
+	for _, folder := range getHookFolders(hooks.baseFolder, hooks.getProfile()) {
+		hookPath := filepath.Join(folder, hook)
+		info, statError := hooks.fs.Stat(hookPath)
+		if statError != nil {
+			continue
+		}
+
+		if !info.IsDir() {
+			filePaths = append(filePaths, hooks.filterExecutables(folder, []os.FileInfo{info})...)
+			continue
+		}
+
+		entries, readError := afero.ReadDir(hooks.fs, hookPath)
+		if readError != nil {
+			hooks.printf("Warning: Unable to read the hooks in %q: %s\n", hookPath, readError.Error())
+			continue
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+
+		filePaths = append(filePaths, hooks.filterExecutables(hookPath, entries)...)
+	}
+
+	return filePaths
+}
+
+// filterExecutables returns the paths of the given files of the given folder
+// which are executable. Hidden files and folders are ignored, other files
+// which are not executable are reported.
+func (hooks *scriptHooks) filterExecutables(folder string, files []os.FileInfo) []string {
+	var executables []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		filePath := filepath.Join(folder, file.Name())
+		if !isExecutable(file) {
+			hooks.printf("Warning: Ignoring the hook %q because it is not executable\n", filePath)
+			continue
+		}
+
+		executables = append(executables, filePath)
+	}
+
+	return executables
+}
+
+// getProfile returns the name of the selected profile.
+func (hooks *scriptHooks) getProfile() string {
+	if hooks.profile == nil || *hooks.profile == "" {
+		return defaultProfile
+	}
+
+	return *hooks.profile
+}
+
+// printf writes the given message to stderr (if available).
+func (hooks *scriptHooks) printf(format string, args ...interface{}) {
+	if hooks.stderr == nil {
+		return
+	}
+
+	fmt.Fprintf(hooks.stderr, format, args...)
+}
+
+// getHookFolders returns the folders which contain the hooks of the given profile.
+// The hooks of the default profile are stored in the base folder, the hooks of all
+// other profiles in addition in a sub folder of the "profiles" folder.
+func getHookFolders(baseFolder, profile string) []string {
+	folders := []string{filepath.Join(baseFolder, "hooks")}
+	if profile != "" && profile != defaultProfile {
+		folders = append(folders, filepath.Join(baseFolder, "profiles", profile, "hooks"))
+	}
+
+	return folders
+}
+
+// getHookEnvironment returns the environment variables with the details of
+// the given event (e.g. DEE_OPERATION=update, DEE_FQDN=www.example.com).
+// The DEE_OLD_* variables contain the fields of the record before an update
+// or delete, the other record variables the fields after a create or update.
+func getHookEnvironment(event hookEvent) []string {
+	env := []string{
+		"DEE_HOOK=" + event.Hook,
+		"DEE_ACTION=" + event.Action,
+		"DEE_PROFILE=" + event.Profile,
+		"DEE_OPERATION=" + event.Operation,
+		"DEE_DOMAIN=" + event.Domain,
+		"DEE_RECORD_ID=" + event.RecordID,
+	}
+
+	if record := getChangedRecordFields(recordChange{Before: event.Before, After: event.After}); record != nil {
+		env = append(env,
+			"DEE_NAME="+record.Name,
+			"DEE_FQDN="+getFullyQualifiedName(record.Name, event.Domain),
+			"DEE_TYPE="+record.Type,
+		)
+	}
+
+	if event.After != nil {
+		env = append(env,
+			"DEE_CONTENT="+event.After.Content,
+			fmt.Sprintf("DEE_TTL=%d", event.After.TTL),
+		)
+	}
+
+	if event.Before != nil {
+		env = append(env,
+			"DEE_OLD_CONTENT="+event.Before.Content,
+			fmt.Sprintf("DEE_OLD_TTL=%d", event.Before.TTL),
+		)
+	}
+
+	return env
+}
+
+// getChangedRecordFields returns the fields of the changed record after
+// the change or, for deleted records, before the change.
+func getChangedRecordFields(change recordChange) *historyRecord {
+	if change.After != nil {
+		return change.After
+	}
+
+	return change.Before
+}
+
+// describeRecordChange returns a short description of the given change
+// (e.g. "www.example.com (A)").
+func describeRecordChange(change recordChange) string {
+	record := getChangedRecordFields(change)
+	if record == nil {
+		return fmt.Sprintf("the record %s of %s", change.RecordID, toDisplayName(change.Domain))
+	}
+
+	return fmt.Sprintf("%s (%s)", getFormattedDomainName(record.Name, change.Domain), record.Type)
+}
+
+// isExecutable returns true if the given file can be executed.
+// All files are considered executable on Windows.
+func isExecutable(file os.FileInfo) bool {
+	return runtime.GOOS == "windows" || file.Mode()&0111 != 0
+}
+
+// runHookScript runs the given executable with the given additional environment
+// variables and stdin. The output of the executable is written to the given writer.
+func runHookScript(ctx context.Context, filePath string, env []string, stdin []byte, output io.Writer) error {
+	command := exec.CommandContext(ctx, filePath)
+	command.Env = append(os.Environ(), env...)
+	command.Stdin = bytes.NewReader(stdin)
+	command.Stdout = output
+	command.Stderr = output
+
+	return command.Run()
+}
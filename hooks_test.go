@@ -0,0 +1,187 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testHookRun is a single run of a hook script.
+type testHookRun struct {
+	filePath string
+	env      []string
+	event    hookEvent
+}
+
+// newTestScriptHooks returns script hooks which record all runs and
+// fail for the hooks in the given list.
+func newTestScriptHooks(fs afero.Fs, profile string, runs *[]testHookRun, failingHooks ...string) *scriptHooks {
+	hooks := newScriptHooks(fs, "/home/user/.dee", &profile, nil)
+	hooks.run = func(ctx context.Context, filePath string, env []string, stdin []byte, output io.Writer) error {
+		var event hookEvent
+		json.Unmarshal(stdin, &event)
+		*runs = append(*runs, testHookRun{filePath, env, event})
+
+		for _, failingHook := range failingHooks {
+			if filePath == failingHook {
+				return fmt.Errorf("exit status 1")
+			}
+		}
+
+		return nil
+	}
+
+	return hooks
+}
+
+// writeTestHook writes the given file with the given mode and creates its folder.
+func writeTestHook(fs afero.Fs, filePath string, content []byte, mode os.FileMode) {
+	fs.MkdirAll(filepath.Dir(filePath), 0755)
+	afero.WriteFile(fs, filePath, content, mode)
+	fs.Chmod(filePath, mode)
+}
+
+// scriptHooks.findHooks should return the executable hooks of the base folder
+// and of the selected profile; the files of a hook folder in alphabetical order.
+func Test_scriptHooks_findHooks(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	writeTestHook(fs, "/home/user/.dee/hooks/pre-change", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/profiles/work/hooks/pre-change/20-firewall", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/profiles/work/hooks/pre-change/10-cmdb", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/profiles/work/hooks/pre-change/.hidden", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/profiles/work/hooks/pre-change/README", []byte("text"), 0644)
+	writeTestHook(fs, "/home/user/.dee/profiles/other/hooks/pre-change", []byte("#!/bin/sh"), 0755)
+
+	hooks := newTestScriptHooks(fs, "work", nil)
+
+	// act
+	result := hooks.findHooks(hookPreChange)
+
+	// assert
+	expected := "/home/user/.dee/hooks/pre-change, /home/user/.dee/profiles/work/hooks/pre-change/10-cmdb, /home/user/.dee/profiles/work/hooks/pre-change/20-firewall"
+	if strings.Join(result, ", ") != expected {
+		t.Fail()
+		t.Logf("findHooks(%q) returned %q but should have returned %q", hookPreChange, strings.Join(result, ", "), expected)
+	}
+}
+
+// hookClient.UpdateRecord should pass the change to the hooks and update the record.
+func Test_hookClient_UpdateRecord_HooksAreRun(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	writeTestHook(fs, "/home/user/.dee/hooks/pre-change", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/hooks/post-change", []byte("#!/bin/sh"), 0755)
+
+	var runs []testHookRun
+	hooks := newTestScriptHooks(fs, defaultProfile, &runs)
+
+	client := newTestDNSClient([]dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600}})
+	hookClient := &hookClient{dnsClientDecorator{client}, []changeHook{hooks}}
+	ctx := withActionName(context.Background(), actionNameCreateOrUpdate)
+
+	// act
+	_, err := hookClient.UpdateRecord(ctx, "example.com", "1", &dnsimple.ChangeRecord{Value: "5.6.7.8"})
+
+	// assert
+	if err != nil || client.calls["UpdateRecord"] != 1 || len(runs) != 2 {
+		t.Fail()
+		t.Logf("UpdateRecord should run both hooks and update the record (error: %v, updates: %d, hook runs: %d)", err, client.calls["UpdateRecord"], len(runs))
+		return
+	}
+
+	if runs[0].event.Hook != hookPreChange || runs[1].event.Hook != hookPostChange {
+		t.Fail()
+		t.Logf("The pre-change hook should run before the post-change hook (runs: %v)", runs)
+	}
+
+	event := runs[1].event
+	if event.Action != actionNameCreateOrUpdate || event.Operation != historyOperationUpdate || event.Before.Content != "1.2.3.4" || event.After.Content != "5.6.7.8" {
+		t.Fail()
+		t.Logf("The hook received the wrong event: %+v", event)
+	}
+
+	env := strings.Join(runs[1].env, " ")
+	for _, variable := range []string{"DEE_HOOK=post-change", "DEE_OPERATION=update", "DEE_FQDN=www.example.com", "DEE_TYPE=A", "DEE_CONTENT=5.6.7.8", "DEE_OLD_CONTENT=1.2.3.4", "DEE_TTL=600"} {
+		if !strings.Contains(env, variable) {
+			t.Fail()
+			t.Logf("The environment of the hook should contain %q: %s", variable, env)
+		}
+	}
+}
+
+// hookClient.UpdateRecord should fetch the record only once if it decorates a history client.
+func Test_hookClient_HistoryClient_RecordIsFetchedOnce(t *testing.T) {
+	// arrange
+	client := newTestDNSClient([]dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600}})
+	log := newTestHistoryLog(time.Now())
+	historyClient := &historyClient{dnsClientDecorator{client}, log}
+	hookClient := &hookClient{dnsClientDecorator{historyClient}, nil}
+
+	// act
+	_, err := hookClient.UpdateRecord(context.Background(), "example.com", "1", &dnsimple.ChangeRecord{Value: "5.6.7.8"})
+
+	// assert
+	entries, _ := log.Entries()
+	if err != nil || client.calls["RetrieveRecord"] != 1 || len(entries) != 1 || entries[0].Before == nil || entries[0].Before.Content != "1.2.3.4" {
+		t.Fail()
+		t.Logf("UpdateRecord should fetch the record once and record it in the history (error: %v, fetched: %d, entries: %v)", err, client.calls["RetrieveRecord"], entries)
+	}
+}
+
+// hookClient.DestroyRecord should not delete the record if a pre-change hook fails.
+func Test_hookClient_PreChangeHookFails_ChangeIsAborted(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	writeTestHook(fs, "/home/user/.dee/hooks/pre-change", []byte("#!/bin/sh"), 0755)
+	writeTestHook(fs, "/home/user/.dee/hooks/post-change", []byte("#!/bin/sh"), 0755)
+
+	var runs []testHookRun
+	hooks := newTestScriptHooks(fs, defaultProfile, &runs, "/home/user/.dee/hooks/pre-change")
+
+	client := newTestDNSClient([]dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600}})
+	hookClient := &hookClient{dnsClientDecorator{client}, []changeHook{hooks}}
+
+	// act
+	err := hookClient.DestroyRecord(context.Background(), "example.com", "1")
+
+	// assert
+	if err == nil || client.calls["DestroyRecord"] != 0 || len(runs) != 1 {
+		t.Fail()
+		t.Logf("DestroyRecord should not delete the record if the pre-change hook fails (error: %v, deletes: %d, hook runs: %d)", err, client.calls["DestroyRecord"], len(runs))
+	}
+}
+
+// hookClient.CreateRecord should not fail if a post-change hook fails.
+func Test_hookClient_PostChangeHookFails_ChangeSucceeds(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	writeTestHook(fs, "/home/user/.dee/hooks/post-change", []byte("#!/bin/sh"), 0755)
+
+	var runs []testHookRun
+	hooks := newTestScriptHooks(fs, defaultProfile, &runs, "/home/user/.dee/hooks/post-change")
+
+	client := newTestDNSClient(nil)
+	hookClient := &hookClient{dnsClientDecorator{client}, []changeHook{hooks}}
+
+	// act
+	_, err := hookClient.CreateRecord(context.Background(), "example.com", &dnsimple.ChangeRecord{Name: "www", Type: "A", Value: "1.2.3.4", Ttl: "600"})
+
+	// assert
+	if err != nil || client.calls["CreateRecord"] != 1 || len(runs) != 1 {
+		t.Fail()
+		t.Logf("CreateRecord should succeed although the post-change hook failed (error: %v, creates: %d, hook runs: %d)", err, client.calls["CreateRecord"], len(runs))
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultCacheTTL defines how long cached API responses
+// without an ETag are served without asking the API.
+const defaultCacheTTL = 5 * time.Minute
+
+// cacheSettings contains the settings of the local API response cache.
+type cacheSettings struct {
+	// Folder is the folder in which the responses are stored.
+	// The cache is disabled if the folder is empty.
+	Folder string
+
+	// Enabled is true if responses can be served from the cache.
+	// Only read-only actions use the cache; all other actions
+	// only invalidate the cached responses they affect.
+	Enabled bool
+
+	// TTL defines how long responses without an ETag are served from the cache.
+	TTL time.Duration
+
+	// Refresh bypasses the cached responses.
+	Refresh bool
+
+	// Offline serves all responses from the cache without contacting the API.
+	Offline bool
+}
+
+// cacheEntry is a cached API response.
+type cacheEntry struct {
+	URL     string      `json:"url"`
+	ETag    string      `json:"etag,omitempty"`
+	Fetched time.Time   `json:"fetched"`
+	Header  http.Header `json:"header"`
+	Body    string      `json:"body"`
+}
+
+// newCacheTransport creates a new cache transport which stores the responses
+// of the given transport in the given file system.
+func newCacheTransport(fs afero.Fs, transport http.RoundTripper, baseURL string, settings cacheSettings) *cacheTransport {
+	return &cacheTransport{
+		fs:        fs,
+		transport: transport,
+		baseURL:   baseURL,
+		settings:  settings,
+		now:       time.Now,
+	}
+}
+
+// cacheTransport is a http.RoundTripper which caches GET responses on disk.
+// Cached responses are revalidated with If-None-Match if the API returned an
+// ETag and expire after the TTL of the cache otherwise.
+type cacheTransport struct {
+	fs        afero.Fs
+	transport http.RoundTripper
+	baseURL   string
+	settings  cacheSettings
+	now       func() time.Time
+}
+
+// RoundTrip returns the cached response for the given request or
+// executes the request and caches the response.
+func (t *cacheTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet {
+		return t.write(request)
+	}
+
+	if !t.settings.Enabled {
+		return t.transport.RoundTrip(request)
+	}
+
+	entry, isCached := t.load(request)
+
+	if t.settings.Offline {
+		if !isCached {
+			return nil, fmt.Errorf("%s is not available offline. Run the command without -offline to update the cache", request.URL.Path)
+		}
+
+		return entry.response(request), nil
+	}
+
+	if isCached && !t.settings.Refresh {
+		if entry.ETag == "" && t.now().Sub(entry.Fetched) < t.settings.TTL {
+			return entry.response(request), nil
+		}
+
+		if entry.ETag != "" {
+			request = request.Clone(request.Context())
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+	}
+
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+
+	// not modified: serve the cached response
+	case response.StatusCode == http.StatusNotModified && isCached:
+		response.Body.Close()
+
+		entry.Fetched = t.now()
+		t.save(request, entry)
+		return entry.response(request), nil
+
+	// cache the new response
+	case response.StatusCode == http.StatusOK:
+		body, readError := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if readError != nil {
+			return nil, readError
+		}
+
+		t.save(request, cacheEntry{
+			URL:     request.URL.String(),
+			ETag:    response.Header.Get("ETag"),
+			Fetched: t.now(),
+			Header:  response.Header,
+			Body:    string(body),
+		})
+
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return response, nil
+}
+
+// write executes the given modifying request and removes
+// the cached responses of the affected domain.
+func (t *cacheTransport) write(request *http.Request) (*http.Response, error) {
+	if t.settings.Offline {
+		return nil, fmt.Errorf("Records cannot be modified offline")
+	}
+
+	response, err := t.transport.RoundTrip(request)
+
+	if folder := t.getDomainFolder(request); folder != "" {
+		t.fs.RemoveAll(folder)
+	}
+
+	return response, err
+}
+
+// load returns the cached response for the given request.
+// Returns false if there is no cached response.
+func (t *cacheTransport) load(request *http.Request) (cacheEntry, bool) {
+	data, readError := afero.ReadFile(t.fs, t.getFilePath(request))
+	if readError != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if unmarshalError := json.Unmarshal(data, &entry); unmarshalError != nil {
+		return cacheEntry{}, false
+	}
+
+	if entry.URL != request.URL.String() {
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// save stores the given response for the given request.
+// Errors are ignored because the cache is optional.
+func (t *cacheTransport) save(request *http.Request, entry cacheEntry) {
+	data, marshalError := json.MarshalIndent(entry, "", "  ")
+	if marshalError != nil {
+		return
+	}
+
+	filePath := t.getFilePath(request)
+	if mkdirError := t.fs.MkdirAll(filepath.Dir(filePath), 0700); mkdirError != nil {
+		return
+	}
+
+	afero.WriteFile(t.fs, filePath, data, 0600)
+}
+
+// getFilePath returns the cache file path for the given request
+// (e.g. "<folder>/domains/example.com/records.json").
+func (t *cacheTransport) getFilePath(request *http.Request) string {
+	segments := t.getPathSegments(request)
+	if len(segments) == 0 {
+		segments = []string{"index"}
+	}
+
+	fileName := segments[len(segments)-1]
+	if request.URL.RawQuery != "" {
+		queryHash := sha256.Sum256([]byte(request.URL.RawQuery))
+		fileName += fmt.Sprintf("-%x", queryHash[:4])
+	}
+
+	segments[len(segments)-1] = fileName + ".json"
+	return filepath.Join(append([]string{t.settings.Folder}, segments...)...)
+}
+
+// getDomainFolder returns the cache folder of the domain
+// affected by the given request or an empty string.
+func (t *cacheTransport) getDomainFolder(request *http.Request) string {
+	segments := t.getPathSegments(request)
+	if len(segments) < 2 || segments[0] != "domains" {
+		return ""
+	}
+
+	return filepath.Join(t.settings.Folder, segments[0], segments[1])
+}
+
+// unsafeFileNameCharacters matches characters which are not used in cache file names.
+var unsafeFileNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._\-]`)
+
+// getPathSegments returns the sanitized path segments of the
+// given request relative to the base URL of the API.
+func (t *cacheTransport) getPathSegments(request *http.Request) []string {
+	requestPath := request.URL.Path
+	if baseURL, err := url.Parse(t.baseURL); err == nil {
+		requestPath = strings.TrimPrefix(requestPath, strings.TrimRight(baseURL.Path, "/"))
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(requestPath, "/") {
+		segment = unsafeFileNameCharacters.ReplaceAllString(segment, "_")
+		if segment == "" || strings.Trim(segment, ".") == "" {
+			continue
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+// response returns the cached response for the given request.
+func (entry cacheEntry) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCacheServer returns a test server which counts the requests and
+// answers with 304 if the If-None-Match header matches the given ETag.
+func newTestCacheServer(etag string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if etag != "" {
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", etag)
+		}
+
+		w.Write([]byte(`[{"record":{"name":"www"}}]`))
+	}))
+
+	return server, &requests
+}
+
+// get executes a GET request for the given URL and returns the response body.
+func get(t *testing.T, client *http.Client, url string) string {
+	response, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %s", url, err)
+	}
+
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	return string(body)
+}
+
+// Responses without an ETag should be served from the cache until the TTL expires.
+func Test_cacheTransport_NoETag_ResponseIsCachedUntilTTLExpires(t *testing.T) {
+	// arrange
+	server, requests := newTestCacheServer("")
+	defer server.Close()
+
+	now := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	transport := newCacheTransport(afero.NewMemMapFs(), http.DefaultTransport, server.URL+"/v1", cacheSettings{Folder: "/cache", Enabled: true, TTL: time.Minute})
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+
+	// act
+	get(t, client, server.URL+"/v1/domains/example.com/records")
+	body := get(t, client, server.URL+"/v1/domains/example.com/records")
+	requestsWithinTTL := *requests
+
+	now = now.Add(2 * time.Minute)
+	get(t, client, server.URL+"/v1/domains/example.com/records")
+
+	// assert
+	if requestsWithinTTL != 1 || body != `[{"record":{"name":"www"}}]` {
+		t.Fail()
+		t.Logf("The second request should be served from the cache (requests: %d, body: %q)", requestsWithinTTL, body)
+	}
+
+	if *requests != 2 {
+		t.Fail()
+		t.Logf("The expired response should be fetched again (requests: %d)", *requests)
+	}
+}
+
+// Responses with an ETag should be revalidated with If-None-Match.
+func Test_cacheTransport_ETag_ResponseIsRevalidated(t *testing.T) {
+	// arrange
+	server, requests := newTestCacheServer(`"v1"`)
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	client := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache", Enabled: true, TTL: time.Hour})}
+
+	// act
+	get(t, client, server.URL+"/domains/example.com/records")
+	body := get(t, client, server.URL+"/domains/example.com/records")
+
+	// assert
+	if *requests != 2 || body != `[{"record":{"name":"www"}}]` {
+		t.Fail()
+		t.Logf("The cached response should be revalidated and served after a 304 (requests: %d, body: %q)", *requests, body)
+	}
+
+	if exists, _ := afero.Exists(fs, "/cache/domains/example.com/records.json"); !exists {
+		t.Fail()
+		t.Logf("The response should be cached per domain")
+	}
+}
+
+// Offline requests should only be served from the cache.
+func Test_cacheTransport_Offline_NoRequestIsSent(t *testing.T) {
+	// arrange
+	server, requests := newTestCacheServer("")
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	online := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache", Enabled: true})}
+	offline := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache", Enabled: true, Offline: true})}
+	get(t, online, server.URL+"/domains")
+
+	// act
+	body := get(t, offline, server.URL+"/domains")
+	_, uncachedError := offline.Get(server.URL + "/domains/example.com/records")
+
+	// assert
+	if *requests != 1 || body == "" {
+		t.Fail()
+		t.Logf("The offline request should be served from the cache (requests: %d)", *requests)
+	}
+
+	if uncachedError == nil {
+		t.Fail()
+		t.Logf("An offline request for an uncached resource should fail")
+	}
+}
+
+// Refresh should bypass the cache.
+func Test_cacheTransport_Refresh_RequestIsSent(t *testing.T) {
+	// arrange
+	server, requests := newTestCacheServer("")
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	client := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache", Enabled: true, TTL: time.Hour, Refresh: true})}
+
+	// act
+	get(t, client, server.URL+"/domains")
+	get(t, client, server.URL+"/domains")
+
+	// assert
+	if *requests != 2 {
+		t.Fail()
+		t.Logf("All requests should be sent if the cache is refreshed (requests: %d)", *requests)
+	}
+}
+
+// Modifying requests should remove the cached responses of the domain.
+func Test_cacheTransport_RecordModified_DomainCacheIsRemoved(t *testing.T) {
+	// arrange
+	server, _ := newTestCacheServer("")
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	list := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache", Enabled: true, TTL: time.Hour})}
+	update := &http.Client{Transport: newCacheTransport(fs, http.DefaultTransport, server.URL, cacheSettings{Folder: "/cache"})}
+	get(t, list, server.URL+"/domains/example.com/records")
+
+	// act
+	request, _ := http.NewRequest(http.MethodPut, server.URL+"/domains/example.com/records/1", nil)
+	update.Do(request)
+
+	// assert
+	if exists, _ := afero.Exists(fs, "/cache/domains/example.com/records.json"); exists {
+		t.Fail()
+		t.Logf("The cached records should be removed after a record of the domain was modified")
+	}
+}
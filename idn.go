@@ -0,0 +1,80 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"strings"
+	"unicode/utf8"
+)
+
+// The supported label formats for displaying internationalized domain names.
+const (
+	// labelsUnicode displays internationalized domain names
+	// with Unicode labels (U-labels, e.g. "münchen.de").
+	labelsUnicode = "unicode"
+
+	// labelsASCII displays internationalized domain names
+	// with ASCII labels (A-labels, e.g. "xn--mnchen-3ya.de").
+	labelsASCII = "ascii"
+)
+
+// idnaProfile converts domain names between Unicode and punycode (IDNA 2008).
+// Unlike idna.Lookup it accepts the underscores and wildcards which are
+// common in DNS record names (e.g. "_dmarc" or "*").
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.BidiRule())
+
+// validateLabels returns an error if the given label format is not supported.
+func validateLabels(value string) error {
+	if value != labelsUnicode && value != labelsASCII {
+		return fmt.Errorf("Unsupported label format %q (supported: %s, %s)", value, labelsUnicode, labelsASCII)
+	}
+
+	return nil
+}
+
+// toASCIIName converts the given domain name to punycode
+// (e.g. "www.münchen.de" → "www.xn--mnchen-3ya.de").
+// ASCII names are returned unchanged.
+func toASCIIName(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+
+	asciiName, err := idnaProfile.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("Invalid internationalized domain name %q: %s", name, err.Error())
+	}
+
+	return asciiName, nil
+}
+
+// toDisplayName returns the given domain name with Unicode labels
+// unless ASCII labels were requested with the -labels option.
+// Names which cannot be converted are returned unchanged.
+func toDisplayName(name string) string {
+	if options.Labels == labelsASCII || !strings.Contains(name, "xn--") {
+		return name
+	}
+
+	unicodeName, err := idnaProfile.ToUnicode(name)
+	if err != nil {
+		return name
+	}
+
+	return unicodeName
+}
+
+// isASCII returns true if the given text only contains ASCII characters.
+func isASCII(text string) bool {
+	for index := 0; index < len(text); index++ {
+		if text[index] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// toASCIIName should convert Unicode labels to punycode and keep ASCII names unchanged.
+func Test_toASCIIName_ValidNames_PunycodeIsReturned(t *testing.T) {
+	// arrange
+	inputs := map[string]string{
+		"münchen.de":         "xn--mnchen-3ya.de",
+		"www.müller.example": "www.xn--mller-kva.example",
+		"_dmarc.bücher.de":   "_dmarc.xn--bcher-kva.de",
+		"www.example.com":    "www.example.com",
+		"xn--mnchen-3ya.de":  "xn--mnchen-3ya.de",
+	}
+
+	for name, expected := range inputs {
+		// act
+		result, err := toASCIIName(name)
+
+		// assert
+		if err != nil || result != expected {
+			t.Fail()
+			t.Logf("toASCIIName(%q) should return %q but returned %q (error: %v)", name, expected, result, err)
+		}
+	}
+}
+
+// toDisplayName should return Unicode labels unless ASCII labels are requested.
+func Test_toDisplayName_LabelOptions(t *testing.T) {
+	// arrange
+	defer func(labels string) { options.Labels = labels }(options.Labels)
+	inputs := []struct {
+		labels   string
+		expected string
+	}{
+		{labelsUnicode, "www.münchen.de"},
+		{labelsASCII, "www.xn--mnchen-3ya.de"},
+	}
+
+	for _, input := range inputs {
+		options.Labels = input.labels
+
+		// act
+		result := toDisplayName("www.xn--mnchen-3ya.de")
+
+		// assert
+		if result != input.expected {
+			t.Fail()
+			t.Logf("toDisplayName with -labels %s should return %q but returned %q", input.labels, input.expected, result)
+		}
+	}
+}
+
+// listAction.Execute should display the unicode names of the domains.
+func Test_listAction_InternationalizedDomain_UnicodeNameIsDisplayed(t *testing.T) {
+	// arrange
+	defer func(labels string) { options.Labels = labels }(options.Labels)
+	options.Labels = labelsUnicode
+
+	infoProvider := testDNSInfoProvider{
+		getDomainsFunc: func() ([]dnsimple.Domain, error) {
+			return []dnsimple.Domain{{Name: "xn--mnchen-3ya.de", UnicodeName: "münchen.de"}}, nil
+		},
+	}
+
+	action := listAction{testInfoProviderFactory{infoProvider, nil}}
+
+	// act
+	result, err := action.Execute(context.Background(), []string{})
+
+	// assert
+	if err != nil || result.Text() != "münchen.de" {
+		t.Fail()
+		t.Logf("listAction.Execute should display the unicode name of the domain (result: %v, error: %v)", result, err)
+	}
+}
+
+// resolveName should convert internationalized names to punycode before matching the domains.
+func Test_resolveName_InternationalizedName_NameIsConvertedToPunycode(t *testing.T) {
+	// arrange
+	factory := testDomainNamesInfoProviderFactory("xn--mnchen-3ya.de")
+
+	// act
+	subdomain, domain, err := resolveName(context.Background(), factory, "Straße.München.de", "", "")
+
+	// assert
+	if err != nil || subdomain != "xn--strae-oqa" || domain != "xn--mnchen-3ya.de" {
+		t.Fail()
+		t.Logf("resolveName should return xn--strae-oqa and xn--mnchen-3ya.de but returned %q and %q (error: %v)", subdomain, domain, err)
+	}
+}
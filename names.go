@@ -0,0 +1,141 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// parseArguments parses the given arguments with the given flag set and
+// returns the positional arguments. Unlike flag.FlagSet.Parse, flags can
+// follow positional arguments (e.g. "home.example.com 1.2.3.4 -ttl 60").
+// All arguments after "--" are positional.
+func parseArguments(flagSet *flag.FlagSet, arguments []string) ([]string, error) {
+	var positionalArguments []string
+	for {
+		if parseError := flagSet.Parse(arguments); parseError != nil {
+			return nil, parseError
+		}
+
+		remaining := flagSet.Args()
+		if len(remaining) == 0 {
+			return positionalArguments, nil
+		}
+
+		consumed := len(arguments) - len(remaining)
+		if consumed > 0 && arguments[consumed-1] == "--" {
+			return append(positionalArguments, remaining...), nil
+		}
+
+		positionalArguments = append(positionalArguments, remaining[0])
+		arguments = remaining[1:]
+	}
+}
+
+// normalizeName returns the given domain name in lower case, without a
+// trailing dot and with internationalized labels converted to punycode
+// (e.g. "WWW.Example.com." → "www.example.com", "München.de" → "xn--mnchen-3ya.de").
+func normalizeName(name string) (string, error) {
+	return toASCIIName(strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), ".")))
+}
+
+// resolveName returns the normalized subdomain and domain for the given fully
+// qualified name. If no name is given the normalized subdomain and domain are
+// returned.
+// The name is split at the given domain if it ends with it and otherwise at
+// the longest domain of the account it ends with.
+func resolveName(ctx context.Context, infoProviderFactory dnsInfoProviderCreator, name, subdomain, domain string) (string, string, error) {
+	domain, domainError := normalizeName(domain)
+	if domainError != nil {
+		return "", "", domainError
+	}
+
+	if name == "" {
+		subdomain, subdomainError := normalizeName(subdomain)
+		return subdomain, domain, subdomainError
+	}
+
+	if subdomain != "" {
+		return "", "", fmt.Errorf("Please use either a fully qualified name or -subdomain")
+	}
+
+	name, nameError := normalizeName(name)
+	if nameError != nil {
+		return "", "", nameError
+	}
+
+	if domain != "" {
+		if subdomain, isMatch := splitName(name, domain); isMatch {
+			return subdomain, domain, nil
+		}
+	}
+
+	// find the longest matching domain of the account
+	infoProvider, infoProviderError := infoProviderFactory.CreateInfoProvider()
+	if infoProviderError != nil {
+		return "", "", fmt.Errorf("Cannot create DNS info provider: %s", infoProviderError.Error())
+	}
+
+	domainNames, domainNamesError := infoProvider.GetDomainNames(ctx)
+	if domainNamesError != nil {
+		return "", "", domainNamesError
+	}
+
+	longestMatch := ""
+	for _, domainName := range domainNames {
+		domainName = strings.ToLower(domainName)
+		if _, isMatch := splitName(name, domainName); isMatch && len(domainName) > len(longestMatch) {
+			longestMatch = domainName
+		}
+	}
+
+	if longestMatch == "" {
+		return "", "", fmt.Errorf("%q does not belong to any of your domains", toDisplayName(name))
+	}
+
+	subdomain, _ = splitName(name, longestMatch)
+	return subdomain, longestMatch, nil
+}
+
+// splitName returns the subdomain of the given name if the name
+// equals or ends with the given domain.
+func splitName(name, domain string) (string, bool) {
+	if name == domain {
+		return "", true
+	}
+
+	if strings.HasSuffix(name, "."+domain) {
+		return strings.TrimSuffix(name, "."+domain), true
+	}
+
+	return "", false
+}
+
+// getPositionalArguments returns the name given as the first positional
+// argument and assigns the second positional argument to the given value
+// (e.g. "home.example.com 1.2.3.4"). Returns an error if the value was
+// also given as a flag or if there are more than two positional arguments.
+func getPositionalArguments(positionalArguments []string, value *string) (string, error) {
+	if len(positionalArguments) > 2 {
+		return "", fmt.Errorf("Too many arguments: %s", strings.Join(positionalArguments[2:], " "))
+	}
+
+	if len(positionalArguments) == 0 {
+		return "", nil
+	}
+
+	if len(positionalArguments) == 2 {
+		if *value != "" {
+			return "", fmt.Errorf("%q was given twice: as an argument and as a flag", positionalArguments[1])
+		}
+
+		*value = positionalArguments[1]
+	}
+
+	return positionalArguments[0], nil
+}
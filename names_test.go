@@ -0,0 +1,126 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"strings"
+	"testing"
+)
+
+// testDomainNamesInfoProviderFactory returns an info provider factory for the given domain names.
+func testDomainNamesInfoProviderFactory(domainNames ...string) testInfoProviderFactory {
+	infoProvider := testDNSInfoProvider{
+		getDomainNamesFunc: func() ([]string, error) {
+			return domainNames, nil
+		},
+	}
+
+	return testInfoProviderFactory{infoProvider, nil}
+}
+
+// parseArguments should accept flags before, between and after positional arguments.
+func Test_parseArguments_FlagsAndPositionalArgumentsAreMixed_AllArgumentsAreParsed(t *testing.T) {
+	// arrange
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	ttl := flagSet.Int("ttl", 0, "")
+	domain := flagSet.String("domain", "", "")
+	arguments := []string{"-domain", "example.com", "www", "-ttl", "60", "1.2.3.4", "--", "-x"}
+
+	// act
+	positionalArguments, err := parseArguments(flagSet, arguments)
+
+	// assert
+	if err != nil || *ttl != 60 || *domain != "example.com" || strings.Join(positionalArguments, " ") != "www 1.2.3.4 -x" {
+		t.Fail()
+		t.Logf("parseArguments(%q) returned %q, ttl %d, domain %q (error: %v)", arguments, positionalArguments, *ttl, *domain, err)
+	}
+}
+
+// resolveName should split the name at the longest matching domain of the account.
+func Test_resolveName_FullyQualifiedName_NameIsSplitAtLongestDomain(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		name      string
+		domain    string
+		subdomain string
+		resolved  string
+	}{
+		{"home.dyn.example.com", "", "home", "dyn.example.com"},
+		{"Home.Example.COM.", "", "home", "example.com"},
+		{"dyn.example.com", "", "", "dyn.example.com"},
+		{"www.example.org", "example.org", "www", "example.org"},
+		{"www.dyn.example.com", "example.com.", "www.dyn", "example.com"},
+	}
+
+	factory := testDomainNamesInfoProviderFactory("example.com", "dyn.example.com")
+
+	for _, input := range inputs {
+		// act
+		subdomain, domain, err := resolveName(context.Background(), factory, input.name, "", input.domain)
+
+		// assert
+		if err != nil || subdomain != input.subdomain || domain != input.resolved {
+			t.Fail()
+			t.Logf("resolveName(%q, %q) should return %q and %q but returned %q and %q (error: %v)", input.name, input.domain, input.subdomain, input.resolved, subdomain, domain, err)
+		}
+	}
+}
+
+// resolveName should return an error if the name does not belong to any domain of the account.
+func Test_resolveName_UnknownDomain_ErrorIsReturned(t *testing.T) {
+	// arrange
+	factory := testDomainNamesInfoProviderFactory("example.com")
+
+	// act
+	_, _, err := resolveName(context.Background(), factory, "www.example.org", "", "")
+
+	// assert
+	if err == nil {
+		t.Fail()
+		t.Logf("resolveName should return an error because www.example.org does not belong to example.com")
+	}
+}
+
+// updateAction.Execute should accept a fully qualified name and an IP as positional arguments.
+func Test_updateAction_PositionalArguments_SubdomainIsUpdated(t *testing.T) {
+	// arrange
+	arguments := []string{"home.dyn.example.com.", "1.2.3.4"}
+	var updated string
+	editor := &testDNSEditor{
+		updateSubdomainFunc: func(domain, subdomain string, ip net.IP) error {
+			updated = subdomain + " " + domain + " " + ip.String()
+			return nil
+		},
+	}
+
+	action := updateAction{testDNSEditorFactory{editor, nil}, testDomainNamesInfoProviderFactory("example.com", "dyn.example.com"), nil, nil}
+
+	// act
+	_, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || updated != "home dyn.example.com 1.2.3.4" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should update home in dyn.example.com but updated %q (error: %v)", arguments, updated, err)
+	}
+}
+
+// GetSubdomainRecord should compare the subdomain names case-insensitively.
+func Test_DNSInfoProvider_GetSubdomainRecord_NameInDifferentCase_RecordIsFound(t *testing.T) {
+	// arrange
+	infoProvider := newDNSInfoProvider(newTestDNSClient(testZone))
+
+	// act
+	record, err := infoProvider.GetSubdomainRecord(context.Background(), "example.com", "WWW", "A")
+
+	// assert
+	if err != nil || record.Id != 1 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord should find the A record of www (error: %v, record: %#v)", err, record)
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// defaultWaitTimeout defines how long -wait waits for a change to
+// become visible on all nameservers.
+const defaultWaitTimeout = 5 * time.Minute
+
+// nameserver is a DNS server which is queried for the propagation of a change.
+type nameserver struct {
+	// Name is the host name or address of the server (e.g. "ns1.dnsimple.com").
+	Name string
+
+	// Address is the address of the server including the port (e.g. "198.241.10.53:53").
+	Address string
+
+	// Recursive is true for resolvers and false for authoritative nameservers.
+	Recursive bool
+}
+
+// propagationQuery describes the DNS record which is expected after a change.
+type propagationQuery struct {
+	// Domain is the zone of the record (e.g. "example.com").
+	Domain string
+
+	// Name is the fully qualified name of the record (e.g. "www.example.com").
+	Name string
+
+	// RecordType is the type of the record (e.g. "A").
+	RecordType string
+
+	// Value is the expected content of the record (e.g. "1.2.3.4").
+	Value string
+
+	// Resolvers contains the addresses of public resolvers which are
+	// queried in addition to the authoritative nameservers.
+	Resolvers []string
+
+	// Timeout defines how long to wait for the change.
+	Timeout time.Duration
+}
+
+// propagationWaiter waits until a DNS change is visible on the nameservers.
+type propagationWaiter interface {
+	// WaitForPropagation blocks until all nameservers return the expected
+	// value or the timeout expires. Returns a summary of the result.
+	WaitForPropagation(ctx context.Context, query propagationQuery) (string, error)
+}
+
+// newDNSPropagationWaiter creates a new propagation waiter which
+// reports the status of each nameserver to the given writer.
+func newDNSPropagationWaiter(output io.Writer) dnsPropagationWaiter {
+	return dnsPropagationWaiter{
+		output:            output,
+		lookupNameservers: lookupAuthoritativeNameservers,
+		interval:          2 * time.Second,
+		queryTimeout:      dnsQueryTimeout,
+	}
+}
+
+// waitForPropagation waits until the given query is answered by all
+// nameservers and returns the given result of the change together with
+// the summary of the propagation. The change has already been applied,
+// so the result is part of the error if the change is not yet visible.
+func waitForPropagation(ctx context.Context, waiter propagationWaiter, result string, query propagationQuery) (message, error) {
+	if waiter == nil {
+		return nil, fmt.Errorf("%s\nNo propagation waiter available", result)
+	}
+
+	summary, err := waiter.WaitForPropagation(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s\n%s", result, err.Error())
+	}
+
+	return successMessage{fmt.Sprintf("%s\n%s", result, summary)}, nil
+}
+
+// getFullyQualifiedName returns the fully qualified name of the given
+// subdomain (e.g. "www.example.com") without converting it for display.
+func getFullyQualifiedName(subdomain, domain string) string {
+	if subdomain == "" {
+		return domain
+	}
+
+	return subdomain + "." + domain
+}
+
+// dnsPropagationWaiter polls the authoritative nameservers of a zone (and
+// optionally a set of resolvers) until they return the expected value.
+type dnsPropagationWaiter struct {
+	output            io.Writer
+	lookupNameservers func(ctx context.Context, domain string) ([]nameserver, error)
+	interval          time.Duration
+	queryTimeout      time.Duration
+}
+
+// WaitForPropagation polls all nameservers until they return the expected
+// value and reports every status change.
+func (waiter dnsPropagationWaiter) WaitForPropagation(ctx context.Context, query propagationQuery) (string, error) {
+	servers, lookupError := waiter.lookupNameservers(ctx, query.Domain)
+	if lookupError != nil {
+		return "", fmt.Errorf("Unable to determine the nameservers of %s: %s", query.Domain, lookupError.Error())
+	}
+
+	for _, resolver := range query.Resolvers {
+		servers = append(servers, nameserver{Name: resolver, Address: getResolverAddress(resolver), Recursive: true})
+	}
+
+	if len(servers) == 0 {
+		return "", fmt.Errorf("No nameservers found for %s", query.Domain)
+	}
+
+	if query.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, query.Timeout)
+		defer cancel()
+	}
+
+	name := toDisplayName(query.Name)
+	waiter.printf("Waiting for %s (%s → %s) on %d nameserver(s)\n", name, query.RecordType, query.Value, len(servers))
+
+	status := make(map[nameserver]string)
+	for {
+		pending := waiter.poll(ctx, servers, query, status)
+		if len(pending) == 0 {
+			return fmt.Sprintf("%s (%s → %s) is visible on %d nameserver(s)", name, query.RecordType, query.Value, len(servers)), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			var names []string
+			for _, server := range pending {
+				names = append(names, server.Name)
+			}
+
+			return "", fmt.Errorf("%s (%s → %s) is not yet visible on %s", name, query.RecordType, query.Value, strings.Join(names, ", "))
+
+		case <-time.After(waiter.interval):
+		}
+	}
+}
+
+// poll queries all servers which do not yet return the expected value,
+// reports changed results and returns the servers which are still pending.
+func (waiter dnsPropagationWaiter) poll(ctx context.Context, servers []nameserver, query propagationQuery, status map[nameserver]string) []nameserver {
+	var pending []nameserver
+	for _, server := range servers {
+		if status[server] == "ok" {
+			continue
+		}
+
+		values, queryError := queryDNSServer(ctx, server, query.Name, query.RecordType, waiter.queryTimeout)
+
+		result := "ok"
+		switch {
+		case queryError != nil:
+			result = queryError.Error()
+			if netError, isNetError := queryError.(net.Error); isNetError && netError.Timeout() {
+				result = "timeout"
+			}
+		case !containsValue(values, query.RecordType, query.Value):
+			result = "no answer"
+			if len(values) > 0 {
+				result = strings.Join(values, ", ")
+			}
+		}
+
+		if result != "ok" {
+			pending = append(pending, server)
+		}
+
+		if result == status[server] {
+			continue
+		}
+
+		status[server] = result
+		if result == "ok" {
+			waiter.printf("  ✓ %s\n", server.Name)
+		} else {
+			waiter.printf("  … %s: %s\n", server.Name, result)
+		}
+	}
+
+	return pending
+}
+
+// printf writes the given message to the output (if available).
+func (waiter dnsPropagationWaiter) printf(format string, args ...interface{}) {
+	if waiter.output == nil {
+		return
+	}
+
+	fmt.Fprintf(waiter.output, format, args...)
+}
+
+// lookupAuthoritativeNameservers returns the authoritative nameservers of the given zone.
+func lookupAuthoritativeNameservers(ctx context.Context, domain string) ([]nameserver, error) {
+	records, err := net.DefaultResolver.LookupNS(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []nameserver
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Host, ".")
+		addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil || len(addresses) == 0 {
+			continue
+		}
+
+		servers = append(servers, nameserver{Name: host, Address: net.JoinHostPort(addresses[0], "53")})
+	}
+
+	return servers, nil
+}
+
+// getResolverAddress returns the address of the given resolver
+// with the default DNS port if no port is given (e.g. "8.8.8.8" → "8.8.8.8:53").
+func getResolverAddress(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+}
+
+// containsValue returns true if the given values contain the expected record content.
+func containsValue(values []string, recordType, expected string) bool {
+	expected = normalizeRecordValue(recordType, expected)
+	for _, value := range values {
+		if value == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeRecordValue returns the given record content in the format
+// returned by queryDNSServer (e.g. without trailing dots).
+func normalizeRecordValue(recordType, value string) string {
+	switch recordType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+
+	case "CNAME", "MX", "NS":
+		return strings.ToLower(strings.TrimSuffix(value, "."))
+	}
+
+	return value
+}
+
+// queryDNSServer queries the given server for the records of the given name
+// and type and returns their contents. The query is sent via UDP and repeated
+// via TCP if the response was truncated.
+func queryDNSServer(ctx context.Context, server nameserver, name, recordType string, timeout time.Duration) ([]string, error) {
+	questionType, isSupported := dnsQuestionTypes[recordType]
+	if !isSupported {
+		return nil, fmt.Errorf("Waiting for %s records is not supported", recordType)
+	}
+
+	questionName, nameError := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if nameError != nil {
+		return nil, nameError
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	request := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: server.Recursive},
+		Questions: []dnsmessage.Question{{Name: questionName, Type: questionType, Class: dnsmessage.ClassINET}},
+	}
+
+	packet, packError := request.Pack()
+	if packError != nil {
+		return nil, packError
+	}
+
+	response, exchangeError := exchangeDNSMessage(ctx, "udp", server.Address, packet, timeout)
+	if exchangeError == nil && response.Header.Truncated {
+		response, exchangeError = exchangeDNSMessage(ctx, "tcp", server.Address, packet, timeout)
+	}
+
+	if exchangeError != nil {
+		return nil, exchangeError
+	}
+
+	if response.Header.ID != id {
+		return nil, fmt.Errorf("Invalid response id")
+	}
+
+	if response.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("%s", strings.TrimPrefix(response.Header.RCode.String(), "RCode"))
+	}
+
+	var values []string
+	for _, answer := range response.Answers {
+		if answer.Header.Type != questionType {
+			continue
+		}
+
+		values = append(values, formatDNSResource(answer.Body))
+	}
+
+	return values, nil
+}
+
+// dnsQuestionTypes maps the supported record types to DNS question types.
+var dnsQuestionTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// formatDNSResource returns the content of the given resource
+// in the format used by the DNSimple API.
+func formatDNSResource(body dnsmessage.ResourceBody) string {
+	switch resource := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(resource.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(resource.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return normalizeRecordValue("CNAME", resource.CNAME.String())
+	case *dnsmessage.MXResource:
+		return normalizeRecordValue("MX", resource.MX.String())
+	case *dnsmessage.NSResource:
+		return normalizeRecordValue("NS", resource.NS.String())
+	case *dnsmessage.TXTResource:
+		return strings.Join(resource.TXT, "")
+	}
+
+	return body.GoString()
+}
+
+// exchangeDNSMessage sends the given DNS message to the given address and returns the response.
+func exchangeDNSMessage(ctx context.Context, network, address string, packet []byte, timeout time.Duration) (dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	connection, dialError := dialer.DialContext(ctx, network, address)
+	if dialError != nil {
+		return dnsmessage.Message{}, dialError
+	}
+
+	defer connection.Close()
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		connection.SetDeadline(deadline)
+	}
+
+	buffer := make([]byte, 65535)
+	var length int
+	if network == "tcp" {
+		// TCP messages are prefixed with their length
+		prefixed := make([]byte, 2+len(packet))
+		binary.BigEndian.PutUint16(prefixed, uint16(len(packet)))
+		copy(prefixed[2:], packet)
+		if _, writeError := connection.Write(prefixed); writeError != nil {
+			return dnsmessage.Message{}, writeError
+		}
+
+		if _, readError := io.ReadFull(connection, buffer[:2]); readError != nil {
+			return dnsmessage.Message{}, readError
+		}
+
+		length = int(binary.BigEndian.Uint16(buffer[:2]))
+		if _, readError := io.ReadFull(connection, buffer[:length]); readError != nil {
+			return dnsmessage.Message{}, readError
+		}
+	} else {
+		if _, writeError := connection.Write(packet); writeError != nil {
+			return dnsmessage.Message{}, writeError
+		}
+
+		var readError error
+		if length, readError = connection.Read(buffer); readError != nil {
+			return dnsmessage.Message{}, readError
+		}
+	}
+
+	var response dnsmessage.Message
+	if unpackError := response.Unpack(buffer[:length]); unpackError != nil {
+		return dnsmessage.Message{}, unpackError
+	}
+
+	return response, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDNSResponder is an in-process DNS server which answers all A queries
+// with the old address until it has answered the given number of queries.
+type testDNSResponder struct {
+	connection net.PacketConn
+
+	lock         sync.Mutex
+	queries      int
+	propagatedAt int
+	oldAddress   [4]byte
+	newAddress   [4]byte
+}
+
+// newTestDNSResponder starts a new DNS responder which returns the new
+// address after the given number of queries.
+func newTestDNSResponder(t *testing.T, oldAddress, newAddress string, propagatedAt int) *testDNSResponder {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start the DNS responder: %s", err)
+	}
+
+	responder := &testDNSResponder{connection: connection, propagatedAt: propagatedAt}
+	copy(responder.oldAddress[:], net.ParseIP(oldAddress).To4())
+	copy(responder.newAddress[:], net.ParseIP(newAddress).To4())
+
+	go responder.serve()
+	return responder
+}
+
+func (responder *testDNSResponder) serve() {
+	buffer := make([]byte, 512)
+	for {
+		length, address, err := responder.connection.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+
+		var request dnsmessage.Message
+		if request.Unpack(buffer[:length]) != nil || len(request.Questions) != 1 {
+			continue
+		}
+
+		responder.lock.Lock()
+		responder.queries++
+		answer := responder.oldAddress
+		if responder.queries > responder.propagatedAt {
+			answer = responder.newAddress
+		}
+		responder.lock.Unlock()
+
+		question := request.Questions[0]
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: request.Header.ID, Response: true, Authoritative: true},
+			Questions: request.Questions,
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: answer},
+			}},
+		}
+
+		packet, _ := response.Pack()
+		responder.connection.WriteTo(packet, address)
+	}
+}
+
+func (responder *testDNSResponder) Close() {
+	responder.connection.Close()
+}
+
+// newTestPropagationWaiter returns a propagation waiter which queries the given responders.
+func newTestPropagationWaiter(output *bytes.Buffer, responders ...*testDNSResponder) dnsPropagationWaiter {
+	var servers []nameserver
+	for index, responder := range responders {
+		servers = append(servers, nameserver{Name: fmt.Sprintf("ns%d.example.net", index+1), Address: responder.connection.LocalAddr().String()})
+	}
+
+	return dnsPropagationWaiter{
+		output: output,
+		lookupNameservers: func(ctx context.Context, domain string) ([]nameserver, error) {
+			return servers, nil
+		},
+		interval:     10 * time.Millisecond,
+		queryTimeout: time.Second,
+	}
+}
+
+var testPropagationQuery = propagationQuery{
+	Domain:     "example.com",
+	Name:       "www.example.com",
+	RecordType: "A",
+	Value:      "5.6.7.8",
+	Timeout:    5 * time.Second,
+}
+
+// WaitForPropagation should return as soon as all nameservers return the new value.
+func Test_dnsPropagationWaiter_ChangeIsPropagated_StatusIsReported(t *testing.T) {
+	// arrange
+	first := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 0)
+	defer first.Close()
+
+	second := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 2)
+	defer second.Close()
+
+	output := new(bytes.Buffer)
+	waiter := newTestPropagationWaiter(output, first, second)
+
+	// act
+	summary, err := waiter.WaitForPropagation(context.Background(), testPropagationQuery)
+
+	// assert
+	if err != nil || !strings.Contains(summary, "visible on 2 nameserver(s)") {
+		t.Fail()
+		t.Logf("WaitForPropagation should succeed (summary: %q, error: %v)", summary, err)
+	}
+
+	expectedLines := []string{"✓ ns1.example.net", "… ns2.example.net: 1.2.3.4", "✓ ns2.example.net"}
+	for _, line := range expectedLines {
+		if !strings.Contains(output.String(), line) {
+			t.Fail()
+			t.Logf("The status output should contain %q but was %q", line, output.String())
+		}
+	}
+}
+
+// WaitForPropagation should return an error with the pending nameservers if the timeout expires.
+func Test_dnsPropagationWaiter_ChangeIsNotPropagated_ErrorIsReturned(t *testing.T) {
+	// arrange
+	first := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 0)
+	defer first.Close()
+
+	second := newTestDNSResponder(t, "1.2.3.4", "5.6.7.8", 1000)
+	defer second.Close()
+
+	waiter := newTestPropagationWaiter(new(bytes.Buffer), first, second)
+	query := testPropagationQuery
+	query.Timeout = 100 * time.Millisecond
+
+	// act
+	_, err := waiter.WaitForPropagation(context.Background(), query)
+
+	// assert
+	if err == nil || !strings.Contains(err.Error(), "ns2.example.net") || strings.Contains(err.Error(), "ns1.example.net") {
+		t.Fail()
+		t.Logf("WaitForPropagation should return an error which names ns2.example.net (error: %v)", err)
+	}
+}
+
+// testPropagationWaiter is a propagationWaiter which records the queries.
+type testPropagationWaiter struct {
+	queries []propagationQuery
+}
+
+func (waiter *testPropagationWaiter) WaitForPropagation(ctx context.Context, query propagationQuery) (string, error) {
+	waiter.queries = append(waiter.queries, query)
+	return "visible", nil
+}
+
+// updateAction.Execute should wait for the new value if -wait is given.
+func Test_updateAction_Wait_PropagationIsAwaited(t *testing.T) {
+	// arrange
+	arguments := []string{"-domain", "example.com", "-subdomain", "www", "-ip", "5.6.7.8", "-wait", "-resolver", "8.8.8.8"}
+	editor := &testDNSEditor{
+		updateSubdomainFunc: func(domain, subdomain string, ip net.IP) error {
+			return nil
+		},
+	}
+
+	waiter := &testPropagationWaiter{}
+	action := updateAction{testDNSEditorFactory{editor, nil}, nil, nil, waiter}
+
+	// act
+	result, err := action.Execute(context.Background(), arguments)
+
+	// assert
+	if err != nil || len(waiter.queries) != 1 || !strings.Contains(result.Text(), "visible") {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) should wait for the propagation (error: %v, queries: %v)", arguments, err, waiter.queries)
+		return
+	}
+
+	query := waiter.queries[0]
+	if query.Name != "www.example.com" || query.RecordType != "A" || query.Value != "5.6.7.8" || strings.Join(query.Resolvers, ",") != "8.8.8.8" {
+		t.Fail()
+		t.Logf("updateAction.Execute(%q) waited for the wrong record: %#v", arguments, query)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// retryPolicy defines how failed API requests are retried.
+type retryPolicy struct {
+	// MaxRetries is the maximum number of retries per request.
+	// Zero disables retries.
+	MaxRetries int
+
+	// MinWait is the base delay of the exponential backoff.
+	MinWait time.Duration
+
+	// MaxWait is the maximum delay between two attempts. Requests are
+	// not retried if the API asks us to wait longer than MaxWait.
+	MaxWait time.Duration
+}
+
+// defaultRetryPolicy defines the default retry policy.
+var defaultRetryPolicy = retryPolicy{
+	MaxRetries: 3,
+	MinWait:    1 * time.Second,
+	MaxWait:    30 * time.Second,
+}
+
+// backoff returns the jittered exponential delay before the given
+// retry attempt (starting at 1). The result lies between half of
+// the exponential delay and the full exponential delay.
+func (policy retryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.MinWait
+	for i := 1; i < attempt && delay < policy.MaxWait; i++ {
+		delay *= 2
+	}
+
+	if delay > policy.MaxWait {
+		delay = policy.MaxWait
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// newRetryTransport creates a new retry transport for the given transport and policy.
+func newRetryTransport(transport http.RoundTripper, policy retryPolicy) *retryTransport {
+	return &retryTransport{
+		transport: transport,
+		policy:    policy,
+		sleep:     sleepContext,
+		now:       time.Now,
+	}
+}
+
+// retryTransport is a http.RoundTripper which retries requests that failed
+// because of transient network errors, server errors (5xx) or rate limiting
+// (429). It respects the Retry-After and X-RateLimit-* headers of the API.
+type retryTransport struct {
+	transport http.RoundTripper
+	policy    retryPolicy
+	sleep     func(ctx context.Context, delay time.Duration) error
+	now       func() time.Time
+
+	lock sync.Mutex
+
+	// rateLimitReset is the time at which the exhausted rate limit
+	// resets. Zero if there are requests left.
+	rateLimitReset time.Time
+}
+
+// RoundTrip executes the given request and retries it according to the retry policy.
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	// wait for the rate limit to reset
+	if err := t.waitForRateLimit(request.Context()); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		attemptRequest, rewindError := rewindRequest(request, attempt)
+		if rewindError != nil {
+			return nil, rewindError
+		}
+
+		response, err := t.transport.RoundTrip(attemptRequest)
+		if response != nil {
+			t.updateRateLimit(response.Header)
+		}
+
+		if attempt >= t.policy.MaxRetries {
+			return response, err
+		}
+
+		wait, retry := t.getRetryDelay(request, response, err, attempt+1)
+		if !retry {
+			return response, err
+		}
+
+		// discard the failed response
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if err := t.sleep(request.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getRetryDelay returns the delay before the given retry attempt
+// and false if the request should not be retried.
+func (t *retryTransport) getRetryDelay(request *http.Request, response *http.Response, err error, attempt int) (time.Duration, bool) {
+
+	// network errors
+	if err != nil {
+		if !isIdempotent(request.Method) && !isConnectionError(err) {
+			return 0, false
+		}
+
+		return t.policy.backoff(attempt), true
+	}
+
+	switch {
+
+	// rate limited: the request was not processed
+	case response.StatusCode == http.StatusTooManyRequests:
+		wait, known := getRetryAfter(response.Header, t.now())
+		if !known {
+			wait = t.policy.backoff(attempt)
+		}
+
+		if wait > t.policy.MaxWait {
+			return 0, false
+		}
+
+		return wait, true
+
+	// server errors
+	case response.StatusCode >= 500 && isIdempotent(request.Method):
+		if wait, known := getRetryAfter(response.Header, t.now()); known {
+			if wait > t.policy.MaxWait {
+				return 0, false
+			}
+
+			return wait, true
+		}
+
+		return t.policy.backoff(attempt), true
+
+	}
+
+	return 0, false
+}
+
+// updateRateLimit remembers when the rate limit resets
+// if there are no requests left.
+func (t *retryTransport) updateRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.rateLimitReset = time.Time{}
+	if remaining > 0 {
+		return
+	}
+
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		t.rateLimitReset = time.Unix(reset, 0)
+	}
+}
+
+// waitForRateLimit waits until the exhausted rate limit resets. Returns an
+// error if the reset is further away than the maximum wait time.
+func (t *retryTransport) waitForRateLimit(ctx context.Context) error {
+	t.lock.Lock()
+	reset := t.rateLimitReset
+	t.lock.Unlock()
+
+	if reset.IsZero() {
+		return nil
+	}
+
+	wait := reset.Sub(t.now())
+	if wait <= 0 {
+		return nil
+	}
+
+	if wait > t.policy.MaxWait {
+		return fmt.Errorf("The API rate limit is exhausted until %s", reset.Format(time.RFC3339))
+	}
+
+	return t.sleep(ctx, wait)
+}
+
+// sleepContext waits for the given delay. Returns the error
+// of the given context if it is done before the delay elapsed.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getRetryAfter returns the delay requested by the API via the Retry-After
+// header (seconds or HTTP date) or the X-RateLimit-Reset header (unix time).
+// Returns false if the response contains neither of them.
+func getRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return maxDuration(date.Sub(now), 0), true
+		}
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return maxDuration(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// isIdempotent returns true if requests with the given HTTP method can safely be repeated.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+// isConnectionError returns true if the given error occurred while establishing
+// the connection; in which case the request has not been sent.
+func isConnectionError(err error) bool {
+	for err != nil {
+		if opError, ok := err.(*net.OpError); ok {
+			return opError.Op == "dial"
+		}
+
+		unwrapper, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			return false
+		}
+
+		err = unwrapper.Unwrap()
+	}
+
+	return false
+}
+
+// rewindRequest returns the request for the given attempt. Retries get
+// a copy of the original request with a fresh copy of the request body.
+func rewindRequest(request *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || request.Body == nil || request.GetBody == nil {
+		return request, nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retryRequest := request.Clone(request.Context())
+	retryRequest.Body = body
+	return retryRequest, nil
+}
+
+// maxDuration returns the larger of the two durations.
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,270 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRetryTransport returns a retry transport which records the delays instead of sleeping.
+func newTestRetryTransport(policy retryPolicy, now time.Time) (*retryTransport, *[]time.Duration) {
+	var delays []time.Duration
+
+	transport := newRetryTransport(http.DefaultTransport, policy)
+	transport.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	transport.now = func() time.Time {
+		return now
+	}
+
+	return transport, &delays
+}
+
+// newTestServer returns a test server which responds with the given status codes in order.
+func newTestServer(statusCodes []int, header http.Header) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statusCode := statusCodes[len(statusCodes)-1]
+		if requests < len(statusCodes) {
+			statusCode = statusCodes[requests]
+		}
+
+		requests++
+
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+
+		w.WriteHeader(statusCode)
+		fmt.Fprintf(w, "{}")
+	}))
+
+	return server, &requests
+}
+
+var testRetryPolicy = retryPolicy{MaxRetries: 3, MinWait: time.Second, MaxWait: 30 * time.Second}
+
+func Test_retryTransport_ServerErrors_IdempotentRequestIsRetried(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{503, 502, 200}, nil)
+	defer server.Close()
+
+	transport, delays := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	request, _ := http.NewRequest("PUT", server.URL, strings.NewReader(`{"content": "10.0.0.1"}`))
+	response, err := client.Do(request)
+
+	// assert
+	if err != nil || response.StatusCode != 200 || *requests != 3 || len(*delays) != 2 {
+		t.Fail()
+		t.Logf("The request should have been retried twice (Requests: %d, Delays: %v, Error: %v).", *requests, *delays, err)
+	}
+}
+
+func Test_retryTransport_ServerError_PostIsNotRetried(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{503, 200}, nil)
+	defer server.Close()
+
+	transport, _ := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	response, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+
+	// assert
+	if err != nil || response.StatusCode != 503 || *requests != 1 {
+		t.Fail()
+		t.Logf("A POST request should not be retried after a server error (Requests: %d).", *requests)
+	}
+}
+
+func Test_retryTransport_TooManyRequests_RetryAfterIsRespected(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{429, 200}, http.Header{"Retry-After": []string{"7"}})
+	defer server.Close()
+
+	transport, delays := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	response, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+
+	// assert
+	if err != nil || response.StatusCode != 200 || *requests != 2 || len(*delays) != 1 || (*delays)[0] != 7*time.Second {
+		t.Fail()
+		t.Logf("The request should have been retried after 7 seconds (Requests: %d, Delays: %v).", *requests, *delays)
+	}
+}
+
+func Test_retryTransport_TooManyRequests_RetryAfterExceedsMaxWait_ErrorResponseIsReturned(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{429, 200}, http.Header{"Retry-After": []string{"3600"}})
+	defer server.Close()
+
+	transport, _ := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	response, err := client.Get(server.URL)
+
+	// assert
+	if err != nil || response.StatusCode != 429 || *requests != 1 {
+		t.Fail()
+		t.Logf("The request should not be retried if the API asks for a longer delay than the maximum (Requests: %d).", *requests)
+	}
+}
+
+func Test_retryTransport_MaxRetriesExceeded_LastResponseIsReturned(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{500}, nil)
+	defer server.Close()
+
+	transport, _ := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	response, err := client.Get(server.URL)
+
+	// assert
+	if err != nil || response.StatusCode != 500 || *requests != 4 {
+		t.Fail()
+		t.Logf("The request should have been sent four times (Requests: %d).", *requests)
+	}
+}
+
+func Test_retryTransport_NoRetries_RequestIsSentOnce(t *testing.T) {
+	// arrange
+	server, requests := newTestServer([]int{500}, nil)
+	defer server.Close()
+
+	transport, _ := newTestRetryTransport(retryPolicy{}, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	client.Get(server.URL)
+
+	// assert
+	if *requests != 1 {
+		t.Fail()
+		t.Logf("The request should not be retried if retries are disabled (Requests: %d).", *requests)
+	}
+}
+
+func Test_retryTransport_ConnectionRefused_RequestIsRetried(t *testing.T) {
+	// arrange
+	server, _ := newTestServer([]int{200}, nil)
+	serverURL := server.URL
+	server.Close()
+
+	transport, delays := newTestRetryTransport(testRetryPolicy, time.Now())
+	client := &http.Client{Transport: transport}
+
+	// act
+	_, err := client.Post(serverURL, "application/json", strings.NewReader("{}"))
+
+	// assert
+	if err == nil || len(*delays) != 3 {
+		t.Fail()
+		t.Logf("A request that could not be sent should be retried (Delays: %v).", *delays)
+	}
+}
+
+func Test_retryTransport_RateLimitExhausted_NextRequestWaitsForReset(t *testing.T) {
+	// arrange
+	now := time.Unix(1454972400, 0)
+	header := http.Header{
+		"X-Ratelimit-Remaining": []string{"0"},
+		"X-Ratelimit-Reset":     []string{fmt.Sprintf("%d", now.Add(10*time.Second).Unix())},
+	}
+	server, _ := newTestServer([]int{200}, header)
+	defer server.Close()
+
+	transport, delays := newTestRetryTransport(testRetryPolicy, now)
+	client := &http.Client{Transport: transport}
+
+	// act
+	client.Get(server.URL)
+	client.Get(server.URL)
+
+	// assert
+	if len(*delays) != 1 || (*delays)[0] != 10*time.Second {
+		t.Fail()
+		t.Logf("The second request should wait until the rate limit resets (Delays: %v).", *delays)
+	}
+}
+
+func Test_retryPolicy_backoff_DelayIsWithinBounds(t *testing.T) {
+	// arrange
+	policy := retryPolicy{MaxRetries: 10, MinWait: time.Second, MaxWait: 10 * time.Second}
+	expectedMaximums := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+
+	for index, expectedMaximum := range expectedMaximums {
+		attempt := index + 1
+
+		// act
+		delay := policy.backoff(attempt)
+
+		// assert
+		if delay < expectedMaximum/2 || delay > expectedMaximum {
+			t.Fail()
+			t.Logf("backoff(%d) returned %s but should be between %s and %s.", attempt, delay, expectedMaximum/2, expectedMaximum)
+		}
+	}
+}
+
+func Test_getRetryAfter(t *testing.T) {
+	// arrange
+	now := time.Unix(1454972400, 0)
+	inputs := []struct {
+		header         http.Header
+		expectedDelay  time.Duration
+		expectedResult bool
+	}{
+		{http.Header{}, 0, false},
+		{http.Header{"Retry-After": []string{"120"}}, 2 * time.Minute, true},
+		{http.Header{"Retry-After": []string{now.Add(time.Minute).UTC().Format(http.TimeFormat)}}, time.Minute, true},
+		{http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{"1454972430"}}, 30 * time.Second, true},
+		{http.Header{"X-Ratelimit-Remaining": []string{"5"}, "X-Ratelimit-Reset": []string{"1454972430"}}, 0, false},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		delay, result := getRetryAfter(input.header, now)
+
+		// assert
+		if delay != input.expectedDelay || result != input.expectedResult {
+			t.Fail()
+			t.Logf("getRetryAfter(%v) returned (%s, %t) but should have returned (%s, %t).", input.header, delay, result, input.expectedDelay, input.expectedResult)
+		}
+	}
+}
+
+// sleepContext should return the error of the context if it is cancelled while waiting.
+func Test_sleepContext_ContextCancelled_ErrorIsReturned(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	err := sleepContext(ctx, time.Hour)
+
+	// assert
+	if err != context.Canceled {
+		t.Fail()
+		t.Logf("sleepContext should return %q but returned %q", context.Canceled, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot file format.
+const snapshotVersion = 1
+
+// snapshotTimeFormat is the format of the timestamp in snapshot file names.
+const snapshotTimeFormat = "20060102T150405Z"
+
+// snapshot contains the records of one or more zones at a given time.
+// The records are stored as returned by the API, including their ids,
+// TTLs and priorities.
+type snapshot struct {
+	Version int            `json:"version"`
+	Time    time.Time      `json:"time"`
+	Zones   []snapshotZone `json:"zones"`
+}
+
+// snapshotZone contains all records of a domain.
+type snapshotZone struct {
+	Domain  string            `json:"domain"`
+	Records []dnsimple.Record `json:"records"`
+}
+
+// Zone returns the zone of the given domain.
+func (s snapshot) Zone(domain string) (snapshotZone, bool) {
+	for _, zone := range s.Zones {
+		if strings.EqualFold(zone.Domain, domain) {
+			return zone, true
+		}
+	}
+
+	return snapshotZone{}, false
+}
+
+// getSnapshotFileName returns the file name of the given snapshot
+// (e.g. "example.com-20160210T120000Z.json" for a single domain
+// or "20160210T120000Z.json" for multiple domains).
+func getSnapshotFileName(s snapshot) string {
+	timestamp := s.Time.UTC().Format(snapshotTimeFormat)
+	if len(s.Zones) == 1 {
+		return fmt.Sprintf("%s-%s.json", s.Zones[0].Domain, timestamp)
+	}
+
+	return fmt.Sprintf("%s.json", timestamp)
+}
+
+// writeSnapshot writes the given snapshot to a new file in the given
+// folder and returns the path of the file.
+func writeSnapshot(fs afero.Fs, folder string, s snapshot) (string, error) {
+	content, marshalError := json.MarshalIndent(s, "", "  ")
+	if marshalError != nil {
+		return "", marshalError
+	}
+
+	if mkdirError := fs.MkdirAll(folder, 0700); mkdirError != nil {
+		return "", mkdirError
+	}
+
+	filePath := filepath.Join(folder, getSnapshotFileName(s))
+	if writeError := afero.WriteFile(fs, filePath, append(content, '\n'), 0600); writeError != nil {
+		return "", writeError
+	}
+
+	return filePath, nil
+}
+
+// readSnapshot reads the snapshot from the given file.
+func readSnapshot(fs afero.Fs, filePath string) (snapshot, error) {
+	content, readError := afero.ReadFile(fs, filePath)
+	if readError != nil {
+		return snapshot{}, fmt.Errorf("Unable to read the snapshot: %s", readError.Error())
+	}
+
+	var s snapshot
+	if unmarshalError := json.Unmarshal(content, &s); unmarshalError != nil {
+		return snapshot{}, fmt.Errorf("The snapshot %q is invalid: %s", filePath, unmarshalError.Error())
+	}
+
+	if s.Version != snapshotVersion {
+		return snapshot{}, fmt.Errorf("The snapshot %q has an unsupported version: %d", filePath, s.Version)
+	}
+
+	return s, nil
+}
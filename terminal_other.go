@@ -0,0 +1,24 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// isTerminal always returns false on platforms
+// without terminal support.
+func isTerminal(file *os.File) bool {
+	return false
+}
+
+// readPassword is not supported on this platform.
+func readPassword(file *os.File) ([]byte, error) {
+	return nil, fmt.Errorf("Reading passwords is not supported on this platform")
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || linux
+// +build darwin linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns true if the given file is connected to a terminal.
+func isTerminal(file *os.File) bool {
+	if file == nil {
+		return false
+	}
+
+	var state syscall.Termios
+	return getTermios(file.Fd(), &state) == nil
+}
+
+// readPassword reads a single line from the given terminal
+// without echoing the typed characters. The terminal state is
+// restored before the process exits if the read is interrupted.
+func readPassword(file *os.File) ([]byte, error) {
+	var oldState syscall.Termios
+	if err := getTermios(file.Fd(), &oldState); err != nil {
+		return nil, err
+	}
+
+	newState := oldState
+	newState.Lflag &^= syscall.ECHO
+	newState.Lflag |= syscall.ICANON | syscall.ISIG
+	newState.Iflag |= syscall.ICRNL
+	if err := setTermios(file.Fd(), &newState); err != nil {
+		return nil, err
+	}
+
+	defer setTermios(file.Fd(), &oldState)
+
+	stop := restoreOnInterrupt(func() {
+		setTermios(file.Fd(), &oldState)
+	})
+	defer stop()
+
+	return readLine(file)
+}
+
+func getTermios(fd uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(state)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func setTermios(fd uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(state)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
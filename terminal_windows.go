@@ -0,0 +1,69 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	enableProcessedInput  = 1
+	enableLineInput       = 2
+	enableEchoInput       = 4
+	enableProcessedOutput = 1
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// isTerminal returns true if the given file is connected to a console.
+func isTerminal(file *os.File) bool {
+	if file == nil {
+		return false
+	}
+
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(file.Fd()), &mode) == nil
+}
+
+// readPassword reads a single line from the given console
+// without echoing the typed characters. The console mode is
+// restored before the process exits if the read is interrupted.
+func readPassword(file *os.File) ([]byte, error) {
+	handle := syscall.Handle(file.Fd())
+
+	var oldMode uint32
+	if err := syscall.GetConsoleMode(handle, &oldMode); err != nil {
+		return nil, err
+	}
+
+	newMode := oldMode
+	newMode &^= enableEchoInput
+	newMode |= enableProcessedInput | enableLineInput | enableProcessedOutput
+	if err := setConsoleMode(handle, newMode); err != nil {
+		return nil, err
+	}
+
+	defer setConsoleMode(handle, oldMode)
+
+	stop := restoreOnInterrupt(func() {
+		setConsoleMode(handle, oldMode)
+	})
+	defer stop()
+
+	return readLine(file)
+}
+
+func setConsoleMode(handle syscall.Handle, mode uint32) error {
+	result, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	if result == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -5,19 +5,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 )
 
 // newUsagePrinter creates a new instance of the usage printer.
-func newUsagePrinter(executableName string, version string, actions []action) usagePrinter {
-	return usagePrinter{executableName, version, actions}
+func newUsagePrinter(executableName string, version string, options *flag.FlagSet, actions []action) usagePrinter {
+	return usagePrinter{executableName, version, options, actions}
 }
 
 // usagePrinter prints usage information for the command line utility.
 type usagePrinter struct {
 	executableName string
 	version        string
+	options        *flag.FlagSet
 	actions        []action
 }
 
@@ -32,9 +34,17 @@ func (printer usagePrinter) PrintUsageInformation(output io.Writer) {
 
 	fmt.Fprintf(output, "Usage:\n")
 	fmt.Fprintf(output, "\n")
-	fmt.Fprintf(output, "  %s <action> [arguments ...]\n", printer.executableName)
+	fmt.Fprintf(output, "  %s [options] <action> [arguments ...]\n", printer.executableName)
 	fmt.Fprintf(output, "\n")
 
+	// Global options
+	if printer.options != nil {
+		fmt.Fprintf(output, "Options:\n")
+		printer.options.SetOutput(output)
+		printer.options.PrintDefaults()
+		fmt.Fprintf(output, "\n")
+	}
+
 	// List of all actions
 	fmt.Fprintf(output, "Actions:\n")
 
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"testing"
 )
 
@@ -25,7 +26,7 @@ func Test_PrintUsageInformation_ResultIsNotEmpty(t *testing.T) {
 			executeMessage: "success",
 		},
 	}
-	usagePrinter := newUsagePrinter("dee", "v0.1.0", actions)
+	usagePrinter := newUsagePrinter("dee", "v0.1.0", flag.NewFlagSet("dee", flag.ContinueOnError), actions)
 
 	// act
 	buf := new(bytes.Buffer)
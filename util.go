@@ -6,17 +6,90 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// subDomainPattern defines a pattern for valid subdomain names.
+// see:
+// - http://stackoverflow.com/questions/7930751/regexp-for-subdomain
+// - http://webmasters.stackexchange.com/questions/16996/maximum-domain-name-length
+// - https://en.wikipedia.org/wiki/Hostname#Restrictions_on_valid_host_names
+var subDomainPattern = regexp.MustCompile(`^(?:[A-Za-z0-9][A-Za-z0-9\-]{0,61}[A-Za-z0-9]|[A-Za-z0-9])$`)
+
+// recordNamePattern defines a pattern for the labels of record names which,
+// unlike subdomain names, can be wildcards (e.g. "*") or contain underscores
+// (e.g. "_dmarc").
+var recordNamePattern = regexp.MustCompile(`^(?:\*|[A-Za-z0-9_](?:[A-Za-z0-9_\-]{0,61}[A-Za-z0-9_])?)$`)
+
 // isEmpty returns true if the given text is empty or contains
 // nothing but white space characters.
 func isEmpty(text string) bool {
 	return strings.TrimSpace(text) == ""
 }
 
+// isValidDomain returns true if the given domain name is valid; otherwise false.
+// Note: This is not a real validation. It only excludes total garbage.
+func isValidDomain(domain string) bool {
+	if len(domain) > 255 {
+		// too long.
+		return false
+	}
+
+	return isEmpty(domain) == false
+}
+
+// isValidSubdomain returns true if the given subdomain name is valid; otherwise false.
+// Note: This is not a real validation. It only excludes total garbage.
+func isValidSubdomain(subdomain string) bool {
+	if subdomain == "" {
+		return true
+	}
+
+	if len(subdomain) > 253 {
+		// too long
+		return false
+	}
+
+	// each part must be valid (if there are multiple parts)
+	for _, part := range strings.Split(subdomain, ".") {
+		if len(part) > 63 {
+			// too long
+			return false
+		}
+
+		if !subDomainPattern.MatchString(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidRecordName returns true if the given record name is valid; otherwise false.
+func isValidRecordName(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	if len(name) > 253 {
+		// too long
+		return false
+	}
+
+	for _, part := range strings.Split(name, ".") {
+		if !recordNamePattern.MatchString(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // stdinHasData returns true if there is data avaialble in the given file (os.Stdin), otherwise false.
 // see: http://stackoverflow.com/questions/22744443/check-if-there-is-something-to-read-on-stdin-in-golang
 func stdinHasData(stdin *os.File) bool {
@@ -32,18 +105,77 @@ func stdinHasData(stdin *os.File) bool {
 	return false
 }
 
+// readLine reads a single line from the given reader without consuming
+// any data beyond the line break. The line break itself is not returned.
+func readLine(reader io.Reader) ([]byte, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+
+			line = append(line, buf[0])
+		}
+
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil, err
+			}
+
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(strings.TrimSuffix(string(line), "\r")), nil
+}
+
+// errNotConfirmable is returned by askForConfirmation if
+// the given input is not an interactive terminal.
+var errNotConfirmable = fmt.Errorf("Cannot ask for confirmation in non-interactive runs")
+
+// askForConfirmation asks the given yes/no question on the given terminal
+// and returns true if the user answered with yes.
+func askForConfirmation(stdin *os.File, stderr io.Writer, question string) (bool, error) {
+	if !isTerminal(stdin) {
+		return false, errNotConfirmable
+	}
+
+	if stderr != nil {
+		fmt.Fprintf(stderr, "%s [y/N]: ", question)
+	}
+
+	answer, readError := readLine(stdin)
+	if readError != nil {
+		return false, fmt.Errorf("Unable to read the confirmation: %s", readError.Error())
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(answer))) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
 // getFormattedDomainName returns the formatted domain name for
-// the given subdomain and domain names.
+// the given subdomain and domain names (see toDisplayName).
 func getFormattedDomainName(subdomain, domain string) string {
 	if domain == "" {
 		return ""
 	}
 
 	if subdomain == "" {
-		return domain
+		return toDisplayName(domain)
 	}
 
-	return fmt.Sprintf("%s.%s", subdomain, domain)
+	return toDisplayName(fmt.Sprintf("%s.%s", subdomain, domain))
 }
 
 // getDNSRecordTypeByIP returns the DNS record type for the given IP.
@@ -55,3 +187,64 @@ func getDNSRecordTypeByIP(ip net.IP) string {
 
 	return "A"
 }
+
+// getIPVersionByRecordType returns the IP version of the given address record type.
+func getIPVersionByRecordType(recordType string) string {
+	if recordType == "AAAA" {
+		return "IPv6"
+	}
+
+	return "IPv4"
+}
+
+// optionalInt is a flag value for integer options which
+// must be distinguished from options that were not given.
+type optionalInt struct {
+	Value int
+	IsSet bool
+}
+
+// String returns the value or an empty string if it is not set.
+func (value *optionalInt) String() string {
+	if value == nil || !value.IsSet {
+		return ""
+	}
+
+	return strconv.Itoa(value.Value)
+}
+
+// Set parses the given integer.
+func (value *optionalInt) Set(text string) error {
+	number, err := strconv.Atoi(text)
+	if err != nil {
+		return err
+	}
+
+	value.Value = number
+	value.IsSet = true
+	return nil
+}
+
+// stringList is a flag value for options which can be
+// given multiple times or as a comma-separated list.
+type stringList []string
+
+// String returns the comma-separated list of values.
+func (list *stringList) String() string {
+	if list == nil {
+		return ""
+	}
+
+	return strings.Join(*list, ",")
+}
+
+// Set appends the given comma-separated values to the list.
+func (list *stringList) Set(text string) error {
+	for _, value := range strings.Split(text, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			*list = append(*list, value)
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -77,3 +78,28 @@ func Test_getFormattedDomainName(t *testing.T) {
 		}
 	}
 }
+
+func Test_readLine(t *testing.T) {
+	// arrange
+	inputs := []struct {
+		input          string
+		expectedResult string
+	}{
+		{"token\n", "token"},
+		{"token\r\nsecond line\n", "token"},
+		{"token", "token"},
+		{"\nsecond line", ""},
+	}
+
+	for _, input := range inputs {
+
+		// act
+		result, _ := readLine(strings.NewReader(input.input))
+
+		// assert
+		if string(result) != input.expectedResult {
+			t.Fail()
+			t.Logf("readLine(%q) returned %q but should have returned %q.", input.input, result, input.expectedResult)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/afero"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The supported payload formats of a webhook.
+const (
+	// webhookFormatJSON sends the details of the change as JSON.
+	webhookFormatJSON = "json"
+
+	// webhookFormatSlack sends a message which can be posted to
+	// Slack or Mattermost incoming webhooks.
+	webhookFormatSlack = "slack"
+)
+
+// webhookTimeout is the maximum duration of a single webhook request.
+const webhookTimeout = 10 * time.Second
+
+// webhookDeliveryTimeout is the maximum duration of the delivery of a change
+// to all webhooks, including retries.
+const webhookDeliveryTimeout = 20 * time.Second
+
+// webhookRetryPolicy defines how failed webhook deliveries are retried.
+var webhookRetryPolicy = retryPolicy{
+	MaxRetries: 3,
+	MinWait:    1 * time.Second,
+	MaxWait:    10 * time.Second,
+}
+
+// webhook contains the settings of a webhook in the webhooks.json file.
+type webhook struct {
+	// URL is the address the changes are posted to.
+	URL string `json:"url"`
+
+	// Format is the payload format (json or slack). Default: json.
+	Format string `json:"format,omitempty"`
+
+	// Secret is the key of the HMAC-SHA256 signature of the payload.
+	// The payload is not signed if the secret is empty.
+	Secret string `json:"secret,omitempty"`
+}
+
+// webhookPayload is the JSON document which is posted to webhooks with the json format.
+type webhookPayload struct {
+	ID        string         `json:"id"`
+	Event     string         `json:"event"`
+	Time      time.Time      `json:"time"`
+	Action    string         `json:"action"`
+	Profile   string         `json:"profile"`
+	User      string         `json:"user"`
+	Host      string         `json:"host"`
+	Operation string         `json:"operation"`
+	Domain    string         `json:"domain"`
+	RecordID  string         `json:"record_id,omitempty"`
+	FQDN      string         `json:"fqdn"`
+	Before    *historyRecord `json:"before,omitempty"`
+	After     *historyRecord `json:"after,omitempty"`
+}
+
+// slackPayload is the message which is posted to webhooks with the slack format.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// newWebhookNotifier creates a change hook which posts all changes to the
+// webhooks in the webhooks.json files of the base folder and of the selected
+// profile. The profile is read when the first change is posted.
+func newWebhookNotifier(fs afero.Fs, baseFolder string, profile *string, stderr io.Writer) *webhookNotifier {
+	return &webhookNotifier{
+		fs:         fs,
+		baseFolder: baseFolder,
+		profile:    profile,
+		stderr:     stderr,
+		client:     &http.Client{Timeout: webhookTimeout},
+		policy:     webhookRetryPolicy,
+		sleep:      sleepContext,
+		now:        time.Now,
+	}
+}
+
+// webhookNotifier posts all applied changes to the configured webhooks.
+// Failed deliveries are retried and reported, but never fail a change.
+type webhookNotifier struct {
+	fs         afero.Fs
+	baseFolder string
+	profile    *string
+	stderr     io.Writer
+	client     *http.Client
+	policy     retryPolicy
+	sleep      func(ctx context.Context, delay time.Duration) error
+	now        func() time.Time
+
+	once     sync.Once
+	webhooks []webhook
+	output   sync.Mutex
+}
+
+// BeforeChange accepts all changes.
+func (notifier *webhookNotifier) BeforeChange(ctx context.Context, change recordChange) error {
+	return nil
+}
+
+// AfterChange posts the given change to all webhooks at once and waits
+// until all deliveries have finished or the delivery timeout has expired.
+func (notifier *webhookNotifier) AfterChange(ctx context.Context, change recordChange) {
+	notifier.once.Do(func() {
+		notifier.webhooks = notifier.loadWebhooks()
+	})
+
+	if len(notifier.webhooks) == 0 {
+		return
+	}
+
+	// the change has already been applied, so the webhooks are notified
+	// even if the action has been cancelled or its timeout has expired.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookDeliveryTimeout)
+	defer cancel()
+
+	payload := notifier.newPayload(ctx, change)
+
+	var deliveries sync.WaitGroup
+	for _, hook := range notifier.webhooks {
+		body, marshalError := notifier.formatPayload(hook, payload)
+		if marshalError != nil {
+			notifier.printf("Warning: Unable to create the payload for the webhook %s: %s\n", getWebhookName(hook.URL), marshalError.Error())
+			continue
+		}
+
+		deliveries.Add(1)
+		go func(hook webhook, body []byte) {
+			defer deliveries.Done()
+
+			if err := notifier.deliver(ctx, hook, payload, body); err != nil {
+				notifier.printf("Warning: Unable to notify the webhook %s about the change of %s: %s\n", getWebhookName(hook.URL), describeRecordChange(change), err.Error())
+			}
+		}(hook, body)
+	}
+
+	deliveries.Wait()
+}
+
+// newPayload returns the JSON payload of the given change.
+func (notifier *webhookNotifier) newPayload(ctx context.Context, change recordChange) webhookPayload {
+	payload := webhookPayload{
+		ID:        newHistoryID(),
+		Event:     "record." + getWebhookEventSuffix(change.Operation),
+		Time:      notifier.now().UTC(),
+		Action:    getActionName(ctx),
+		Profile:   notifier.getProfile(),
+		User:      getCurrentUserName(),
+		Operation: change.Operation,
+		Domain:    change.Domain,
+		RecordID:  change.RecordID,
+		Before:    change.Before,
+		After:     change.After,
+	}
+
+	payload.Host, _ = os.Hostname()
+	if record := getChangedRecordFields(change); record != nil {
+		payload.FQDN = getFullyQualifiedName(record.Name, change.Domain)
+	}
+
+	return payload
+}
+
+// formatPayload returns the body of the request to the given webhook.
+func (notifier *webhookNotifier) formatPayload(hook webhook, payload webhookPayload) ([]byte, error) {
+	if hook.Format == webhookFormatSlack {
+		return json.Marshal(slackPayload{formatWebhookMessage(payload)})
+	}
+
+	return json.Marshal(payload)
+}
+
+// deliver posts the given body to the given webhook. Network errors, rate
+// limits (429) and server errors (5xx) are retried. Receivers can detect
+// retried deliveries by the X-Dee-Delivery header.
+func (notifier *webhookNotifier) deliver(ctx context.Context, hook webhook, payload webhookPayload, body []byte) error {
+	for attempt := 0; ; attempt++ {
+		request, requestError := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+		if requestError != nil {
+			return requestError
+		}
+
+		request = request.WithContext(ctx)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("User-Agent", fmt.Sprintf("dee/%s", version()))
+		request.Header.Set("X-Dee-Event", payload.Event)
+		request.Header.Set("X-Dee-Delivery", payload.ID)
+		if hook.Secret != "" {
+			request.Header.Set("X-Dee-Signature", "sha256="+signWebhookPayload(hook.Secret, body))
+		}
+
+		err := notifier.post(request)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= notifier.policy.MaxRetries || !isRetryableWebhookError(err) {
+			return err
+		}
+
+		if sleepError := notifier.sleep(ctx, notifier.policy.backoff(attempt+1)); sleepError != nil {
+			return err
+		}
+	}
+}
+
+// post sends the given request and returns an error if
+// the webhook did not respond with a success status code.
+func (notifier *webhookNotifier) post(request *http.Request) error {
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return webhookError{err.Error(), 0}
+	}
+
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return webhookError{response.Status, response.StatusCode}
+	}
+
+	return nil
+}
+
+// loadWebhooks returns the webhooks of the base folder and of the selected
+// profile. Invalid files and webhooks are reported and skipped.
+func (notifier *webhookNotifier) loadWebhooks() []webhook {
+	var webhooks []webhook
+	for _, filePath := range getWebhookFilePaths(notifier.baseFolder, notifier.getProfile()) {
+		hooks, readError := readWebhooks(notifier.fs, filePath)
+		if readError != nil {
+			notifier.printf("Warning: Unable to read the webhooks in %q: %s\n", filePath, readError.Error())
+			continue
+		}
+
+		for _, hook := range hooks {
+			if validationError := validateWebhook(hook); validationError != nil {
+				notifier.printf("Warning: Ignoring a webhook in %q: %s\n", filePath, validationError.Error())
+				continue
+			}
+
+			webhooks = append(webhooks, hook)
+		}
+	}
+
+	return webhooks
+}
+
+// getProfile returns the name of the selected profile.
+func (notifier *webhookNotifier) getProfile() string {
+	if notifier.profile == nil || *notifier.profile == "" {
+		return defaultProfile
+	}
+
+	return *notifier.profile
+}
+
+// printf writes the given message to stderr (if available).
+// It can be called by concurrent deliveries.
+func (notifier *webhookNotifier) printf(format string, args ...interface{}) {
+	notifier.output.Lock()
+	defer notifier.output.Unlock()
+
+	printf(notifier.stderr, format, args...)
+}
+
+// webhookError is returned if a webhook could not be reached or
+// responded with an error. The status code is zero for network errors.
+type webhookError struct {
+	message    string
+	statusCode int
+}
+
+func (err webhookError) Error() string {
+	return err.message
+}
+
+// isRetryableWebhookError returns true if the delivery which failed
+// with the given error should be retried.
+func isRetryableWebhookError(err error) bool {
+	hookError, isWebhookError := err.(webhookError)
+	if !isWebhookError {
+		return false
+	}
+
+	return hookError.statusCode == 0 || hookError.statusCode == http.StatusTooManyRequests || hookError.statusCode >= 500
+}
+
+// readWebhooks reads the webhooks from the given file.
+// Returns an empty list if the file does not exist.
+func readWebhooks(fs afero.Fs, filePath string) ([]webhook, error) {
+	content, readError := afero.ReadFile(fs, filePath)
+	if os.IsNotExist(readError) {
+		return nil, nil
+	}
+
+	if readError != nil {
+		return nil, readError
+	}
+
+	var webhooks []webhook
+	if err := json.Unmarshal(content, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// validateWebhook returns an error if the given webhook has no valid URL or an unknown format.
+func validateWebhook(hook webhook) error {
+	hookURL, parseError := url.Parse(hook.URL)
+	if parseError != nil || (hookURL.Scheme != "http" && hookURL.Scheme != "https") || hookURL.Host == "" {
+		return fmt.Errorf("%q is not a valid http or https URL", hook.URL)
+	}
+
+	if hook.Format != "" && hook.Format != webhookFormatJSON && hook.Format != webhookFormatSlack {
+		return fmt.Errorf("Unknown format %q. Please use json or slack", hook.Format)
+	}
+
+	return nil
+}
+
+// getWebhookFilePaths returns the webhook files of the given profile. The webhooks of
+// the default profile are stored in the base folder, the webhooks of all other
+// profiles in addition in a sub folder of the "profiles" folder.
+func getWebhookFilePaths(baseFolder, profile string) []string {
+	filePaths := []string{filepath.Join(baseFolder, "webhooks.json")}
+	if profile != "" && profile != defaultProfile {
+		filePaths = append(filePaths, filepath.Join(baseFolder, "profiles", profile, "webhooks.json"))
+	}
+
+	return filePaths
+}
+
+// getWebhookName returns the scheme and host of the given webhook URL (e.g.
+// "https://hooks.slack.com"). The path is omitted because it often contains a token.
+func getWebhookName(webhookURL string) string {
+	hookURL, parseError := url.Parse(webhookURL)
+	if parseError != nil {
+		return "(invalid URL)"
+	}
+
+	return hookURL.Scheme + "://" + hookURL.Host
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 signature of the given body.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getWebhookEventSuffix returns the event name of the given operation (e.g. "updated").
+func getWebhookEventSuffix(operation string) string {
+	switch operation {
+	case historyOperationCreate:
+		return "created"
+
+	case historyOperationUpdate:
+		return "updated"
+
+	case historyOperationDelete:
+		return "deleted"
+	}
+
+	return operation
+}
+
+// formatWebhookMessage returns a chat message for the given change
+// (e.g. "*office.example.com (A)* updated: `1.2.3.4 → 5.6.7.8` ...").
+func formatWebhookMessage(payload webhookPayload) string {
+	change := recordChange{payload.Operation, payload.Domain, payload.RecordID, payload.Before, payload.After}
+
+	values := formatHistoryChange(historyEntry{Before: payload.Before, After: payload.After})
+
+	return fmt.Sprintf("*%s* %s: `%s` (dee %s by %s@%s, profile %s)", describeRecordChange(change), getWebhookEventSuffix(payload.Operation), values, payload.Action, payload.User, payload.Host, payload.Profile)
+}
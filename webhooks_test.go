@@ -0,0 +1,271 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRecordChange is the update of the office.example.com address.
+var testRecordChange = recordChange{
+	Operation: historyOperationUpdate,
+	Domain:    "example.com",
+	RecordID:  "1",
+	Before:    &historyRecord{Name: "office", Type: "A", Content: "1.2.3.4", TTL: 600},
+	After:     &historyRecord{Name: "office", Type: "A", Content: "5.6.7.8", TTL: 600},
+}
+
+// webhookNotifier.AfterChange should post the change as signed JSON.
+func Test_webhookNotifier_AfterChange_SignedJSONIsPosted(t *testing.T) {
+	// arrange
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer server.Close()
+
+	stderr := new(bytes.Buffer)
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+server.URL+`/dns", "secret": "s3cr3t"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+	ctx := withActionName(context.Background(), actionNameCreateOrUpdate)
+
+	// act
+	notifier.AfterChange(ctx, testRecordChange)
+
+	// assert
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fail()
+		t.Logf("The webhook should receive a JSON payload but received %q (warnings: %s)", body, stderr.String())
+		return
+	}
+
+	if payload.Event != "record.updated" || payload.Action != actionNameCreateOrUpdate || payload.FQDN != "office.example.com" || payload.Before.Content != "1.2.3.4" || payload.After.Content != "5.6.7.8" {
+		t.Fail()
+		t.Logf("The webhook received the wrong payload: %s", body)
+	}
+
+	if header.Get("X-Dee-Signature") != "sha256="+signWebhookPayload("s3cr3t", body) || header.Get("X-Dee-Event") != "record.updated" || header.Get("X-Dee-Delivery") != payload.ID {
+		t.Fail()
+		t.Logf("The webhook received the wrong headers: %v", header)
+	}
+}
+
+// webhookNotifier.AfterChange should post a chat message to webhooks with the slack format.
+func Test_webhookNotifier_SlackFormat_MessageIsPosted(t *testing.T) {
+	// arrange
+	var message slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+server.URL+`", "format": "slack"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, nil)
+
+	// act
+	notifier.AfterChange(context.Background(), testRecordChange)
+
+	// assert
+	if !strings.HasPrefix(message.Text, "*office.example.com (A)* updated: `1.2.3.4 → 5.6.7.8`") {
+		t.Fail()
+		t.Logf("The webhook received the wrong message: %q", message.Text)
+	}
+}
+
+// webhookNotifier.AfterChange should retry deliveries which failed with a server error.
+func Test_webhookNotifier_ServerError_DeliveryIsRetried(t *testing.T) {
+	// arrange
+	var deliveries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries = append(deliveries, r.Header.Get("X-Dee-Delivery"))
+		if len(deliveries) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	stderr := new(bytes.Buffer)
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+server.URL+`"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+	notifier.sleep = func(ctx context.Context, delay time.Duration) error {
+		return nil
+	}
+
+	// act
+	notifier.AfterChange(context.Background(), testRecordChange)
+
+	// assert
+	if len(deliveries) != 3 || deliveries[0] != deliveries[2] || stderr.Len() != 0 {
+		t.Fail()
+		t.Logf("The delivery should be retried with the same id until it succeeds (deliveries: %v, warnings: %s)", deliveries, stderr.String())
+	}
+}
+
+// A failed webhook delivery should be reported but not fail the change.
+func Test_hookClient_WebhookFails_ChangeSucceeds(t *testing.T) {
+	// arrange
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	stderr := new(bytes.Buffer)
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+server.URL+`/secret-token"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+
+	client := newTestDNSClient([]dnsimple.Record{{Id: 1, Name: "office", RecordType: "A", Content: "1.2.3.4", Ttl: 600}})
+	hookClient := &hookClient{dnsClientDecorator{client}, []changeHook{notifier}}
+
+	// act
+	_, err := hookClient.UpdateRecord(context.Background(), "example.com", "1", &dnsimple.ChangeRecord{Value: "5.6.7.8"})
+
+	// assert
+	if err != nil || client.calls["UpdateRecord"] != 1 {
+		t.Fail()
+		t.Logf("UpdateRecord should succeed although the webhook failed (error: %v)", err)
+	}
+
+	expectedWarning := fmt.Sprintf("Warning: Unable to notify the webhook %s about the change of office.example.com (A): 401 Unauthorized\n", server.URL)
+	if requests != 1 || stderr.String() != expectedWarning {
+		t.Fail()
+		t.Logf("The failed delivery should not be retried and be reported without the token (requests: %d, warnings: %q)", requests, stderr.String())
+	}
+}
+
+// webhookNotifier.loadWebhooks should return the valid webhooks of the base folder and of the profile.
+func Test_webhookNotifier_loadWebhooks(t *testing.T) {
+	// arrange
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee/profiles/work", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "https://hooks.example.com/all"}, {"url": "ftp://example.com"}]`), 0600)
+	afero.WriteFile(fs, "/home/user/.dee/profiles/work/webhooks.json", []byte(`[{"url": "https://chat.example.com/hooks/abc", "format": "slack"}]`), 0600)
+
+	profile := "work"
+	stderr := new(bytes.Buffer)
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+
+	// act
+	webhooks := notifier.loadWebhooks()
+
+	// assert
+	if len(webhooks) != 2 || webhooks[0].URL != "https://hooks.example.com/all" || webhooks[1].Format != webhookFormatSlack {
+		t.Fail()
+		t.Logf("loadWebhooks returned %+v", webhooks)
+	}
+
+	if !strings.Contains(stderr.String(), `"ftp://example.com" is not a valid http or https URL`) {
+		t.Fail()
+		t.Logf("The invalid webhook should be reported: %q", stderr.String())
+	}
+}
+
+// webhookNotifier.AfterChange should notify all webhooks at the same time.
+func Test_webhookNotifier_AfterChange_WebhooksAreNotifiedConcurrently(t *testing.T) {
+	// arrange
+	var arrivals sync.WaitGroup
+	arrivals.Add(2)
+	allArrived := make(chan struct{})
+	go func() {
+		arrivals.Wait()
+		close(allArrived)
+	}()
+
+	// each webhook only responds once both webhooks have received the change
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals.Done()
+		select {
+		case <-allArrived:
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	first := httptest.NewServer(handler)
+	defer first.Close()
+
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	stderr := new(bytes.Buffer)
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+first.URL+`"}, {"url": "`+second.URL+`"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+
+	// act
+	notifier.AfterChange(context.Background(), testRecordChange)
+
+	// assert
+	if stderr.Len() > 0 {
+		t.Fail()
+		t.Logf("Both webhooks should have been notified at the same time: %s", stderr.String())
+	}
+}
+
+// webhookNotifier.AfterChange should notify the webhooks about an applied
+// change even if the context of the action has been cancelled.
+func Test_webhookNotifier_AfterChange_ActionCancelled_WebhookIsNotified(t *testing.T) {
+	// arrange
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	stderr := new(bytes.Buffer)
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/home/user/.dee", 0700)
+	afero.WriteFile(fs, "/home/user/.dee/webhooks.json", []byte(`[{"url": "`+server.URL+`"}]`), 0600)
+
+	profile := defaultProfile
+	notifier := newWebhookNotifier(fs, "/home/user/.dee", &profile, stderr)
+
+	ctx, cancel := context.WithCancel(withActionName(context.Background(), actionNameUpdate))
+	cancel()
+
+	// act
+	notifier.AfterChange(ctx, testRecordChange)
+
+	// assert
+	if payload.Action != actionNameUpdate || stderr.Len() > 0 {
+		t.Fail()
+		t.Logf("The webhook should be notified about the update although the action was cancelled (payload: %#v, stderr: %q)", payload, stderr.String())
+	}
+}
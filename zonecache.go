@@ -0,0 +1,235 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"strings"
+	"sync"
+)
+
+// newZoneCacheClient creates a new DNS client which caches the domains
+// and records returned by the given client for the lifetime of the client.
+func newZoneCacheClient(client dnsClient) *zoneCacheClient {
+	return &zoneCacheClient{
+		client:  client,
+		zones:   make(map[string][]dnsimple.Record),
+		queries: make(map[recordQuery][]dnsimple.Record),
+	}
+}
+
+// recordQuery identifies a name-filtered record query.
+type recordQuery struct {
+	Domain     string
+	Name       string
+	RecordType string
+}
+
+// zoneCacheClient is a dnsClient which keeps a snapshot of the zones
+// it has fetched. The snapshot of a domain is invalidated when a record of
+// the domain is created, updated or deleted.
+type zoneCacheClient struct {
+	client dnsClient
+
+	lock    sync.Mutex
+	domains []dnsimple.Domain
+	zones   map[string][]dnsimple.Record
+	queries map[recordQuery][]dnsimple.Record
+}
+
+// GetDomains returns the cached list of domains.
+func (c *zoneCacheClient) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.domains != nil {
+		return c.domains, nil
+	}
+
+	domains, err := c.client.GetDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.domains = domains
+	return domains, nil
+}
+
+// GetRecords returns the cached snapshot of all records of the given domain.
+func (c *zoneCacheClient) GetRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.getZone(ctx, domain)
+}
+
+// RetrieveRecord returns the record with the given id from the snapshot
+// of the domain or the cached queries if the record has already been fetched.
+func (c *zoneCacheClient) RetrieveRecord(ctx context.Context, domain string, id string) (dnsimple.Record, error) {
+	c.lock.Lock()
+	cachedRecords := c.zones[domain]
+	for query, records := range c.queries {
+		if query.Domain == domain {
+			cachedRecords = append(cachedRecords[:len(cachedRecords):len(cachedRecords)], records...)
+		}
+	}
+	c.lock.Unlock()
+
+	for _, record := range cachedRecords {
+		if fmt.Sprintf("%d", record.Id) == id {
+			return record, nil
+		}
+	}
+
+	return c.client.RetrieveRecord(ctx, domain, id)
+}
+
+// FindRecords returns the records of the given domain with the given name and type.
+// The records are taken from the zone snapshot if the domain has already been fetched;
+// otherwise the query is passed to the underlying client if it supports name-filtered
+// queries or the snapshot of the domain is fetched.
+func (c *zoneCacheClient) FindRecords(ctx context.Context, domain, name, recordType string) ([]dnsimple.Record, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	query := recordQuery{domain, name, recordType}
+	if records, isCached := c.queries[query]; isCached {
+		return records, nil
+	}
+
+	finder, isFinder := c.client.(dnsRecordFinder)
+	if _, isCached := c.zones[domain]; isCached || !isFinder {
+		zone, err := c.getZone(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		return filterRecords(zone, name, recordType), nil
+	}
+
+	records, err := finder.FindRecords(ctx, domain, name, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	c.queries[query] = records
+	return records, nil
+}
+
+// CreateRecord creates a new record and invalidates the snapshot of the given domain.
+func (c *zoneCacheClient) CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	defer c.invalidate(domain)
+	return c.client.CreateRecord(ctx, domain, opts)
+}
+
+// CreateRecordFields creates a new record with all fields and invalidates the snapshot of the given domain.
+func (c *zoneCacheClient) CreateRecordFields(ctx context.Context, domain string, fields recordFields) (string, error) {
+	fieldCreator, isFieldCreator := c.client.(dnsRecordFieldCreator)
+	if !isFieldCreator {
+		return "", fmt.Errorf("The DNS client does not support setting the priority of a record")
+	}
+
+	defer c.invalidate(domain)
+	return fieldCreator.CreateRecordFields(ctx, domain, fields)
+}
+
+// UpdateRecord updates the given record and invalidates the snapshot of the given domain.
+func (c *zoneCacheClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	defer c.invalidate(domain)
+	return c.client.UpdateRecord(ctx, domain, id, opts)
+}
+
+// UpdateRecordFields updates all fields of the given record and invalidates the snapshot of the given domain.
+func (c *zoneCacheClient) UpdateRecordFields(ctx context.Context, domain string, id string, fields recordFields) (string, error) {
+	fieldUpdater, isFieldUpdater := c.client.(dnsRecordFieldUpdater)
+	if !isFieldUpdater {
+		return "", fmt.Errorf("The DNS client does not support changing the priority of a record")
+	}
+
+	defer c.invalidate(domain)
+	return fieldUpdater.UpdateRecordFields(ctx, domain, id, fields)
+}
+
+// DestroyRecord deletes the given record and invalidates the snapshot of the given domain.
+func (c *zoneCacheClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	defer c.invalidate(domain)
+	return c.client.DestroyRecord(ctx, domain, id)
+}
+
+// getZone returns the snapshot of the given domain and fetches it if necessary.
+// The caller must hold the lock.
+func (c *zoneCacheClient) getZone(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	if records, isCached := c.zones[domain]; isCached {
+		return records, nil
+	}
+
+	records, err := c.client.GetRecords(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	c.zones[domain] = records
+	return records, nil
+}
+
+// invalidate removes all cached records of the given domain.
+func (c *zoneCacheClient) invalidate(domain string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.zones, domain)
+	for query := range c.queries {
+		if query.Domain == domain {
+			delete(c.queries, query)
+		}
+	}
+}
+
+// filterRecords returns the records with the given name and type.
+// An empty record type matches all types.
+func filterRecords(records []dnsimple.Record, name, recordType string) []dnsimple.Record {
+	var filteredRecords []dnsimple.Record
+	for _, record := range records {
+		if !strings.EqualFold(record.Name, name) {
+			continue
+		}
+
+		if recordType != "" && record.RecordType != recordType {
+			continue
+		}
+
+		filteredRecords = append(filteredRecords, record)
+	}
+
+	return filteredRecords
+}
+
+// sharedClientFactory creates a single DNS client per invocation which
+// is shared by all info providers and editors, so they use the same
+// zone snapshots.
+type sharedClientFactory struct {
+	clientFactory dnsClientFactory
+
+	once   sync.Once
+	client dnsClient
+	err    error
+}
+
+// CreateClient returns the shared client and creates it on the first call.
+func (factory *sharedClientFactory) CreateClient() (dnsClient, error) {
+	factory.once.Do(func() {
+		client, err := factory.clientFactory.CreateClient()
+		if err != nil {
+			factory.err = err
+			return
+		}
+
+		factory.client = newZoneCacheClient(client)
+	})
+
+	return factory.client, factory.err
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"net"
+	"testing"
+)
+
+// testDNSClient is a dnsClient which serves the given records and counts the API calls.
+type testDNSClient struct {
+	records []dnsimple.Record
+	calls   map[string]int
+}
+
+func newTestDNSClient(records []dnsimple.Record) *testDNSClient {
+	return &testDNSClient{records, make(map[string]int)}
+}
+
+func (client *testDNSClient) GetDomains(ctx context.Context) ([]dnsimple.Domain, error) {
+	client.calls["GetDomains"]++
+	return []dnsimple.Domain{{Name: "example.com"}}, nil
+}
+
+func (client *testDNSClient) GetRecords(ctx context.Context, domain string) ([]dnsimple.Record, error) {
+	client.calls["GetRecords"]++
+	return client.records, nil
+}
+
+func (client *testDNSClient) RetrieveRecord(ctx context.Context, domain string, id string) (dnsimple.Record, error) {
+	client.calls["RetrieveRecord"]++
+	for _, record := range client.records {
+		if record.StringId() == id {
+			return record, nil
+		}
+	}
+
+	return dnsimple.Record{}, fmt.Errorf("Record not found")
+}
+
+func (client *testDNSClient) CreateRecord(ctx context.Context, domain string, opts *dnsimple.ChangeRecord) (string, error) {
+	client.calls["CreateRecord"]++
+	return "", nil
+}
+
+func (client *testDNSClient) UpdateRecord(ctx context.Context, domain string, id string, opts *dnsimple.ChangeRecord) (string, error) {
+	client.calls["UpdateRecord"]++
+	return "", nil
+}
+
+func (client *testDNSClient) DestroyRecord(ctx context.Context, domain string, id string) error {
+	client.calls["DestroyRecord"]++
+	return nil
+}
+
+// testDNSRecordFinder is a testDNSClient which supports name-filtered queries.
+type testDNSRecordFinder struct {
+	*testDNSClient
+}
+
+func (client testDNSRecordFinder) FindRecords(ctx context.Context, domain, name, recordType string) ([]dnsimple.Record, error) {
+	client.calls["FindRecords"]++
+	return filterRecords(client.records, name, recordType), nil
+}
+
+var testZone = []dnsimple.Record{
+	{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4"},
+	{Id: 2, Name: "www", RecordType: "AAAA", Content: "::1"},
+	{Id: 3, Name: "", RecordType: "MX", Content: "mail.example.com"},
+}
+
+// Repeated lookups should only fetch the zone once.
+func Test_zoneCacheClient_RepeatedLookups_ZoneIsFetchedOnce(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	infoProvider := newDNSInfoProvider(newZoneCacheClient(client))
+
+	// act
+	infoProvider.GetDomainRecords(context.Background(), "example.com")
+	infoProvider.GetSubdomainRecords(context.Background(), "example.com", "www")
+	record, _ := infoProvider.GetSubdomainRecord(context.Background(), "example.com", "www", "AAAA")
+
+	// assert
+	if client.calls["GetRecords"] != 1 {
+		t.Fail()
+		t.Logf("The zone should be fetched once but was fetched %d times", client.calls["GetRecords"])
+	}
+
+	if record.Id != 2 {
+		t.Fail()
+		t.Logf("GetSubdomainRecord should return the AAAA record but returned %#v", record)
+	}
+}
+
+// A write should invalidate the snapshot of the domain.
+func Test_zoneCacheClient_RecordUpdated_ZoneIsFetchedAgain(t *testing.T) {
+	// arrange
+	client := newTestDNSClient(testZone)
+	cache := newZoneCacheClient(client)
+	cache.GetRecords(context.Background(), "example.com")
+
+	// act
+	cache.UpdateRecord(context.Background(), "example.com", "1", &dnsimple.ChangeRecord{})
+	cache.GetRecords(context.Background(), "example.com")
+
+	// assert
+	if client.calls["GetRecords"] != 2 {
+		t.Fail()
+		t.Logf("The zone should be fetched again after an update but was fetched %d times", client.calls["GetRecords"])
+	}
+}
+
+// The editor should not fetch the zone more than once per change.
+func Test_zoneCacheClient_CreateOrUpdate_RecordsAreQueriedOnce(t *testing.T) {
+	// arrange
+	client := testDNSRecordFinder{newTestDNSClient(testZone)}
+	cache := newZoneCacheClient(client)
+	infoProvider := newDNSInfoProvider(cache)
+	editor := newDNSEditor(cache, infoProvider)
+	ip := net.ParseIP("5.6.7.8")
+
+	// act
+	infoProvider.GetSubdomainRecord(context.Background(), "example.com", "www", "A")
+	err := editor.UpdateSubdomain(context.Background(), "example.com", "www", ip)
+
+	// assert
+	if err != nil {
+		t.Fail()
+		t.Logf("UpdateSubdomain should not return an error: %s", err)
+	}
+
+	if client.calls["FindRecords"] != 1 || client.calls["GetRecords"] != 0 {
+		t.Fail()
+		t.Logf("The records should be queried once by name but were queried %d times (%d full zone downloads)", client.calls["FindRecords"], client.calls["GetRecords"])
+	}
+}
+
+// The shared client factory should return the same client for all calls.
+func Test_sharedClientFactory_MultipleCalls_SameClientIsReturned(t *testing.T) {
+	// arrange
+	factory := &sharedClientFactory{clientFactory: testDNSClientFactory{newTestDNSClient(testZone)}}
+
+	// act
+	first, _ := factory.CreateClient()
+	second, _ := factory.CreateClient()
+
+	// assert
+	if first != second {
+		t.Fail()
+		t.Logf("CreateClient should return the same client instance for all calls")
+	}
+}
+
+type testDNSClientFactory struct {
+	client dnsClient
+}
+
+func (factory testDNSClientFactory) CreateClient() (dnsClient, error) {
+	return factory.client, nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// zoneFile contains the records of a zone file. The names of the
+// records are relative to the origin (e.g. "www" for www.example.com).
+type zoneFile struct {
+	Origin  string
+	Records []dnsimple.Record
+}
+
+// zoneFileToken is a single field of a zone file line.
+type zoneFileToken struct {
+	text   string
+	quoted bool
+}
+
+// parseZoneFile parses the records of the given zone file. The given origin or,
+// if empty, the first $ORIGIN directive is the origin of the zone; the names of all
+// records must belong to it. Records without a TTL get the TTL of the last $TTL
+// directive or the given default TTL.
+// Like DNSimple, the contents of the records are taken as absolute names:
+// trailing dots are removed but the origin is never appended.
+func parseZoneFile(reader io.Reader, origin string, defaultTTL int64) (zoneFile, error) {
+	zone := zoneFile{Origin: strings.ToLower(strings.TrimSuffix(origin, "."))}
+	currentOrigin := zone.Origin
+	ttl := defaultTTL
+	lastName, hasLastName := "", false
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for {
+		tokens, startsWithSpace, firstLineNumber, readError := readZoneFileLine(scanner, &lineNumber)
+		if readError != nil {
+			return zoneFile{}, fmt.Errorf("Line %d: %s", firstLineNumber, readError.Error())
+		}
+
+		if tokens == nil {
+			break
+		}
+
+		if len(tokens) == 0 {
+			continue
+		}
+
+		lineError := func(format string, args ...interface{}) error {
+			return fmt.Errorf("Line %d: %s", firstLineNumber, fmt.Sprintf(format, args...))
+		}
+
+		// directives
+		if strings.HasPrefix(tokens[0].text, "$") && !startsWithSpace {
+			if len(tokens) != 2 {
+				return zoneFile{}, lineError("%s requires exactly one value", tokens[0].text)
+			}
+
+			switch strings.ToUpper(tokens[0].text) {
+			case "$ORIGIN":
+				currentOrigin = strings.ToLower(strings.TrimSuffix(tokens[1].text, "."))
+				if zone.Origin == "" {
+					zone.Origin = currentOrigin
+				}
+
+			case "$TTL":
+				value, parseError := strconv.ParseInt(tokens[1].text, 10, 64)
+				if parseError != nil || value < 0 {
+					return zoneFile{}, lineError("Invalid TTL %q", tokens[1].text)
+				}
+
+				ttl = value
+
+			default:
+				return zoneFile{}, lineError("Unsupported directive %s", tokens[0].text)
+			}
+
+			continue
+		}
+
+		if zone.Origin == "" {
+			return zoneFile{}, lineError("No origin defined. Please add a $ORIGIN directive")
+		}
+
+		// name
+		name := lastName
+		if startsWithSpace {
+			if !hasLastName {
+				return zoneFile{}, lineError("The first record has no name")
+			}
+		} else {
+			var nameError error
+			if name, nameError = getZoneFileRecordName(tokens[0].text, currentOrigin, zone.Origin); nameError != nil {
+				return zoneFile{}, lineError("%s", nameError.Error())
+			}
+
+			tokens = tokens[1:]
+		}
+
+		lastName, hasLastName = name, true
+
+		// TTL and class in any order
+		record := dnsimple.Record{Name: name, Ttl: ttl}
+		for index := 0; index < 2 && len(tokens) > 0; index++ {
+			if value, parseError := strconv.ParseInt(tokens[0].text, 10, 64); parseError == nil && !tokens[0].quoted {
+				record.Ttl = value
+				tokens = tokens[1:]
+			} else if strings.EqualFold(tokens[0].text, "IN") {
+				tokens = tokens[1:]
+			}
+		}
+
+		if len(tokens) < 2 {
+			return zoneFile{}, lineError("A record needs a type and a content")
+		}
+
+		record.RecordType = strings.ToUpper(tokens[0].text)
+		tokens = tokens[1:]
+
+		// priority
+		if hasZoneFilePriority(record.RecordType) {
+			priority, parseError := strconv.ParseInt(tokens[0].text, 10, 64)
+			if parseError != nil || len(tokens) < 2 {
+				return zoneFile{}, lineError("%s records need a priority and a content", record.RecordType)
+			}
+
+			record.Prio = priority
+			tokens = tokens[1:]
+		}
+
+		record.Content = getZoneFileRecordContent(record.RecordType, tokens)
+		zone.Records = append(zone.Records, record)
+	}
+
+	return zone, nil
+}
+
+// readZoneFileLine reads the tokens of the next logical line of a zone file.
+// Lines are joined while parentheses are open. Returns nil tokens at the end
+// of the file and an empty list for lines without records.
+func readZoneFileLine(scanner *bufio.Scanner, lineNumber *int) ([]zoneFileToken, bool, int, error) {
+	var tokens []zoneFileToken
+	startsWithSpace := false
+	firstLineNumber := *lineNumber + 1
+	depth := 0
+
+	for line := 0; ; line++ {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, false, firstLineNumber, err
+			}
+
+			if depth > 0 {
+				return nil, false, firstLineNumber, fmt.Errorf("Missing closing parenthesis")
+			}
+
+			if line == 0 {
+				return nil, false, firstLineNumber, nil
+			}
+
+			break
+		}
+
+		*lineNumber++
+		text := scanner.Text()
+		if line == 0 {
+			startsWithSpace = len(text) > 0 && (text[0] == ' ' || text[0] == '\t')
+		}
+
+		lineTokens, lineDepth, tokenizeError := tokenizeZoneFileLine(text, depth)
+		if tokenizeError != nil {
+			return nil, false, firstLineNumber, tokenizeError
+		}
+
+		tokens = append(tokens, lineTokens...)
+		depth = lineDepth
+		if depth == 0 {
+			break
+		}
+	}
+
+	if tokens == nil {
+		tokens = []zoneFileToken{}
+	}
+
+	return tokens, startsWithSpace, firstLineNumber, nil
+}
+
+// tokenizeZoneFileLine splits the given line into whitespace separated fields.
+// Quoted strings are single fields, comments start with a semicolon and
+// parentheses change the given depth.
+func tokenizeZoneFileLine(line string, depth int) ([]zoneFileToken, int, error) {
+	var tokens []zoneFileToken
+	var current []rune
+	inQuotes, isQuoted, isEscaped := false, false, false
+
+	flush := func() {
+		if len(current) > 0 || isQuoted {
+			tokens = append(tokens, zoneFileToken{string(current), isQuoted})
+		}
+
+		current, isQuoted = nil, false
+	}
+
+	for _, character := range line {
+		switch {
+		case isEscaped:
+			current = append(current, character)
+			isEscaped = false
+
+		case inQuotes && character == '\\':
+			isEscaped = true
+
+		case character == '"':
+			inQuotes = !inQuotes
+			isQuoted = true
+
+		case inQuotes:
+			current = append(current, character)
+
+		case character == ';':
+			flush()
+			return tokens, depth, nil
+
+		case character == '(':
+			flush()
+			depth++
+
+		case character == ')':
+			flush()
+			if depth == 0 {
+				return nil, depth, fmt.Errorf("Unexpected closing parenthesis")
+			}
+
+			depth--
+
+		case character == ' ' || character == '\t':
+			flush()
+
+		default:
+			current = append(current, character)
+		}
+	}
+
+	if inQuotes {
+		return nil, depth, fmt.Errorf("Missing closing quote")
+	}
+
+	flush()
+	return tokens, depth, nil
+}
+
+// getZoneFileRecordName returns the name of a record relative to the origin of the
+// zone. Relative names (e.g. "www" or "@") belong to the current origin.
+func getZoneFileRecordName(name, currentOrigin, zoneOrigin string) (string, error) {
+	absoluteName := strings.ToLower(name)
+	switch {
+	case absoluteName == "@":
+		absoluteName = currentOrigin
+
+	case strings.HasSuffix(absoluteName, "."):
+		absoluteName = strings.TrimSuffix(absoluteName, ".")
+
+	default:
+		absoluteName = absoluteName + "." + currentOrigin
+	}
+
+	subdomain, isMatch := splitName(absoluteName, zoneOrigin)
+	if !isMatch {
+		return "", fmt.Errorf("The name %q does not belong to %s", name, zoneOrigin)
+	}
+
+	return subdomain, nil
+}
+
+// getZoneFileRecordContent returns the content of a record with the given
+// type and fields. The strings of TXT records are concatenated.
+func getZoneFileRecordContent(recordType string, tokens []zoneFileToken) string {
+	var texts []string
+	allQuoted := true
+	for _, token := range tokens {
+		texts = append(texts, token.text)
+		allQuoted = allQuoted && token.quoted
+	}
+
+	if isTextRecordType(recordType) && allQuoted {
+		return strings.Join(texts, "")
+	}
+
+	if len(tokens) == 1 && !tokens[0].quoted && !isTextRecordType(recordType) {
+		return strings.TrimSuffix(texts[0], ".")
+	}
+
+	// keep the quotes of quoted strings (e.g. CAA 0 issue "letsencrypt.org")
+	for index, token := range tokens {
+		if token.quoted {
+			texts[index] = quoteZoneFileText(token.text)
+		}
+	}
+
+	return strings.Join(texts, " ")
+}
+
+// formatZoneFile returns the given records in the zone file format with
+// names relative to the given origin.
+func formatZoneFile(origin string, records []dnsimple.Record) string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "$ORIGIN %s.\n", origin)
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\n", strings.Join(getZoneFileFields(record), "\t"))
+	}
+
+	w.Flush()
+
+	return buf.String()
+}
+
+// getZoneFileFields returns the fields of the given record in a zone file
+// (e.g. "www", "600", "IN", "A", "1.2.3.4").
+func getZoneFileFields(record dnsimple.Record) []string {
+	name := record.Name
+	if name == "" {
+		name = "@"
+	}
+
+	content := record.Content
+	if isTextRecordType(record.RecordType) {
+		content = quoteZoneFileText(content)
+	}
+
+	if hasZoneFilePriority(record.RecordType) {
+		content = fmt.Sprintf("%d %s", record.Prio, content)
+	}
+
+	return []string{name, fmt.Sprintf("%d", record.Ttl), "IN", record.RecordType, content}
+}
+
+// quoteZoneFileText returns the given text as a quoted zone file string.
+func quoteZoneFileText(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// hasZoneFilePriority returns true if records of the given type have a priority.
+func hasZoneFilePriority(recordType string) bool {
+	return recordType == "MX" || recordType == "SRV"
+}
+
+// isTextRecordType returns true if the content of the given record type is quoted text.
+func isTextRecordType(recordType string) bool {
+	return recordType == "TXT" || recordType == "SPF"
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/pearkes/dnsimple"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseZoneFile should parse directives, priorities, quoted strings and multi-line records.
+func Test_parseZoneFile_ValidZoneFile_RecordsAreReturned(t *testing.T) {
+	// arrange
+	content := `$ORIGIN example.com.
+$TTL 3600
+@ IN SOA ns1.dnsimple.com. admin.dnsimple.com. (
+	1 86400 7200 604800 300 ) ; the serial is ignored
+@         IN  MX   10 mail.example.com.
+www   600 IN  A    1.2.3.4
+          IN  AAAA ::1
+_dmarc.example.com. TXT "v=DMARC1; p=none" "; rua=mailto:\"dmarc\"@example.com"
+`
+
+	// act
+	zone, err := parseZoneFile(strings.NewReader(content), "", 600)
+
+	// assert
+	expected := []dnsimple.Record{
+		{Name: "", RecordType: "SOA", Content: "ns1.dnsimple.com. admin.dnsimple.com. 1 86400 7200 604800 300", Ttl: 3600},
+		{Name: "", RecordType: "MX", Content: "mail.example.com", Ttl: 3600, Prio: 10},
+		{Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+		{Name: "www", RecordType: "AAAA", Content: "::1", Ttl: 3600},
+		{Name: "_dmarc", RecordType: "TXT", Content: `v=DMARC1; p=none; rua=mailto:"dmarc"@example.com`, Ttl: 3600},
+	}
+
+	if err != nil || zone.Origin != "example.com" || !reflect.DeepEqual(zone.Records, expected) {
+		t.Fail()
+		t.Logf("parseZoneFile returned %v (origin: %q, error: %v) but %v was expected", zone.Records, zone.Origin, err, expected)
+	}
+}
+
+// parseZoneFile should return the records written by formatZoneFile.
+func Test_parseZoneFile_FormattedZoneFile_RecordsAreUnchanged(t *testing.T) {
+	// arrange
+	records := []dnsimple.Record{
+		{Name: "", RecordType: "MX", Content: "mail.example.com", Ttl: 3600, Prio: 10},
+		{Name: "*", RecordType: "CNAME", Content: "example.com", Ttl: 600},
+		{Name: "txt", RecordType: "TXT", Content: `a "quoted" \ text; with semicolon`, Ttl: 60},
+	}
+
+	// act
+	zone, err := parseZoneFile(strings.NewReader(formatZoneFile("example.com", records)), "", 600)
+
+	// assert
+	if err != nil || !reflect.DeepEqual(zone.Records, records) {
+		t.Fail()
+		t.Logf("parseZoneFile(formatZoneFile(%v)) returned %v (error: %v)", records, zone.Records, err)
+	}
+}
+
+// parseZoneFile should return CAA, SRV and MX records written by formatZoneFile unchanged.
+func Test_parseZoneFile_FormattedCAASRVAndMXRecords_RecordsAreUnchanged(t *testing.T) {
+	// arrange
+	records := []dnsimple.Record{
+		{Name: "", RecordType: "CAA", Content: `0 issue "letsencrypt.org"`, Ttl: 3600},
+		{Name: "", RecordType: "CAA", Content: `0 iodef "mailto:security@example.com"`, Ttl: 3600},
+		{Name: "_sip._tcp", RecordType: "SRV", Content: "5 5060 sip.example.com", Ttl: 600, Prio: 10},
+		{Name: "", RecordType: "MX", Content: "mx1.example.com", Ttl: 600, Prio: 20},
+	}
+
+	// act
+	zone, err := parseZoneFile(strings.NewReader(formatZoneFile("example.com", records)), "", 600)
+
+	// assert
+	if err != nil || !reflect.DeepEqual(zone.Records, records) {
+		t.Fail()
+		t.Logf("parseZoneFile(formatZoneFile(%v)) returned %v (error: %v)", records, zone.Records, err)
+	}
+}
+
+// parseZoneFile should return an error with the line number for invalid records.
+func Test_parseZoneFile_InvalidRecords_ErrorIsReturned(t *testing.T) {
+	inputs := []struct {
+		content       string
+		expectedError string
+	}{
+		{"www 600 IN A 1.2.3.4", "Line 1: No origin defined. Please add a $ORIGIN directive"},
+		{"$ORIGIN example.com.\n\nwww 600 IN A", "Line 3: A record needs a type and a content"},
+		{"$ORIGIN example.com.\nmail.example.org. MX 10 mx.example.org", `Line 2: The name "mail.example.org." does not belong to example.com`},
+		{"$ORIGIN example.com.\n@ MX mx.example.com", "Line 2: MX records need a priority and a content"},
+		{"$ORIGIN example.com.\n@ TXT \"unterminated", "Line 2: Missing closing quote"},
+	}
+
+	for _, input := range inputs {
+		// act
+		_, err := parseZoneFile(strings.NewReader(input.content), "", 600)
+
+		// assert
+		if err == nil || err.Error() != input.expectedError {
+			t.Fail()
+			t.Logf("parseZoneFile(%q) should return %q but returned %v", input.content, input.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pearkes/dnsimple"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// The operations of a zone plan.
+const (
+	planOperationCreate = "create"
+	planOperationUpdate = "update"
+	planOperationDelete = "delete"
+)
+
+// zonePlan contains the changes which converge the records of a domain to the desired records.
+type zonePlan struct {
+	Domain  string          `json:"domain"`
+	Changes []plannedChange `json:"changes"`
+}
+
+// plannedChange is a single change of a zone plan. Creates have no current
+// record, deletes have no desired record.
+type plannedChange struct {
+	Operation string           `json:"operation"`
+	Current   *dnsimple.Record `json:"current,omitempty"`
+	Desired   *dnsimple.Record `json:"desired,omitempty"`
+}
+
+// Record returns the desired record of the change or the current record if it is deleted.
+func (change plannedChange) Record() dnsimple.Record {
+	if change.Desired != nil {
+		return *change.Desired
+	}
+
+	return *change.Current
+}
+
+// newZonePlan returns the changes which converge the current records of the given
+// domain to the desired records. Records with the same name, type and content are
+// updated if their TTL or priority differ. The remaining records with the same name
+// and type are updated in order; all other records are created or deleted.
+// Records managed by the DNS provider (see isProviderRecord) are ignored.
+func newZonePlan(domain string, current, desired []dnsimple.Record) zonePlan {
+	current = withoutProviderRecords(current)
+	desired = withoutProviderRecords(desired)
+
+	plan := zonePlan{Domain: domain}
+	matched := make(map[int]bool)
+
+	// pair records with the same name, type and content first
+	var unmatchedDesired []dnsimple.Record
+	for _, desiredRecord := range desired {
+		index := findRecord(current, matched, func(record dnsimple.Record) bool {
+			return isSameRecordSet(record, desiredRecord) && record.Content == desiredRecord.Content
+		})
+
+		if index < 0 {
+			unmatchedDesired = append(unmatchedDesired, desiredRecord)
+			continue
+		}
+
+		matched[index] = true
+		if current[index].Ttl != desiredRecord.Ttl || current[index].Prio != desiredRecord.Prio {
+			plan.add(planOperationUpdate, &current[index], desiredRecord)
+		}
+	}
+
+	// update the remaining records with the same name and type or create them
+	for _, desiredRecord := range unmatchedDesired {
+		index := findRecord(current, matched, func(record dnsimple.Record) bool {
+			return isSameRecordSet(record, desiredRecord)
+		})
+
+		if index < 0 {
+			plan.add(planOperationCreate, nil, desiredRecord)
+			continue
+		}
+
+		matched[index] = true
+		plan.add(planOperationUpdate, &current[index], desiredRecord)
+	}
+
+	// delete all records which are not desired
+	for index := range current {
+		if !matched[index] {
+			currentRecord := current[index]
+			plan.Changes = append(plan.Changes, plannedChange{Operation: planOperationDelete, Current: &currentRecord})
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return compareRecords(plan.Changes[i].Record(), plan.Changes[j].Record()) < 0
+	})
+
+	return plan
+}
+
+// add appends a change with a copy of the given desired record to the plan.
+func (plan *zonePlan) add(operation string, current *dnsimple.Record, desired dnsimple.Record) {
+	var currentRecord *dnsimple.Record
+	if current != nil {
+		copied := *current
+		currentRecord = &copied
+	}
+
+	plan.Changes = append(plan.Changes, plannedChange{Operation: operation, Current: currentRecord, Desired: &desired})
+}
+
+// IsEmpty returns true if the plan contains no changes.
+func (plan zonePlan) IsEmpty() bool {
+	return len(plan.Changes) == 0
+}
+
+// Apply applies the changes of the plan with the given editor and returns the number
+// of applied changes. Deletes are applied first so that records which conflict with
+// new records (e.g. an A record which is replaced by a CNAME) are removed before.
+func (plan zonePlan) Apply(ctx context.Context, editor dnsRecordEditor) (int, error) {
+	applied := 0
+	for _, operation := range []string{planOperationDelete, planOperationUpdate, planOperationCreate} {
+		for _, change := range plan.Changes {
+			if change.Operation != operation {
+				continue
+			}
+
+			if err := applyPlannedChange(ctx, editor, plan.Domain, change); err != nil {
+				record := change.Record()
+				return applied, fmt.Errorf("Unable to %s the %s record of %s: %s", change.Operation, record.RecordType, getFormattedDomainName(record.Name, plan.Domain), err.Error())
+			}
+
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// applyPlannedChange applies the given change to the given domain.
+func applyPlannedChange(ctx context.Context, editor dnsRecordEditor, domain string, change plannedChange) error {
+	switch change.Operation {
+	case planOperationCreate:
+		_, err := editor.CreateRecord(ctx, domain, recordFields{
+			Name:       change.Desired.Name,
+			Content:    change.Desired.Content,
+			RecordType: change.Desired.RecordType,
+			TTL:        change.Desired.Ttl,
+			Priority:   change.Desired.Prio,
+		})
+		return err
+
+	case planOperationUpdate:
+		recordChange := recordUpdate{}
+		if change.Desired.Content != change.Current.Content {
+			recordChange.Content = &change.Desired.Content
+		}
+
+		if change.Desired.Ttl != change.Current.Ttl {
+			ttl := int(change.Desired.Ttl)
+			recordChange.TTL = &ttl
+		}
+
+		if change.Desired.Prio != change.Current.Prio {
+			priority := int(change.Desired.Prio)
+			recordChange.Priority = &priority
+		}
+
+		return editor.UpdateRecordByID(ctx, domain, change.Current.StringId(), recordChange)
+
+	case planOperationDelete:
+		return editor.DeleteRecordByID(ctx, domain, change.Current.StringId())
+	}
+
+	return fmt.Errorf("Unknown operation %q", change.Operation)
+}
+
+// formatZonePlan formats the changes of the given plan as a table
+// with one line per change (e.g. "~ www.example.com  A  600  1.2.3.4 → 5.6.7.8").
+func formatZonePlan(plan zonePlan) string {
+	buf := new(bytes.Buffer)
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+
+	for index, change := range plan.Changes {
+		record := change.Record()
+
+		symbol, ttl, content := "+", fmt.Sprintf("%d", record.Ttl), record.Content
+		switch change.Operation {
+		case planOperationDelete:
+			symbol = "-"
+
+		case planOperationUpdate:
+			symbol = "~"
+			ttl = formatPlannedValue(fmt.Sprintf("%d", change.Current.Ttl), ttl)
+			content = formatPlannedValue(change.Current.Content, content)
+			if change.Current.Prio != change.Desired.Prio {
+				content = fmt.Sprintf("%s (priority %s)", content, formatPlannedValue(fmt.Sprintf("%d", change.Current.Prio), fmt.Sprintf("%d", change.Desired.Prio)))
+			}
+		}
+
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s", symbol, getFormattedDomainName(record.Name, plan.Domain), record.RecordType, ttl, content)
+
+		if index < len(plan.Changes)-1 {
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	w.Flush()
+
+	return buf.String()
+}
+
+// formatPlannedValue returns "current → desired" if the values differ or the value otherwise.
+func formatPlannedValue(current, desired string) string {
+	if current == desired {
+		return desired
+	}
+
+	return fmt.Sprintf("%s → %s", current, desired)
+}
+
+// isProviderRecord returns true if the given record is managed by
+// the DNS provider (the SOA and the NS records of the domain itself).
+func isProviderRecord(record dnsimple.Record) bool {
+	return record.RecordType == "SOA" || (record.RecordType == "NS" && record.Name == "")
+}
+
+// withoutProviderRecords returns the given records without the records
+// managed by the DNS provider.
+func withoutProviderRecords(records []dnsimple.Record) []dnsimple.Record {
+	var filtered []dnsimple.Record
+	for _, record := range records {
+		if !isProviderRecord(record) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered
+}
+
+// isSameRecordSet returns true if the given records have the same name and type.
+func isSameRecordSet(record, otherRecord dnsimple.Record) bool {
+	return strings.EqualFold(record.Name, otherRecord.Name) && record.RecordType == otherRecord.RecordType
+}
+
+// findRecord returns the index of the first record which is not yet
+// matched and satisfies the given condition or -1.
+func findRecord(records []dnsimple.Record, matched map[int]bool, condition func(record dnsimple.Record) bool) int {
+	for index, record := range records {
+		if !matched[index] && condition(record) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// compareRecords orders records by name, type and content.
+func compareRecords(record, otherRecord dnsimple.Record) int {
+	if name, otherName := strings.ToLower(record.Name), strings.ToLower(otherRecord.Name); name != otherName {
+		return strings.Compare(name, otherName)
+	}
+
+	if record.RecordType != otherRecord.RecordType {
+		return strings.Compare(record.RecordType, otherRecord.RecordType)
+	}
+
+	return strings.Compare(record.Content, otherRecord.Content)
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Andreas Koch. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"github.com/pearkes/dnsimple"
+	"testing"
+)
+
+// newZonePlan should create, update and delete the records which differ.
+func Test_newZonePlan_RecordsDiffer_ChangesArePlanned(t *testing.T) {
+	// arrange
+	current := []dnsimple.Record{
+		{Id: 1, Name: "", RecordType: "SOA", Content: "ns1.dnsimple.com admin.dnsimple.com 1"},
+		{Id: 2, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+		{Id: 3, Name: "api", RecordType: "A", Content: "10.0.0.1", Ttl: 600},
+		{Id: 4, Name: "old", RecordType: "CNAME", Content: "www.example.com", Ttl: 600},
+		{Id: 5, Name: "", RecordType: "MX", Content: "mail.example.com", Ttl: 3600, Prio: 10},
+	}
+
+	desired := []dnsimple.Record{
+		{Id: 2, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 60},
+		{Id: 3, Name: "api", RecordType: "A", Content: "10.0.0.2", Ttl: 600},
+		{Id: 6, Name: "new", RecordType: "TXT", Content: "hello", Ttl: 600},
+		{Id: 5, Name: "", RecordType: "MX", Content: "mail.example.com", Ttl: 3600, Prio: 10},
+	}
+
+	// act
+	plan := newZonePlan("example.com", current, desired)
+
+	// assert
+	expected := "~ api.example.com  A      600       10.0.0.1 → 10.0.0.2\n" +
+		"+ new.example.com  TXT    600       hello\n" +
+		"- old.example.com  CNAME  600       www.example.com\n" +
+		"~ www.example.com  A      600 → 60  1.2.3.4"
+	if formatted := formatZonePlan(plan); formatted != expected {
+		t.Fail()
+		t.Logf("newZonePlan returned\n%s\nbut the following plan was expected\n%s", formatted, expected)
+	}
+}
+
+// newZonePlan should not plan any changes for identical records.
+func Test_newZonePlan_SameRecords_PlanIsEmpty(t *testing.T) {
+	// arrange
+	records := []dnsimple.Record{
+		{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600},
+		{Id: 2, Name: "www", RecordType: "A", Content: "1.2.3.5", Ttl: 600},
+	}
+
+	reordered := []dnsimple.Record{records[1], records[0]}
+
+	// act
+	plan := newZonePlan("example.com", records, reordered)
+
+	// assert
+	if !plan.IsEmpty() {
+		t.Fail()
+		t.Logf("newZonePlan should not plan any changes for identical records but planned %v", plan.Changes)
+	}
+}
+
+// zonePlan.Apply should delete records before creating new ones.
+func Test_zonePlan_Apply_DeletesAreAppliedFirst(t *testing.T) {
+	// arrange
+	current := []dnsimple.Record{{Id: 1, Name: "www", RecordType: "A", Content: "1.2.3.4", Ttl: 600}}
+	desired := []dnsimple.Record{{Name: "www", RecordType: "CNAME", Content: "example.com", Ttl: 600}}
+	plan := newZonePlan("example.com", current, desired)
+
+	var operations []string
+	editor := testDNSEditor{
+		createRecordFunc: func(domain string, fields recordFields) (string, error) {
+			operations = append(operations, "create "+fields.RecordType)
+			return "2", nil
+		},
+		deleteRecordByIDFunc: func(domain, id string) error {
+			operations = append(operations, "delete "+id)
+			return nil
+		},
+	}
+
+	// act
+	applied, err := plan.Apply(context.Background(), editor)
+
+	// assert
+	if err != nil || applied != 2 || len(operations) != 2 || operations[0] != "delete 1" || operations[1] != "create CNAME" {
+		t.Fail()
+		t.Logf("Apply should delete the A record before creating the CNAME (operations: %v, error: %v)", operations, err)
+	}
+}